@@ -2,61 +2,88 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"flag"
 	"os"
-	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 
 	"pvz/internal/app"
+	"pvz/internal/config"
+	"pvz/internal/database"
+	"pvz/internal/logger"
 )
 
 func main() {
-	dbHost := os.Getenv("DATABASE_HOST")
-	dbPort := os.Getenv("DATABASE_PORT")
-	dbUser := os.Getenv("DATABASE_USER")
-	dbPassword := os.Getenv("DATABASE_PASSWORD")
-	dbName := os.Getenv("DATABASE_NAME")
-	serverPort := os.Getenv("SERVER_PORT")
-	secret := os.Getenv("SECRET")
-	grpcPort := os.Getenv("GRPC_PORT")
-	prometheusPort := os.Getenv("PROMETHEUS_PORT")
-
-	levelStr, exists := os.LookupEnv("LOG_LEVEL")
-	if !exists {
-		logrus.Error("Строка уровня логгирования не установлена")
-		levelStr = "info"
-	}
-	level, err := logrus.ParseLevel(strings.ToLower(levelStr))
-	if err != nil {
-		logrus.WithError(err).Error("Ошибка")
-		level = logrus.InfoLevel
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		os.Exit(printConfig(args[2:]))
 	}
-	logrus.SetLevel(level)
-	logrus.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
 
-	logrus.Infof("Установлен уровень логирования: %s", level.String())
+	cfg, err := config.Load(args, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("Ошибка конфигурации")
+	}
 
-	if dbHost == "" || dbPort == "" || dbUser == "" || dbPassword == "" || dbName == "" || serverPort == "" || secret == "" || grpcPort == "" || prometheusPort == "" {
-		logrus.Fatal("Не все переменные окружения заданы")
+	level, _ := logrus.ParseLevel(cfg.Log.Level)
+	logrus.SetLevel(level)
+	if err := logger.SetFormat(cfg.Log.Format); err != nil {
+		logrus.WithError(err).Error("Ошибка")
 	}
 
-	jwtSecret := []byte(secret)
+	logrus.Infof("Установлен уровень логирования: %s", level.String())
 
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", dbUser, dbPassword, dbHost, dbPort, dbName)
+	var db *pgxpool.Pool
+	if cfg.Storage == config.StoragePostgres {
+		poolConfig, err := pgxpool.ParseConfig(cfg.Database.DSN())
+		if err != nil {
+			logrus.WithError(err).Fatal("Ошибка разбора строки подключения к БД")
+		}
+		if cfg.Database.MaxConns > 0 {
+			poolConfig.MaxConns = cfg.Database.MaxConns
+		}
+		poolConfig.MinConns = cfg.Database.MinConns
 
-	db, err := pgxpool.New(context.Background(), dsn)
+		db, err = pgxpool.NewWithConfig(context.Background(), poolConfig)
 
-	if err != nil {
-		logrus.WithError(err).Fatal("Ошибка подключения к БД")
+		if err != nil {
+			logrus.WithError(err).Fatal("Ошибка подключения к БД")
+		}
 	}
 
-	application := app.NewApp(db, serverPort, grpcPort, prometheusPort, jwtSecret)
+	var pool database.DBPool
+	if db != nil {
+		pool = db
+	}
+	application := app.NewApp(pool, cfg)
 	if err := application.Run(); err != nil {
-		db.Close()
+		if db != nil {
+			db.Close()
+		}
 		logrus.WithError(err).Fatal("Ошибка выполнения приложения")
 	}
 }
+
+func printConfig(args []string) int {
+	cfg, err := config.Load(args, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if cfg == nil {
+		logrus.WithError(err).Error("Ошибка конфигурации")
+		return 2
+	}
+	if writeErr := cfg.Redacted().WriteYAML(os.Stdout); writeErr != nil {
+		logrus.WithError(writeErr).Error("Ошибка вывода конфигурации")
+		return 1
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Конфигурация некорректна")
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/database"
+	"pvz/internal/models"
+	"pvz/migrations"
+)
+
+type command struct {
+	description string
+	setup       func(fs *flag.FlagSet) func(ctx context.Context, c *cli) error
+}
+
+var commands = map[string]command{
+	"users create": {
+		description: "создать пользователя (например, первого модератора)",
+		setup:       usersCreate,
+	},
+	"users list": {
+		description: "список пользователей",
+		setup:       usersList,
+	},
+	"pvz create": {
+		description: "создать ПВЗ",
+		setup:       pvzCreate,
+	},
+	"pvz list": {
+		description: "список ПВЗ",
+		setup:       pvzList,
+	},
+	"pvz delete": {
+		description: "удалить ПВЗ вместе с приёмками и товарами",
+		setup:       pvzDelete,
+	},
+	"receptions close": {
+		description: "принудительно закрыть активную приёмку ПВЗ",
+		setup:       receptionsClose,
+	},
+	"migrate": {
+		description: "применить встроенные миграции",
+		setup:       migrate,
+	},
+	"token": {
+		description: "выпустить JWT для пользователя",
+		setup:       token,
+	},
+}
+
+func required(name, value string) error {
+	if value == "" {
+		return &usageError{msg: fmt.Sprintf("не задан флаг -%s", name)}
+	}
+	return nil
+}
+
+func parseUUID(name, value string) (uuid.UUID, error) {
+	if err := required(name, value); err != nil {
+		return uuid.Nil, err
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, &usageError{msg: fmt.Sprintf("неверный идентификатор -%s: %q", name, value)}
+	}
+	return id, nil
+}
+
+func usersCreate(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	email := fs.String("email", "", "email пользователя")
+	password := fs.String("password", "", "пароль пользователя")
+	role := fs.String("role", "moderator", "роль: employee или moderator")
+	return func(ctx context.Context, c *cli) error {
+		if err := required("email", *email); err != nil {
+			return err
+		}
+		if err := required("password", *password); err != nil {
+			return err
+		}
+		user, err := c.svc.Register(ctx, &models.RegisterRequest{Email: *email, Password: *password, Role: *role})
+		if err != nil {
+			return err
+		}
+		return c.printUsers([]models.User{*user})
+	}
+}
+
+func usersList(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	return func(ctx context.Context, c *cli) error {
+		users, err := c.db.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+		return c.printUsers(users)
+	}
+}
+
+func pvzCreate(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	city := fs.String("city", "", "город: Москва, Санкт-Петербург или Казань")
+	return func(ctx context.Context, c *cli) error {
+		if err := required("city", *city); err != nil {
+			return err
+		}
+		pvz := &models.PVZ{City: *city}
+		if err := c.svc.CreatePVZ(ctx, pvz, "moderator"); err != nil {
+			return err
+		}
+		return c.printPVZs([]models.PVZ{*pvz})
+	}
+}
+
+func pvzList(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	limit := fs.Int("limit", 100, "число ПВЗ на странице")
+	page := fs.Int("page", 1, "номер страницы")
+	return func(ctx context.Context, c *cli) error {
+		if *limit < 1 || *page < 1 {
+			return &usageError{msg: "-limit и -page должны быть положительными"}
+		}
+		pvzs, err := c.db.GetPVZs(ctx, *limit, (*page-1)*(*limit))
+		if err != nil {
+			return err
+		}
+		return c.printPVZs(pvzs)
+	}
+}
+
+func pvzDelete(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	idStr := fs.String("id", "", "идентификатор ПВЗ")
+	return func(ctx context.Context, c *cli) error {
+		id, err := parseUUID("id", *idStr)
+		if err != nil {
+			return err
+		}
+		if err := c.db.DeletePVZ(ctx, id); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return fmt.Errorf("ПВЗ %s не найден", id)
+			}
+			return err
+		}
+		return c.printMessage("ПВЗ удалён", map[string]string{"id": id.String()})
+	}
+}
+
+func receptionsClose(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	pvzIdStr := fs.String("pvz", "", "идентификатор ПВЗ")
+	return func(ctx context.Context, c *cli) error {
+		pvzId, err := parseUUID("pvz", *pvzIdStr)
+		if err != nil {
+			return err
+		}
+		// Закрывать приёмку в сервисе может только сотрудник ПВЗ, pvzctl действует от его имени.
+		rec, err := c.svc.CloseLastReception(ctx, "employee", pvzId)
+		if err != nil {
+			return err
+		}
+		return c.printReceptions([]models.Reception{*rec})
+	}
+}
+
+func migrate(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	return func(ctx context.Context, c *cli) error {
+		names, err := migrations.Apply(ctx, c.pool)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(names))
+		for _, name := range names {
+			rows = append(rows, []string{name})
+		}
+		return c.print(map[string][]string{"applied": names}, []string{"MIGRATION"}, rows)
+	}
+}
+
+func token(fs *flag.FlagSet) func(ctx context.Context, c *cli) error {
+	role := fs.String("role", "", "роль: employee или moderator")
+	userIdStr := fs.String("user-id", "", "идентификатор пользователя (по умолчанию новый)")
+	email := fs.String("email", "", "email зарегистрированного пользователя вместо -user-id")
+	return func(ctx context.Context, c *cli) error {
+		userId := uuid.New()
+		tokenRole := *role
+		switch {
+		case *email != "" && *userIdStr != "":
+			return &usageError{msg: "-email и -user-id нельзя задавать одновременно"}
+		case *email != "":
+			user, err := c.db.GetUserByEmail(ctx, *email)
+			if errors.Is(err, database.ErrNotFound) {
+				return fmt.Errorf("пользователь %s не найден", *email)
+			}
+			if err != nil {
+				return err
+			}
+			userId = user.ID
+			if tokenRole == "" {
+				tokenRole = user.Role
+			}
+		case *userIdStr != "":
+			var err error
+			if userId, err = parseUUID("user-id", *userIdStr); err != nil {
+				return err
+			}
+		}
+		if err := required("role", tokenRole); err != nil {
+			return err
+		}
+		signed, err := c.svc.IssueToken(userId, tokenRole)
+		if err != nil {
+			return err
+		}
+		if c.json {
+			return c.print(map[string]string{"userId": userId.String(), "role": tokenRole, "token": signed}, nil, nil)
+		}
+		_, err = fmt.Fprintln(c.out, signed)
+		return err
+	}
+}
+
+func (c *cli) printUsers(users []models.User) error {
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []string{u.ID.String(), u.Email, u.Role})
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	return c.print(users, []string{"ID", "EMAIL", "ROLE"}, rows)
+}
+
+func (c *cli) printPVZs(pvzs []models.PVZ) error {
+	rows := make([][]string, 0, len(pvzs))
+	for _, p := range pvzs {
+		rows = append(rows, []string{p.ID.String(), p.City, p.RegistrationDate.Format(time.RFC3339)})
+	}
+	if pvzs == nil {
+		pvzs = []models.PVZ{}
+	}
+	return c.print(pvzs, []string{"ID", "CITY", "REGISTERED"}, rows)
+}
+
+func (c *cli) printReceptions(recs []models.Reception) error {
+	rows := make([][]string, 0, len(recs))
+	for _, r := range recs {
+		rows = append(rows, []string{r.ID.String(), r.PVZId.String(), r.Status, r.DateTime.Format(time.RFC3339)})
+	}
+	return c.print(recs, []string{"ID", "PVZ", "STATUS", "OPENED"}, rows)
+}
+
+func (c *cli) printMessage(message string, fields map[string]string) error {
+	if c.json {
+		out := map[string]string{"message": message}
+		for k, v := range fields {
+			out[k] = v
+		}
+		return c.print(out, nil, nil)
+	}
+	_, err := fmt.Fprintln(c.out, message)
+	return err
+}
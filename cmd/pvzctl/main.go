@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/config"
+	"pvz/internal/database"
+	"pvz/internal/services"
+)
+
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+type environment struct {
+	stdout    io.Writer
+	stderr    io.Writer
+	lookupEnv func(string) (string, bool)
+	connect   func(ctx context.Context, cfg *config.Config) (database.DBPool, func(), error)
+}
+
+type cli struct {
+	out  io.Writer
+	json bool
+	pool database.DBPool
+	db   *database.PGXDatabase
+	svc  *services.Service
+}
+
+func main() {
+	logrus.SetLevel(logrus.WarnLevel)
+	os.Exit(run(context.Background(), os.Args[1:], environment{
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		lookupEnv: os.LookupEnv,
+		connect:   connectPool,
+	}))
+}
+
+func connectPool(ctx context.Context, cfg *config.Config) (database.DBPool, func(), error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.Database.DSN())
+	if err != nil {
+		return nil, nil, err
+	}
+	poolConfig.MaxConns = 2
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pool, pool.Close, nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Использование: pvzctl [-config файл] [-o table|json] <команда> [флаги]")
+	fmt.Fprintln(w, "\nКоманды:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-18s %s\n", name, commands[name].description)
+	}
+	fmt.Fprintln(w, "\nПодключение к БД и секрет JWT берутся из той же конфигурации, что и у сервиса.")
+}
+
+func run(ctx context.Context, args []string, env environment) int {
+	fs := flag.NewFlagSet("pvzctl", flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() { usage(env.stderr) }
+	configPath := fs.String("config", "", "путь к YAML-файлу конфигурации")
+	output := fs.String("o", "table", "формат вывода: table или json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(env.stderr, "pvzctl: неизвестный формат вывода %q\n", *output)
+		return 2
+	}
+
+	name, cmd, cmdArgs := lookupCommand(fs.Args())
+	if cmd == nil {
+		if len(fs.Args()) > 0 {
+			fmt.Fprintf(env.stderr, "pvzctl: неизвестная команда %q\n\n", strings.Join(fs.Args(), " "))
+		}
+		usage(env.stderr)
+		return 2
+	}
+
+	cmdFlags := flag.NewFlagSet("pvzctl "+name, flag.ContinueOnError)
+	cmdFlags.SetOutput(env.stderr)
+	runCmd := cmd.setup(cmdFlags)
+	if err := cmdFlags.Parse(cmdArgs); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if cmdFlags.NArg() > 0 {
+		fmt.Fprintf(env.stderr, "pvzctl %s: лишние аргументы: %s\n", name, strings.Join(cmdFlags.Args(), " "))
+		return 2
+	}
+
+	var configArgs []string
+	if *configPath != "" {
+		configArgs = []string{"-config", *configPath}
+	}
+	cfg, err := config.Load(configArgs, env.lookupEnv)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "pvzctl: ошибка конфигурации:\n%v\n", err)
+		return 1
+	}
+
+	if cfg.Storage != config.StoragePostgres {
+		fmt.Fprintf(env.stderr, "pvzctl: хранилище %q не поддерживается, нужен postgres\n", cfg.Storage)
+		return 1
+	}
+
+	pool, closePool, err := env.connect(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "pvzctl: ошибка подключения к БД: %v\n", err)
+		return 1
+	}
+	defer closePool()
+
+	// Изменения из pvzctl тоже должны попасть в outbox и ленту ПВЗ, иначе потребители их не увидят.
+	var opts []database.Option
+	if cfg.Features.Events {
+		opts = append(opts, database.WithOutbox())
+	}
+	if cfg.Features.PVZEvents {
+		opts = append(opts, database.WithNotify())
+	}
+	db := database.NewPGXDatabase(pool, opts...)
+	c := &cli{
+		out:  env.stdout,
+		json: *output == "json",
+		pool: pool,
+		db:   db,
+		svc: services.NewService(db, []byte(cfg.Auth.Secret),
+			services.WithTokenTTL(cfg.Auth.TokenTTL),
+			services.WithDummyTokenTTL(cfg.Auth.DummyTokenTTL),
+		),
+	}
+
+	if err := runCmd(ctx, c); err != nil {
+		fmt.Fprintf(env.stderr, "pvzctl %s: %v\n", name, err)
+		var uErr *usageError
+		if errors.As(err, &uErr) {
+			cmdFlags.PrintDefaults()
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+func lookupCommand(args []string) (string, *command, []string) {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return args[0] + " " + args[1], &cmd, args[2:]
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := commands[args[0]]; ok {
+			return args[0], &cmd, args[1:]
+		}
+	}
+	return "", nil, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/config"
+	"pvz/internal/database"
+)
+
+var testEnv = map[string]string{
+	"DATABASE_HOST":     "localhost",
+	"DATABASE_USER":     "postgres",
+	"DATABASE_PASSWORD": "password",
+	"DATABASE_NAME":     "pvz",
+	"SECRET":            "secret",
+}
+
+func runCLI(t *testing.T, mock pgxmock.PgxPoolIface, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	connected := false
+	code := run(context.Background(), args, environment{
+		stdout: &stdout,
+		stderr: &stderr,
+		lookupEnv: func(key string) (string, bool) {
+			value, ok := testEnv[key]
+			return value, ok
+		},
+		connect: func(ctx context.Context, cfg *config.Config) (database.DBPool, func(), error) {
+			connected = true
+			return mock, func() {}, nil
+		},
+	})
+	if mock == nil {
+		assert.False(t, connected, "к БД не должно быть подключения")
+	}
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	code, _, stderr := runCLI(t, nil)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "users create")
+
+	code, _, stderr = runCLI(t, nil, "pvz", "rename")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `неизвестная команда "pvz rename"`)
+
+	code, _, stderr = runCLI(t, nil, "pvz", "create", "-h")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr, "-city")
+
+	code, _, _ = runCLI(t, nil, "-o", "yaml", "users", "list")
+	assert.Equal(t, 2, code)
+}
+
+func TestRunMissingFlag(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	code, _, stderr := runCLI(t, mock, "pvz", "delete")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "не задан флаг -id")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunUsersList(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, role FROM users ORDER BY email")).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "email", "role"}).AddRow(id, "admin@example.com", "moderator"))
+
+	code, stdout, stderr := runCLI(t, mock, "users", "list")
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "EMAIL")
+	assert.Contains(t, stdout, "admin@example.com")
+	assert.Contains(t, stdout, id.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunPVZDeleteNotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	id := uuid.New()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM pvz WHERE id=$1")).
+		WithArgs(id).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	code, _, stderr := runCLI(t, mock, "pvz", "delete", "-id", id.String())
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "не найден")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunToken(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	userID := uuid.New()
+	code, stdout, stderr := runCLI(t, mock, "-o", "json", "token", "-role", "employee", "-user-id", userID.String())
+	require.Equal(t, 0, code, stderr)
+
+	var out map[string]string
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	assert.Equal(t, userID.String(), out["userId"])
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(out["token"], claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(testEnv["SECRET"]), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "employee", claims["role"])
+
+	code, _, _ = runCLI(t, mock, "token", "-role", "admin")
+	assert.Equal(t, 1, code)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"text/tabwriter"
+)
+
+func (c *cli) print(v interface{}, headers []string, rows [][]string) error {
+	if c.json {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	if len(headers) > 0 {
+		tw.Write([]byte(strings.Join(headers, "\t") + "\n"))
+	}
+	for _, row := range rows {
+		tw.Write([]byte(strings.Join(row, "\t") + "\n"))
+	}
+	return tw.Flush()
+}
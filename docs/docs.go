@@ -0,0 +1,8 @@
+// Package docs встраивает спецификацию API в бинарник, чтобы проверять по ней
+// запросы и ответы во время работы сервиса.
+package docs
+
+import _ "embed"
+
+//go:embed swagger.yaml
+var Swagger []byte
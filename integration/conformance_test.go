@@ -0,0 +1,19 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/database/dbtest"
+)
+
+func TestPGXDatabaseConformance(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) dbtest.Storage {
+		_, err := testPool.Exec(context.Background(), `TRUNCATE users, pvz, outbox CASCADE`)
+		require.NoError(t, err)
+		return database.NewPGXDatabase(testPool, database.WithOutbox())
+	})
+}
@@ -18,16 +18,19 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 
+	"pvz/docs"
 	"pvz/internal/database"
 	"pvz/internal/middleware"
 	"pvz/internal/models"
 	"pvz/internal/services"
 	"pvz/internal/transport/rest"
+	"pvz/migrations"
 )
 
 var testServerURL string
 var postgresInstance *embeddedpostgres.EmbeddedPostgres
 var testPool *pgxpool.Pool
+var testDSN string
 
 func TestMain(m *testing.M) {
 	cfg := embeddedpostgres.DefaultConfig().
@@ -39,16 +42,16 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Не удалось запустить embedded postgres: %v", err)
 	}
 
-	connStr := cfg.GetConnectionURL()
+	testDSN = cfg.GetConnectionURL()
 	var err error
-	testPool, err = pgxpool.New(context.Background(), connStr)
+	testPool, err = pgxpool.New(context.Background(), testDSN)
 	if err != nil {
 		log.Fatalf("Не удалось создать pgxpool: %v", err)
 	}
 
 	time.Sleep(time.Second)
 
-	if err := runMigrations("../migrations/init.sql"); err != nil {
+	if _, err := migrations.Apply(context.Background(), testPool); err != nil {
 		log.Fatalf("Ошибка при миграциях: %v", err)
 	}
 
@@ -58,19 +61,26 @@ func TestMain(m *testing.M) {
 	mw := middleware.NewMiddleware(jwtSecret)
 	h := rest.NewHandler(svc)
 
-	r := mux.NewRouter()
+	validate, err := mw.OpenAPIMiddleware(docs.Swagger, middleware.OpenAPIOptions{ValidateResponses: true})
+	if err != nil {
+		log.Fatalf("Ошибка загрузки спецификации API: %v", err)
+	}
 
-	r.HandleFunc("/dummyLogin", h.DummyLoginHandler).Methods("POST")
-	r.HandleFunc("/register", h.RegisterHandler).Methods("POST")
-	r.HandleFunc("/login", h.LoginHandler).Methods("POST")
+	r := mux.NewRouter()
+	r.Handle("/dummyLogin", validate(http.HandlerFunc(h.DummyLoginHandler))).Methods("POST")
+	r.Handle("/register", validate(http.HandlerFunc(h.RegisterHandler))).Methods("POST")
+	r.Handle("/login", validate(http.HandlerFunc(h.LoginHandler))).Methods("POST")
 
-	r.Handle("/pvz", mw.AuthMiddleware(http.HandlerFunc(h.CreatePVZHandler))).Methods("POST")
-	r.Handle("/pvz", mw.AuthMiddleware(http.HandlerFunc(h.ListPVZHandler))).Methods("GET")
-	r.Handle("/pvz/{pvzId}/close_last_reception", mw.AuthMiddleware(http.HandlerFunc(h.CloseLastReceptionHandler))).Methods("POST")
-	r.Handle("/pvz/{pvzId}/delete_last_product", mw.AuthMiddleware(http.HandlerFunc(h.DeleteLastProductHandler))).Methods("POST")
+	api := r.PathPrefix("/").Subrouter()
+	api.Use(mw.AuthMiddleware)
+	api.Use(validate)
+	api.HandleFunc("/pvz", h.CreatePVZHandler).Methods("POST")
+	api.HandleFunc("/pvz", h.ListPVZHandler).Methods("GET")
+	api.HandleFunc("/pvz/{pvzId}/close_last_reception", h.CloseLastReceptionHandler).Methods("POST")
+	api.HandleFunc("/pvz/{pvzId}/delete_last_product", h.DeleteLastProductHandler).Methods("POST")
 
-	r.Handle("/receptions", mw.AuthMiddleware(http.HandlerFunc(h.CreateReceptionHandler))).Methods("POST")
-	r.Handle("/products", mw.AuthMiddleware(http.HandlerFunc(h.AddProductHandler))).Methods("POST")
+	api.HandleFunc("/receptions", h.CreateReceptionHandler).Methods("POST")
+	api.HandleFunc("/products", h.AddProductHandler).Methods("POST")
 
 	ts := httptest.NewServer(r)
 	testServerURL = ts.URL
@@ -84,15 +94,6 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-func runMigrations(path string) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	_, err = testPool.Exec(context.Background(), string(data))
-	return err
-}
-
 func TestHandlersFullFlow(t *testing.T) {
 	var modToken, empToken string
 	var pvzId uuid.UUID
@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/models"
+	"pvz/migrations"
+)
+
+// TestMigrationClosesDuplicateOpenReceptions воспроизводит базу, в которой до появления
+// индекса у ПВЗ успели открыться две приёмки: миграция закрывает старшую и создает индекс.
+func TestMigrationClosesDuplicateOpenReceptions(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewPGXDatabase(testPool)
+	pvz := &models.PVZ{City: "Москва", RegistrationDate: time.Now()}
+	require.NoError(t, db.CreatePVZ(ctx, pvz))
+
+	_, err := testPool.Exec(ctx, `DROP INDEX receptions_one_open_per_pvz_idx`)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = migrations.Apply(context.Background(), testPool)
+	})
+
+	insert := `INSERT INTO receptions (date_time, pvz_id, status) VALUES ($1, $2, 'in_progress') RETURNING id`
+	var older, newer uuid.UUID
+	require.NoError(t, testPool.QueryRow(ctx, insert, time.Now().Add(-time.Hour), pvz.ID).Scan(&older))
+	require.NoError(t, testPool.QueryRow(ctx, insert, time.Now(), pvz.ID).Scan(&newer))
+
+	_, err = migrations.Apply(ctx, testPool)
+	require.NoError(t, err)
+
+	var status string
+	var reason *string
+	var closedAt *time.Time
+	query := `SELECT status, close_reason, closed_at FROM receptions WHERE id=$1`
+	require.NoError(t, testPool.QueryRow(ctx, query, older).Scan(&status, &reason, &closedAt))
+	assert.Equal(t, "close", status)
+	require.NotNil(t, reason)
+	assert.Equal(t, models.CloseReasonMigrationDedup, *reason)
+	assert.NotNil(t, closedAt)
+
+	require.NoError(t, testPool.QueryRow(ctx, query, newer).Scan(&status, &reason, &closedAt))
+	assert.Equal(t, "in_progress", status, "последняя открытая приёмка остается открытой")
+	assert.Nil(t, reason)
+
+	_, err = testPool.Exec(ctx, `INSERT INTO receptions (date_time, pvz_id, status) VALUES (now(), $1, 'in_progress')`, pvz.ID)
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr, "после миграции индекс снова создан")
+	assert.Equal(t, database.OpenReceptionIndex, pgErr.ConstraintName)
+}
@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/models"
+)
+
+func TestListenEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan models.OutboxEvent, 16)
+	go database.ListenEvents(ctx, testDSN, func(event models.OutboxEvent) {
+		received <- event
+	})
+
+	db := database.NewPGXDatabase(testPool, database.WithNotify())
+	pvz := &models.PVZ{City: "Казань", RegistrationDate: time.Now()}
+	require.NoError(t, db.CreatePVZ(ctx, pvz))
+
+	// Слушатель подключается асинхронно, поэтому приёмка открывается и закрывается,
+	// пока он не получит первое событие.
+	deadline := time.After(10 * time.Second)
+	for {
+		_, err := db.CreateReception(ctx, pvz.ID)
+		require.NoError(t, err)
+		_, err = db.CloseLastReception(ctx, pvz.ID)
+		require.NoError(t, err)
+		select {
+		case event := <-received:
+			assert.Equal(t, pvz.ID, event.PVZId)
+			assert.Contains(t, []string{models.EventReceptionOpened, models.EventReceptionClosed}, event.Type)
+			assert.NotZero(t, event.ID)
+			return
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("событие не получено через LISTEN")
+		}
+	}
+}
+
+func TestListenPVZChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replica := database.NewCachedDatabase(database.NewPGXDatabase(testPool), time.Hour, 100)
+	invalidated := make(chan struct{}, 16)
+	go database.ListenPVZChanges(ctx, testDSN, func() {
+		replica.Invalidate()
+		invalidated <- struct{}{}
+	})
+	waitInvalidated := func() {
+		t.Helper()
+		select {
+		case <-invalidated:
+		case <-time.After(10 * time.Second):
+			t.Fatal("уведомление об изменении ПВЗ не получено")
+		}
+	}
+	// Первый вызов приходит сразу после подписки.
+	waitInvalidated()
+
+	_, err := replica.GetPVZ(ctx)
+	require.NoError(t, err)
+
+	other := database.NewPGXDatabase(testPool)
+	pvz := &models.PVZ{City: "Москва", RegistrationDate: time.Now()}
+	require.NoError(t, other.CreatePVZ(ctx, pvz))
+	waitInvalidated()
+
+	all, err := replica.GetPVZ(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, p := range all {
+		ids = append(ids, p.Id)
+	}
+	assert.Contains(t, ids, pvz.ID.String(), "ПВЗ, созданный другой репликой, виден после уведомления")
+}
@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/models"
+	"pvz/internal/problem"
+	"pvz/internal/services"
+)
+
+// TestCreateReceptionConcurrent открывает приёмку одного ПВЗ из многих горутин сразу:
+// успешно должна завершиться ровно одна попытка, остальные — получить
+// RECEPTION_ALREADY_OPEN, а не внутреннюю ошибку.
+func TestCreateReceptionConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewPGXDatabase(testPool)
+	svc := services.NewService(db, []byte("testsecret"))
+	pvz := &models.PVZ{City: "Москва", RegistrationDate: time.Now()}
+	require.NoError(t, db.CreatePVZ(ctx, pvz))
+
+	const rounds, workers = 10, 32
+	for round := 0; round < rounds; round++ {
+		var wg sync.WaitGroup
+		var created, conflicts atomic.Int32
+		start := make(chan struct{})
+		unexpected := make(chan error, workers)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, err := svc.CreateReception(ctx, "employee", pvz.ID)
+				switch {
+				case err == nil:
+					created.Add(1)
+				case errors.Is(err, services.ErrReceptionAlreadyOpen):
+					assert.Equal(t, problem.CodeReceptionAlreadyOpen, problem.From(err).Code)
+					conflicts.Add(1)
+				default:
+					unexpected <- err
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		close(unexpected)
+		for err := range unexpected {
+			t.Errorf("раунд %d: неожиданная ошибка: %v", round, err)
+		}
+		assert.EqualValues(t, 1, created.Load(), "раунд %d: открыта не одна приёмка", round)
+		assert.EqualValues(t, workers-1, conflicts.Load(), "раунд %d", round)
+
+		var open int
+		err := testPool.QueryRow(ctx, `SELECT COUNT(*) FROM receptions WHERE pvz_id=$1 AND status='in_progress'`, pvz.ID).Scan(&open)
+		require.NoError(t, err)
+		require.Equal(t, 1, open, "раунд %d: в БД больше одной открытой приёмки", round)
+
+		_, err = db.CloseLastReception(ctx, pvz.ID)
+		require.NoError(t, err)
+	}
+}
+
+// TestOpenReceptionIndex проверяет, что инвариант держит сама БД, даже если вставлять
+// приёмки в обход PGXDatabase.
+func TestOpenReceptionIndex(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewPGXDatabase(testPool)
+	pvz := &models.PVZ{City: "Казань", RegistrationDate: time.Now()}
+	require.NoError(t, db.CreatePVZ(ctx, pvz))
+
+	insert := `INSERT INTO receptions (date_time, pvz_id, status) VALUES (now(), $1, $2)`
+	_, err := testPool.Exec(ctx, insert, pvz.ID, "in_progress")
+	require.NoError(t, err)
+	_, err = testPool.Exec(ctx, insert, pvz.ID, "close")
+	require.NoError(t, err, "закрытых приёмок может быть сколько угодно")
+	_, err = testPool.Exec(ctx, insert, pvz.ID, "in_progress")
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, "23505", pgErr.Code)
+	assert.Equal(t, database.OpenReceptionIndex, pgErr.ConstraintName)
+
+	_, err = testPool.Exec(ctx, `INSERT INTO receptions (date_time, pvz_id, status, close_reason) VALUES (now(), $1, 'close', $2)`,
+		pvz.ID, models.CloseReasonMigrationDedup)
+	assert.NoError(t, err, "причина закрытия миграцией допускается ограничением close_reason")
+}
@@ -1,108 +1,348 @@
 package app
 
 import (
+	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 
+	"pvz/docs"
+	"pvz/internal/config"
 	"pvz/internal/database"
+	"pvz/internal/events"
+	"pvz/internal/idempotency"
 	"pvz/internal/metrics"
 	"pvz/internal/middleware"
+	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
+	"pvz/internal/problem"
+	"pvz/internal/ratelimit"
 	"pvz/internal/services"
+	"pvz/internal/tlsutil"
+	graphqlh "pvz/internal/transport/graphql"
 	grpch "pvz/internal/transport/grpc"
 	"pvz/internal/transport/rest"
 )
 
 type App struct {
-	pool           database.DBPool
-	port           string
-	grpcport       string
-	prometheusport string
-	jwtSecret      []byte
+	pool database.DBPool
+	cfg  *config.Config
 }
 
-func NewApp(pool database.DBPool, port string, grpcport string, prometheusport string, jwtSecret []byte) *App {
-	return &App{pool: pool, port: port, grpcport: grpcport, prometheusport: prometheusport, jwtSecret: jwtSecret}
+func NewApp(pool database.DBPool, cfg *config.Config) *App {
+	return &App{pool: pool, cfg: cfg}
 }
 
 func (a *App) Run() error {
 	logrus.Info("Приложение запускается...")
 
-	db := database.NewPGXDatabase(a.pool)
-	logrus.Info("Соединение с базой данных установлено")
+	var feed *events.Feed
+	publishToFeed := func(event models.OutboxEvent) {
+		if events.IsFeedEvent(event.Type) {
+			feed.Publish(event)
+		}
+	}
+	if a.cfg.Features.PVZEvents {
+		feed = events.NewFeed(a.cfg.Feed.BufferSize)
+	}
+
+	var db database.Database
+	var idempotencyStore idempotency.Store
+	var outboxStore events.OutboxStore
+	if a.cfg.Storage == config.StorageMemory {
+		var opts []database.MemoryOption
+		if a.cfg.Features.Events {
+			opts = append(opts, database.WithMemoryOutbox())
+		}
+		if feed != nil {
+			opts = append(opts, database.WithMemoryNotify(publishToFeed))
+		}
+		memoryDB := database.NewMemoryDatabase(opts...)
+		db, outboxStore = memoryDB, memoryDB
+		idempotencyStore = idempotency.NewMemoryStore()
+		logrus.Warn("Данные хранятся в памяти процесса и будут потеряны при перезапуске")
+	} else {
+		opts := []database.Option{database.WithSlowQueryThreshold(a.cfg.Database.SlowQueryThreshold)}
+		if a.cfg.Features.Events {
+			opts = append(opts, database.WithOutbox())
+		}
+		if feed != nil {
+			opts = append(opts, database.WithNotify())
+			go database.ListenEvents(context.Background(), a.cfg.Database.DSN(), publishToFeed)
+		}
+		pgxDB := database.NewPGXDatabase(a.pool, opts...)
+		db, idempotencyStore, outboxStore = pgxDB, pgxDB, pgxDB
+		logrus.Info("Соединение с базой данных установлено")
+		if a.cfg.Features.PVZCache {
+			cached := database.NewCachedDatabase(pgxDB, a.cfg.PVZCache.TTL, a.cfg.PVZCache.Size)
+			go database.ListenPVZChanges(context.Background(), a.cfg.Database.DSN(), cached.Invalidate)
+			db = cached
+			logrus.WithFields(logrus.Fields{
+				"ttl":  a.cfg.PVZCache.TTL,
+				"size": a.cfg.PVZCache.Size,
+			}).Info("Кеш ПВЗ включен")
+		}
+	}
+
+	if a.cfg.Features.Events {
+		publisher, err := a.newPublisher()
+		if err != nil {
+			return err
+		}
+		defer publisher.Close()
+		relay := events.NewRelay(outboxStore, publisher,
+			events.WithInterval(a.cfg.Events.PollInterval),
+			events.WithBatchSize(a.cfg.Events.BatchSize),
+		)
+		go relay.Run(context.Background())
+		logrus.WithField("publisher", a.cfg.Events.Publisher).Info("Публикация доменных событий включена")
+	}
 
-	service := services.NewService(db, a.jwtSecret)
+	service := services.NewService(db, []byte(a.cfg.Auth.Secret),
+		services.WithTokenTTL(a.cfg.Auth.TokenTTL),
+		services.WithDummyTokenTTL(a.cfg.Auth.DummyTokenTTL),
+		services.WithFeed(feed),
+	)
 	logrus.Info("Сервис инициализирован")
 
+	go a.refreshOpenReceptions(service)
+	if a.cfg.Features.Idempotency {
+		go a.deleteExpiredIdempotencyKeys(idempotencyStore)
+	}
+	if a.cfg.Features.AutoClose {
+		go a.autoCloseReceptions(service)
+	}
+
 	handler := rest.NewHandler(service)
 	logrus.Info("Обработчики REST-запросов инициализированы")
 
-	middle := middleware.NewMiddleware(a.jwtSecret)
+	middle := middleware.NewMiddleware([]byte(a.cfg.Auth.Secret))
+	var rateLimits map[string]ratelimit.Rule
+	if a.cfg.Features.RateLimiting {
+		rateLimits = a.cfg.RateLimits
+	}
+	limiter := ratelimit.NewLimiter(rateLimits)
 
 	router := mux.NewRouter()
+	router.Use(middle.RequestIDMiddleware)
 	router.Use(middle.MetricsMiddleware)
+	// Ответы API по умолчанию не кешируются: в них токены и персональные данные.
+	router.Use(middle.CacheControlMiddleware("no-store"))
+	// Проверка по спецификации идет после аутентификации и лимитов, чтобы анонимные и
+	// сверхлимитные запросы отсекались до разбора тела.
+	validate := func(next http.Handler) http.Handler { return next }
+	if a.cfg.Features.OpenAPI {
+		var err error
+		validate, err = middle.OpenAPIMiddleware(docs.Swagger, middleware.OpenAPIOptions{
+			ValidateResponses: a.cfg.Server.ValidateResponses,
+		})
+		if err != nil {
+			return err
+		}
+		logrus.WithField("validate_responses", a.cfg.Server.ValidateResponses).Info("Проверка запросов по спецификации API включена")
+	}
+	router.NotFoundHandler = middle.RequestIDMiddleware(middle.MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "Маршрут не найден")
+	})))
+	router.MethodNotAllowedHandler = middle.RequestIDMiddleware(middle.MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "Метод не поддерживается")
+	})))
 
-	router.HandleFunc("/dummyLogin", handler.DummyLoginHandler).Methods("POST")
-	router.HandleFunc("/register", handler.RegisterHandler).Methods("POST")
-	router.HandleFunc("/login", handler.LoginHandler).Methods("POST")
+	publicLimit := middle.RateLimitMiddleware(limiter)
+	if a.cfg.Features.DummyLogin {
+		router.Handle("/dummyLogin", publicLimit(validate(http.HandlerFunc(handler.DummyLoginHandler)))).Methods("POST")
+	}
+	router.Handle("/register", publicLimit(validate(http.HandlerFunc(handler.RegisterHandler)))).Methods("POST")
+	router.Handle("/login", publicLimit(validate(http.HandlerFunc(handler.LoginHandler)))).Methods("POST")
+	if a.cfg.Features.GraphQL {
+		// Вход и регистрация в GraphQL идут без токена, поэтому токен проверяется, только если передан.
+		graphqlHandler := graphqlh.NewHandler(service, graphqlh.WithDummyLogin(a.cfg.Features.DummyLogin))
+		router.Handle("/graphql", middle.OptionalAuthMiddleware(publicLimit(graphqlHandler))).Methods("POST")
+	}
 
 	api := router.PathPrefix("/").Subrouter()
 	api.Use(middle.AuthMiddleware)
+	api.Use(middle.RateLimitMiddleware(limiter))
+	api.Use(validate)
+
+	idempotent := func(next http.Handler) http.Handler { return next }
+	if a.cfg.Features.Idempotency {
+		idempotent = middle.IdempotencyMiddleware(idempotencyStore, a.cfg.Idempotency.TTL)
+	}
 
-	api.HandleFunc("/pvz", handler.CreatePVZHandler).Methods("POST")
-	api.HandleFunc("/pvz", handler.ListPVZHandler).Methods("GET")
+	api.Handle("/pvz", idempotent(http.HandlerFunc(handler.CreatePVZHandler))).Methods("POST")
+	// Список ПВЗ отдается с ETag: клиент хранит ответ и перепроверяет его условным запросом.
+	api.Handle("/pvz", middle.CacheControlMiddleware("private, no-cache")(http.HandlerFunc(handler.ListPVZHandler))).Methods("GET")
 	api.HandleFunc("/pvz/{pvzId}/close_last_reception", handler.CloseLastReceptionHandler).Methods("POST")
 	api.HandleFunc("/pvz/{pvzId}/delete_last_product", handler.DeleteLastProductHandler).Methods("POST")
+	if a.cfg.Features.PVZEvents {
+		api.HandleFunc("/pvz/{pvzId}/events", handler.PVZEventsHandler).Methods("GET")
+	}
+	api.HandleFunc("/exports/receptions", handler.ExportReceptionsHandler).Methods("GET")
+	// Агрегаты для дашбордов допускают отставание на минуту.
+	api.Handle("/analytics/intake", middle.CacheControlMiddleware("private, max-age=60")(http.HandlerFunc(handler.IntakeAnalyticsHandler))).Methods("GET")
 
-	api.HandleFunc("/receptions", handler.CreateReceptionHandler).Methods("POST")
-	api.HandleFunc("/products", handler.AddProductHandler).Methods("POST")
+	api.Handle("/receptions", idempotent(http.HandlerFunc(handler.CreateReceptionHandler))).Methods("POST")
+	api.Handle("/products", idempotent(http.HandlerFunc(handler.AddProductHandler))).Methods("POST")
 	logrus.Info("Маршруты зарегистрированы")
 
-	const readmax, writemax, idlemax = 5 * time.Second, 10 * time.Second, 120 * time.Second
+	cors := a.cfg.Server.CORS
+	httpHandler := middle.SecurityHeadersMiddleware(a.cfg.Server.HSTSMaxAge)(
+		middle.CORSMiddleware(middleware.CORSOptions{
+			AllowedOrigins:   cors.AllowedOrigins,
+			AllowedMethods:   cors.AllowedMethods,
+			AllowedHeaders:   cors.AllowedHeaders,
+			ExposedHeaders:   cors.ExposedHeaders,
+			AllowCredentials: cors.AllowCredentials,
+			MaxAge:           cors.MaxAge,
+		})(
+			middle.BodyLimitMiddleware(a.cfg.Server.MaxBodyBytes)(router),
+		),
+	)
+
 	server := &http.Server{
-		Addr:         ":" + a.port,
-		Handler:      router,
-		ReadTimeout:  readmax,
-		WriteTimeout: writemax,
-		IdleTimeout:  idlemax,
+		Addr:         ":" + a.cfg.Server.HTTPPort,
+		Handler:      httpHandler,
+		ReadTimeout:  a.cfg.Server.ReadTimeout,
+		WriteTimeout: a.cfg.Server.WriteTimeout,
+		IdleTimeout:  a.cfg.Server.IdleTimeout,
 	}
-	logrus.Infof("HTTP сервер настроен и будет запущен на порту: %s", a.port)
-
-	lis, err := net.Listen("tcp", ":"+a.grpcport)
-	if err != nil {
-		return err
+	if a.cfg.Server.HTTPTLS.Enabled() {
+		tlsConfig, err := serverTLSConfig(a.cfg.Server.HTTPTLS, "")
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = tlsConfig
+		logrus.Info("Для HTTP сервера включен TLS")
 	}
-	grpcServer := grpc.NewServer()
-
-	srv := grpch.NewGrpcServer(service)
-	pb.RegisterPVZServiceServer(grpcServer, srv)
-	reflection.Register(grpcServer)
-	logrus.Infof("GRPC сервер запущен на порту: %s", a.grpcport)
+	logrus.Infof("HTTP сервер настроен и будет запущен на порту: %s", a.cfg.Server.HTTPPort)
 
 	errChan := make(chan error, 3)
 
-	go func() {
-		logrus.Info("GRPC сервер начинает обслуживание подключений")
-		errChan <- grpcServer.Serve(lis)
-	}()
+	if a.cfg.Features.GRPC {
+		lis, err := net.Listen("tcp", ":"+a.cfg.Server.GRPCPort)
+		if err != nil {
+			return err
+		}
+		var serverOpts []grpc.ServerOption
+		interceptors := []grpc.UnaryServerInterceptor{grpch.RequestIDInterceptor, grpch.LanguageInterceptor}
+		streamInterceptors := []grpc.StreamServerInterceptor{grpch.RequestIDStreamInterceptor, grpch.LanguageStreamInterceptor}
+		if tlsCfg := a.cfg.Server.GRPCTLS; tlsCfg.Enabled() {
+			tlsConfig, err := serverTLSConfig(tlsCfg.TLSConfig, tlsCfg.ClientCAFile)
+			if err != nil {
+				return err
+			}
+			serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+			if tlsCfg.ClientCAFile != "" {
+				interceptors = append(interceptors, grpch.ClientCertInterceptor(tlsCfg.ClientRoles))
+				streamInterceptors = append(streamInterceptors, grpch.ClientCertStreamInterceptor(tlsCfg.ClientRoles))
+				logrus.Info("Для GRPC сервера включен mTLS")
+			} else {
+				logrus.Info("Для GRPC сервера включен TLS")
+			}
+		}
+		interceptors = append(interceptors, grpch.RateLimitInterceptor(limiter))
+		streamInterceptors = append(streamInterceptors, grpch.RateLimitStreamInterceptor(limiter))
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(interceptors...), grpc.ChainStreamInterceptor(streamInterceptors...))
+		grpcServer := grpc.NewServer(serverOpts...)
+
+		srv := grpch.NewGrpcServer(service)
+		pb.RegisterPVZServiceServer(grpcServer, srv)
+		if a.cfg.Features.GRPCReflection {
+			reflection.Register(grpcServer)
+		}
+		logrus.Infof("GRPC сервер запущен на порту: %s", a.cfg.Server.GRPCPort)
+
+		go func() {
+			logrus.Info("GRPC сервер начинает обслуживание подключений")
+			errChan <- grpcServer.Serve(lis)
+		}()
+	}
 
 	go func() {
 		logrus.Info("HTTP сервер начинает обслуживание запросов")
+		if server.TLSConfig != nil {
+			errChan <- server.ListenAndServeTLS("", "")
+			return
+		}
 		errChan <- server.ListenAndServe()
 	}()
 
-	go func() {
-		logrus.Infof("Сервер метрик запущен на порту: %s", a.prometheusport)
-		http.Handle("/metrics", metrics.Handler())
-		errChan <- http.ListenAndServe(":"+a.prometheusport, nil)
-	}()
+	if a.cfg.Features.Metrics {
+		go func() {
+			logrus.Infof("Сервер метрик запущен на порту: %s", a.cfg.Server.MetricsPort)
+			var collectors []prometheus.Collector
+			if statter, ok := a.pool.(metrics.PoolStatter); ok {
+				collectors = append(collectors, metrics.NewPoolCollector(statter))
+			}
+			http.Handle("/metrics", metrics.Handler(collectors...))
+			errChan <- http.ListenAndServe(":"+a.cfg.Server.MetricsPort, nil)
+		}()
+	}
+
+	return <-errChan
+}
+
+func (a *App) newPublisher() (events.Publisher, error) {
+	if a.cfg.Events.Publisher == config.PublisherInProcess {
+		return events.NewInProcessPublisher(), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return events.NewNATSPublisher(ctx, a.cfg.Events.NATSURL, a.cfg.Events.NATSStream, a.cfg.Events.SubjectPrefix)
+}
 
-	err = <-errChan
-	return err
+func serverTLSConfig(tlsCfg config.TLSConfig, clientCAFile string) (*tls.Config, error) {
+	reloader, err := tlsutil.NewCertReloader(tlsCfg.CertFile, tlsCfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return tlsutil.ServerConfig(reloader, clientCAFile)
+}
+
+func (a *App) refreshOpenReceptions(service *services.Service) {
+	const refreshInterval = time.Minute
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := service.RefreshOpenReceptions(context.Background()); err != nil {
+			logrus.WithError(err).Error("Ошибка обновления метрики открытых приёмок")
+		}
+		<-ticker.C
+	}
+}
+
+func (a *App) deleteExpiredIdempotencyKeys(store idempotency.Store) {
+	const cleanupInterval = time.Hour
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := store.DeleteExpiredIdempotencyKeys(context.Background())
+		if err != nil {
+			logrus.WithError(err).Error("Ошибка удаления устаревших ключей идемпотентности")
+		} else if deleted > 0 {
+			logrus.WithField("deleted", deleted).Info("Удалены устаревшие ключи идемпотентности")
+		}
+		<-ticker.C
+	}
+}
+
+func (a *App) autoCloseReceptions(service *services.Service) {
+	limits := models.AutoCloseLimits{Default: a.cfg.AutoClose.MaxOpen, ByCity: a.cfg.AutoClose.CityLimits}
+	ticker := time.NewTicker(a.cfg.AutoClose.Interval)
+	defer ticker.Stop()
+	for {
+		if err := service.AutoCloseReceptions(context.Background(), limits); err != nil {
+			logrus.WithError(err).Error("Ошибка автоматического закрытия приёмок")
+		}
+		<-ticker.C
+	}
 }
@@ -0,0 +1,715 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"pvz/internal/logger"
+	"pvz/internal/ratelimit"
+)
+
+const redacted = "******"
+
+var knownCities = map[string]bool{"Москва": true, "Санкт-Петербург": true, "Казань": true}
+
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+type Config struct {
+	Server      ServerConfig              `yaml:"server"`
+	Storage     string                    `yaml:"storage"`
+	Database    DatabaseConfig            `yaml:"database"`
+	Auth        AuthConfig                `yaml:"auth"`
+	Log         LogConfig                 `yaml:"log"`
+	Idempotency IdempotencyConfig         `yaml:"idempotency"`
+	AutoClose   AutoCloseConfig           `yaml:"auto_close"`
+	Events      EventsConfig              `yaml:"events"`
+	Feed        FeedConfig                `yaml:"feed"`
+	PVZCache    PVZCacheConfig            `yaml:"pvz_cache"`
+	RateLimits  map[string]ratelimit.Rule `yaml:"rate_limits"`
+	Features    FeaturesConfig            `yaml:"features"`
+}
+
+type ServerConfig struct {
+	HTTPPort     string        `yaml:"http_port"`
+	GRPCPort     string        `yaml:"grpc_port"`
+	MetricsPort  string        `yaml:"metrics_port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	MaxBodyBytes int64         `yaml:"max_body_bytes"`
+	HSTSMaxAge   time.Duration `yaml:"hsts_max_age"`
+	CORS         CORSConfig    `yaml:"cors"`
+	HTTPTLS      TLSConfig     `yaml:"http_tls"`
+	GRPCTLS      GRPCTLSConfig `yaml:"grpc_tls"`
+	// ValidateResponses проверяет и ответы по спецификации API; включается в тестовых
+	// окружениях, чтобы расхождение ответа со спецификацией давало 500.
+	ValidateResponses bool `yaml:"validate_responses"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+type GRPCTLSConfig struct {
+	TLSConfig    `yaml:",inline"`
+	ClientCAFile string            `yaml:"client_ca_file"`
+	ClientRoles  map[string]string `yaml:"client_roles"`
+}
+
+type DatabaseConfig struct {
+	Host               string        `yaml:"host"`
+	Port               string        `yaml:"port"`
+	User               string        `yaml:"user"`
+	Password           string        `yaml:"password"`
+	Name               string        `yaml:"name"`
+	MaxConns           int32         `yaml:"max_conns"`
+	MinConns           int32         `yaml:"min_conns"`
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+}
+
+type AuthConfig struct {
+	Secret        string        `yaml:"secret"`
+	TokenTTL      time.Duration `yaml:"token_ttl"`
+	DummyTokenTTL time.Duration `yaml:"dummy_token_ttl"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// AutoCloseConfig задает, как долго приёмка может оставаться открытой до автоматического
+// закрытия: MaxOpen по умолчанию и CityLimits для отдельных городов.
+type AutoCloseConfig struct {
+	Interval   time.Duration            `yaml:"interval"`
+	MaxOpen    time.Duration            `yaml:"max_open"`
+	CityLimits map[string]time.Duration `yaml:"city_limits"`
+}
+
+const (
+	PublisherNATS      = "nats"
+	PublisherInProcess = "inprocess"
+)
+
+type EventsConfig struct {
+	Publisher     string        `yaml:"publisher"`
+	NATSURL       string        `yaml:"nats_url"`
+	NATSStream    string        `yaml:"nats_stream"`
+	SubjectPrefix string        `yaml:"subject_prefix"`
+	PollInterval  time.Duration `yaml:"poll_interval"`
+	BatchSize     int           `yaml:"batch_size"`
+}
+
+// FeedConfig задает, сколько последних событий хранится для продолжения SSE по Last-Event-ID.
+type FeedConfig struct {
+	BufferSize int `yaml:"buffer_size"`
+}
+
+// PVZCacheConfig задает кеш чтения ПВЗ: время жизни записи и число записей.
+type PVZCacheConfig struct {
+	TTL  time.Duration `yaml:"ttl"`
+	Size int           `yaml:"size"`
+}
+
+type FeaturesConfig struct {
+	DummyLogin     bool `yaml:"dummy_login"`
+	GRPC           bool `yaml:"grpc"`
+	GRPCReflection bool `yaml:"grpc_reflection"`
+	Metrics        bool `yaml:"metrics"`
+	RateLimiting   bool `yaml:"rate_limiting"`
+	Idempotency    bool `yaml:"idempotency"`
+	AutoClose      bool `yaml:"auto_close"`
+	Events         bool `yaml:"events"`
+	PVZEvents      bool `yaml:"pvz_events"`
+	GraphQL        bool `yaml:"graphql"`
+	OpenAPI        bool `yaml:"openapi_validation"`
+	PVZCache       bool `yaml:"pvz_cache"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			HTTPPort:     "8080",
+			GRPCPort:     "3000",
+			MetricsPort:  "9000",
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+			IdleTimeout:  120 * time.Second,
+			MaxBodyBytes: 1 << 20,
+			HSTSMaxAge:   365 * 24 * time.Hour,
+			CORS: CORSConfig{
+				AllowedOrigins: []string{},
+				AllowedMethods: []string{"GET", "POST"},
+				AllowedHeaders: []string{"Authorization", "Content-Type", "Idempotency-Key", "If-None-Match", "X-Request-ID"},
+				ExposedHeaders: []string{"X-Request-ID", "Retry-After", "Idempotent-Replayed", "Content-Disposition", "ETag"},
+				MaxAge:         10 * time.Minute,
+			},
+			GRPCTLS: GRPCTLSConfig{
+				ClientRoles: map[string]string{},
+			},
+		},
+		Storage: StoragePostgres,
+		Database: DatabaseConfig{
+			Port:               "5432",
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Auth: AuthConfig{
+			TokenTTL:      72 * time.Hour,
+			DummyTokenTTL: 72 * time.Hour,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logger.FormatText,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		AutoClose: AutoCloseConfig{
+			Interval:   5 * time.Minute,
+			MaxOpen:    12 * time.Hour,
+			CityLimits: map[string]time.Duration{},
+		},
+		Events: EventsConfig{
+			Publisher:     PublisherNATS,
+			NATSURL:       "nats://localhost:4222",
+			NATSStream:    "PVZ_EVENTS",
+			SubjectPrefix: "pvz.events",
+			PollInterval:  time.Second,
+			BatchSize:     100,
+		},
+		Feed: FeedConfig{
+			BufferSize: 1024,
+		},
+		PVZCache: PVZCacheConfig{
+			TTL:  time.Minute,
+			Size: 1000,
+		},
+		RateLimits: map[string]ratelimit.Rule{},
+		Features: FeaturesConfig{
+			DummyLogin:     true,
+			GRPC:           true,
+			GRPCReflection: true,
+			Metrics:        true,
+			RateLimiting:   true,
+			Idempotency:    true,
+			AutoClose:      true,
+			PVZEvents:      true,
+			GraphQL:        true,
+			OpenAPI:        true,
+			PVZCache:       true,
+		},
+	}
+}
+
+type binding struct {
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+}
+
+// bindings описывает, какие переменные окружения и флаги переопределяют поля cfg.
+// Секреты задаются только через файл или окружение, чтобы не попадать в список процессов.
+func bindings(cfg *Config) []binding {
+	return []binding{
+		{"SERVER_PORT", "http-port", "порт HTTP сервера", setString(&cfg.Server.HTTPPort)},
+		{"GRPC_PORT", "grpc-port", "порт gRPC сервера", setString(&cfg.Server.GRPCPort)},
+		{"PROMETHEUS_PORT", "metrics-port", "порт сервера метрик", setString(&cfg.Server.MetricsPort)},
+		{"HTTP_READ_TIMEOUT", "http-read-timeout", "таймаут чтения запроса", setDuration(&cfg.Server.ReadTimeout)},
+		{"HTTP_WRITE_TIMEOUT", "http-write-timeout", "таймаут записи ответа", setDuration(&cfg.Server.WriteTimeout)},
+		{"HTTP_IDLE_TIMEOUT", "http-idle-timeout", "таймаут простоя keep-alive соединения", setDuration(&cfg.Server.IdleTimeout)},
+		{"MAX_BODY_BYTES", "max-body-bytes", "максимальный размер тела запроса в байтах", setInt64(&cfg.Server.MaxBodyBytes)},
+		{"HSTS_MAX_AGE", "hsts-max-age", "max-age заголовка Strict-Transport-Security (0 — не отправлять)", setDuration(&cfg.Server.HSTSMaxAge)},
+		{"CORS_ALLOWED_ORIGINS", "cors-allowed-origins", "разрешенные источники CORS через запятую, пусто — CORS выключен", setStringList(&cfg.Server.CORS.AllowedOrigins)},
+		{"CORS_ALLOWED_METHODS", "cors-allowed-methods", "разрешенные методы CORS через запятую", setStringList(&cfg.Server.CORS.AllowedMethods)},
+		{"CORS_ALLOWED_HEADERS", "cors-allowed-headers", "разрешенные заголовки CORS через запятую", setStringList(&cfg.Server.CORS.AllowedHeaders)},
+		{"CORS_EXPOSED_HEADERS", "cors-exposed-headers", "заголовки ответа, доступные браузеру, через запятую", setStringList(&cfg.Server.CORS.ExposedHeaders)},
+		{"CORS_ALLOW_CREDENTIALS", "cors-allow-credentials", "разрешить передачу учетных данных CORS", setBool(&cfg.Server.CORS.AllowCredentials)},
+		{"CORS_MAX_AGE", "cors-max-age", "время кеширования preflight-ответа", setDuration(&cfg.Server.CORS.MaxAge)},
+		{"HTTP_TLS_CERT_FILE", "http-tls-cert", "сертификат HTTP сервера (PEM), включает TLS", setString(&cfg.Server.HTTPTLS.CertFile)},
+		{"HTTP_TLS_KEY_FILE", "http-tls-key", "ключ сертификата HTTP сервера (PEM)", setString(&cfg.Server.HTTPTLS.KeyFile)},
+		{"GRPC_TLS_CERT_FILE", "grpc-tls-cert", "сертификат gRPC сервера (PEM), включает TLS", setString(&cfg.Server.GRPCTLS.CertFile)},
+		{"GRPC_TLS_KEY_FILE", "grpc-tls-key", "ключ сертификата gRPC сервера (PEM)", setString(&cfg.Server.GRPCTLS.KeyFile)},
+		{"GRPC_TLS_CLIENT_CA_FILE", "grpc-tls-client-ca", "CA клиентских сертификатов, включает mTLS", setString(&cfg.Server.GRPCTLS.ClientCAFile)},
+		{"GRPC_TLS_CLIENT_ROLES", "grpc-tls-client-roles", "роли клиентов mTLS вида CN=роль через запятую", setStringMap(&cfg.Server.GRPCTLS.ClientRoles)},
+		{"VALIDATE_RESPONSES", "validate-responses", "проверять ответы по спецификации API (для тестовых окружений)", setBool(&cfg.Server.ValidateResponses)},
+		{"STORAGE", "storage", "хранилище: postgres или memory (данные в памяти процесса)", setString(&cfg.Storage)},
+		{"DATABASE_HOST", "db-host", "адрес БД", setString(&cfg.Database.Host)},
+		{"DATABASE_PORT", "db-port", "порт БД", setString(&cfg.Database.Port)},
+		{"DATABASE_USER", "db-user", "пользователь БД", setString(&cfg.Database.User)},
+		{"DATABASE_PASSWORD", "", "", setString(&cfg.Database.Password)},
+		{"DATABASE_NAME", "db-name", "имя БД", setString(&cfg.Database.Name)},
+		{"DATABASE_MAX_CONNS", "db-max-conns", "максимальный размер пула соединений (0 — по умолчанию pgx)", setInt32(&cfg.Database.MaxConns)},
+		{"DATABASE_MIN_CONNS", "db-min-conns", "минимальный размер пула соединений", setInt32(&cfg.Database.MinConns)},
+		{"SLOW_QUERY_THRESHOLD", "slow-query-threshold", "порог логирования медленных запросов (0 — выключено)", setDuration(&cfg.Database.SlowQueryThreshold)},
+		{"SECRET", "", "", setString(&cfg.Auth.Secret)},
+		{"TOKEN_TTL", "token-ttl", "время жизни токена после /login", setDuration(&cfg.Auth.TokenTTL)},
+		{"DUMMY_TOKEN_TTL", "dummy-token-ttl", "время жизни токена после /dummyLogin", setDuration(&cfg.Auth.DummyTokenTTL)},
+		{"LOG_LEVEL", "log-level", "уровень логирования", setString(&cfg.Log.Level)},
+		{"LOG_FORMAT", "log-format", "формат логов: text или json", setString(&cfg.Log.Format)},
+		{"IDEMPOTENCY_TTL", "idempotency-ttl", "время хранения ключей идемпотентности", setDuration(&cfg.Idempotency.TTL)},
+		{"AUTO_CLOSE_INTERVAL", "auto-close-interval", "период проверки забытых приёмок", setDuration(&cfg.AutoClose.Interval)},
+		{"AUTO_CLOSE_MAX_OPEN", "auto-close-max-open", "сколько приёмка может быть открыта до автозакрытия", setDuration(&cfg.AutoClose.MaxOpen)},
+		{"AUTO_CLOSE_CITY_LIMITS", "auto-close-city-limits", "лимиты открытой приёмки по городам вида город=длительность через запятую", setDurationMap(&cfg.AutoClose.CityLimits)},
+		{"EVENTS_PUBLISHER", "events-publisher", "публикация событий: nats или inprocess (только внутри процесса)", setString(&cfg.Events.Publisher)},
+		{"NATS_URL", "nats-url", "адрес NATS", setString(&cfg.Events.NATSURL)},
+		{"NATS_STREAM", "nats-stream", "поток JetStream для событий", setString(&cfg.Events.NATSStream)},
+		{"EVENTS_SUBJECT_PREFIX", "events-subject-prefix", "префикс тем событий", setString(&cfg.Events.SubjectPrefix)},
+		{"EVENTS_POLL_INTERVAL", "events-poll-interval", "период чтения outbox", setDuration(&cfg.Events.PollInterval)},
+		{"EVENTS_BATCH_SIZE", "events-batch-size", "число событий outbox за один проход", setInt(&cfg.Events.BatchSize)},
+		{"FEED_BUFFER_SIZE", "feed-buffer-size", "сколько последних событий хранится для продолжения SSE по Last-Event-ID", setInt(&cfg.Feed.BufferSize)},
+		{"PVZ_CACHE_TTL", "pvz-cache-ttl", "время жизни записи кеша ПВЗ", setDuration(&cfg.PVZCache.TTL)},
+		{"PVZ_CACHE_SIZE", "pvz-cache-size", "число записей в кеше ПВЗ", setInt(&cfg.PVZCache.Size)},
+		{"RATE_LIMITS", "rate-limits", "лимиты запросов вида маршрут=скорость:корзина через запятую", setRateLimits(&cfg.RateLimits)},
+		{"FEATURE_DUMMY_LOGIN", "feature-dummy-login", "включить /dummyLogin", setBool(&cfg.Features.DummyLogin)},
+		{"FEATURE_GRPC", "feature-grpc", "включить gRPC сервер", setBool(&cfg.Features.GRPC)},
+		{"FEATURE_GRPC_REFLECTION", "feature-grpc-reflection", "включить gRPC reflection", setBool(&cfg.Features.GRPCReflection)},
+		{"FEATURE_METRICS", "feature-metrics", "включить сервер метрик", setBool(&cfg.Features.Metrics)},
+		{"FEATURE_RATE_LIMITING", "feature-rate-limiting", "включить ограничение частоты запросов", setBool(&cfg.Features.RateLimiting)},
+		{"FEATURE_IDEMPOTENCY", "feature-idempotency", "включить ключи идемпотентности", setBool(&cfg.Features.Idempotency)},
+		{"FEATURE_EVENTS", "feature-events", "включить outbox и публикацию доменных событий", setBool(&cfg.Features.Events)},
+		{"FEATURE_PVZ_EVENTS", "feature-pvz-events", "включить GET /pvz/{pvzId}/events (SSE)", setBool(&cfg.Features.PVZEvents)},
+		{"FEATURE_GRAPHQL", "feature-graphql", "включить /graphql", setBool(&cfg.Features.GraphQL)},
+		{"FEATURE_OPENAPI_VALIDATION", "feature-openapi-validation", "проверять запросы по спецификации API", setBool(&cfg.Features.OpenAPI)},
+		{"FEATURE_AUTO_CLOSE", "feature-auto-close", "включить автоматическое закрытие забытых приёмок", setBool(&cfg.Features.AutoClose)},
+		{"FEATURE_PVZ_CACHE", "feature-pvz-cache", "кешировать чтение ПВЗ в памяти процесса", setBool(&cfg.Features.PVZCache)},
+	}
+}
+
+// Load собирает конфигурацию: значения по умолчанию, затем YAML-файл (флаг -config
+// или CONFIG_FILE), затем переменные окружения и, наконец, флаги командной строки.
+// При ошибке валидации возвращается и сама конфигурация, чтобы ее можно было показать.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	binds := bindings(cfg)
+
+	fs := flag.NewFlagSet("pvz", flag.ContinueOnError)
+	configPath, _ := lookupEnv("CONFIG_FILE")
+	fs.StringVar(&configPath, "config", configPath, "путь к YAML-файлу конфигурации")
+	byFlag := make(map[string]binding)
+	for _, b := range binds {
+		if b.flag == "" {
+			continue
+		}
+		byFlag[b.flag] = b
+		fs.String(b.flag, "", fmt.Sprintf("%s (%s)", b.usage, b.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("неизвестные аргументы: %v", fs.Args())
+	}
+
+	if configPath != "" {
+		if err := cfg.loadFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, b := range binds {
+		value, ok := lookupEnv(b.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := b.set(value); err != nil {
+			return nil, fmt.Errorf("переменная окружения %s: %w", b.env, err)
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		b, ok := byFlag[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := b.set(f.Value.String()); err != nil {
+			flagErr = fmt.Errorf("флаг -%s: %w", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла конфигурации: %w", err)
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("ошибка разбора файла конфигурации %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+	port := func(field, value string) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 65535 {
+			fail(field, "неверный порт %q", value)
+		}
+	}
+	positive := func(field string, d time.Duration) {
+		if d <= 0 {
+			fail(field, "должно быть больше нуля, получено %s", d)
+		}
+	}
+	required := func(field, env, value string) {
+		if value == "" {
+			fail(field, "значение не задано (%s)", env)
+		}
+	}
+
+	port("server.http_port", c.Server.HTTPPort)
+	if c.Features.GRPC {
+		port("server.grpc_port", c.Server.GRPCPort)
+		if c.Server.GRPCPort == c.Server.HTTPPort {
+			fail("server.grpc_port", "совпадает с server.http_port")
+		}
+	}
+	if c.Features.Metrics {
+		port("server.metrics_port", c.Server.MetricsPort)
+		if c.Server.MetricsPort == c.Server.HTTPPort || (c.Features.GRPC && c.Server.MetricsPort == c.Server.GRPCPort) {
+			fail("server.metrics_port", "совпадает с портом другого сервера")
+		}
+	}
+	positive("server.read_timeout", c.Server.ReadTimeout)
+	positive("server.write_timeout", c.Server.WriteTimeout)
+	positive("server.idle_timeout", c.Server.IdleTimeout)
+	if c.Server.MaxBodyBytes <= 0 {
+		fail("server.max_body_bytes", "должно быть больше нуля")
+	}
+	if c.Server.HSTSMaxAge < 0 {
+		fail("server.hsts_max_age", "не может быть отрицательным")
+	}
+	for _, origin := range c.Server.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.Server.CORS.AllowCredentials {
+				fail("server.cors.allowed_origins", "\"*\" нельзя сочетать с allow_credentials")
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			fail("server.cors.allowed_origins", "неверный источник %q, ожидается схема://хост[:порт]", origin)
+		}
+	}
+	if len(c.Server.CORS.AllowedOrigins) > 0 && len(c.Server.CORS.AllowedMethods) == 0 {
+		fail("server.cors.allowed_methods", "список не может быть пустым")
+	}
+	if c.Server.CORS.MaxAge < 0 {
+		fail("server.cors.max_age", "не может быть отрицательным")
+	}
+	tlsFiles := func(field string, tc TLSConfig) {
+		if (tc.CertFile == "") != (tc.KeyFile == "") {
+			fail(field, "cert_file и key_file задаются вместе")
+		}
+		for _, file := range []string{tc.CertFile, tc.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				fail(field, "файл %s недоступен", file)
+			}
+		}
+	}
+	tlsFiles("server.http_tls", c.Server.HTTPTLS)
+	tlsFiles("server.grpc_tls", c.Server.GRPCTLS.TLSConfig)
+	if c.Server.GRPCTLS.ClientCAFile != "" {
+		if !c.Server.GRPCTLS.Enabled() {
+			fail("server.grpc_tls.client_ca_file", "mTLS требует server.grpc_tls.cert_file")
+		}
+		if _, err := os.Stat(c.Server.GRPCTLS.ClientCAFile); err != nil {
+			fail("server.grpc_tls.client_ca_file", "файл %s недоступен", c.Server.GRPCTLS.ClientCAFile)
+		}
+	}
+	if len(c.Server.GRPCTLS.ClientRoles) > 0 && c.Server.GRPCTLS.ClientCAFile == "" {
+		fail("server.grpc_tls.client_roles", "роли клиентов требуют server.grpc_tls.client_ca_file")
+	}
+	for cn, role := range c.Server.GRPCTLS.ClientRoles {
+		if role != "employee" && role != "moderator" {
+			fail("server.grpc_tls.client_roles."+cn, "неизвестная роль %q", role)
+		}
+	}
+
+	switch c.Storage {
+	case StoragePostgres:
+		required("database.host", "DATABASE_HOST", c.Database.Host)
+		port("database.port", c.Database.Port)
+		required("database.user", "DATABASE_USER", c.Database.User)
+		required("database.password", "DATABASE_PASSWORD", c.Database.Password)
+		required("database.name", "DATABASE_NAME", c.Database.Name)
+	case StorageMemory:
+	default:
+		fail("storage", "неизвестное хранилище %q (postgres или memory)", c.Storage)
+	}
+	if c.Database.MaxConns < 0 {
+		fail("database.max_conns", "не может быть отрицательным")
+	}
+	if c.Database.MinConns < 0 {
+		fail("database.min_conns", "не может быть отрицательным")
+	}
+	if c.Database.MaxConns > 0 && c.Database.MinConns > c.Database.MaxConns {
+		fail("database.min_conns", "больше database.max_conns (%d > %d)", c.Database.MinConns, c.Database.MaxConns)
+	}
+	if c.Database.SlowQueryThreshold < 0 {
+		fail("database.slow_query_threshold", "не может быть отрицательным")
+	}
+
+	required("auth.secret", "SECRET", c.Auth.Secret)
+	positive("auth.token_ttl", c.Auth.TokenTTL)
+	positive("auth.dummy_token_ttl", c.Auth.DummyTokenTTL)
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		fail("log.level", "неизвестный уровень %q", c.Log.Level)
+	}
+	if c.Log.Format != logger.FormatText && c.Log.Format != logger.FormatJSON {
+		fail("log.format", "ожидается %s или %s, получено %q", logger.FormatText, logger.FormatJSON, c.Log.Format)
+	}
+
+	positive("idempotency.ttl", c.Idempotency.TTL)
+
+	if c.Features.AutoClose {
+		positive("auto_close.interval", c.AutoClose.Interval)
+		positive("auto_close.max_open", c.AutoClose.MaxOpen)
+		for city, limit := range c.AutoClose.CityLimits {
+			if !knownCities[city] {
+				fail("auto_close.city_limits", "неизвестный город %q", city)
+			}
+			positive("auto_close.city_limits."+city, limit)
+		}
+	}
+
+	if c.Features.Events {
+		switch c.Events.Publisher {
+		case PublisherNATS:
+			required("events.nats_url", "NATS_URL", c.Events.NATSURL)
+			required("events.nats_stream", "NATS_STREAM", c.Events.NATSStream)
+		case PublisherInProcess:
+		default:
+			fail("events.publisher", "неизвестный способ публикации %q (nats или inprocess)", c.Events.Publisher)
+		}
+		required("events.subject_prefix", "EVENTS_SUBJECT_PREFIX", c.Events.SubjectPrefix)
+		positive("events.poll_interval", c.Events.PollInterval)
+		if c.Events.BatchSize < 1 {
+			fail("events.batch_size", "должно быть не меньше 1")
+		}
+	}
+
+	if c.Features.PVZEvents && c.Feed.BufferSize < 0 {
+		fail("feed.buffer_size", "не может быть отрицательным")
+	}
+
+	if c.Features.PVZCache {
+		positive("pvz_cache.ttl", c.PVZCache.TTL)
+		if c.PVZCache.Size < 1 {
+			fail("pvz_cache.size", "должно быть не меньше 1")
+		}
+	}
+
+	for route, rule := range c.RateLimits {
+		if rule.Rate < 0 {
+			fail("rate_limits."+route, "скорость не может быть отрицательной")
+		}
+		if rule.Burst < 1 {
+			fail("rate_limits."+route, "размер корзины должен быть не меньше 1")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c DatabaseConfig) DSN() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   c.Host + ":" + c.Port,
+		Path:   "/" + c.Name,
+	}
+	return u.String()
+}
+
+// Redacted возвращает копию конфигурации со скрытыми секретами.
+func (c *Config) Redacted() *Config {
+	r := *c
+	if r.Database.Password != "" {
+		r.Database.Password = redacted
+	}
+	if r.Auth.Secret != "" {
+		r.Auth.Secret = redacted
+	}
+	return &r
+}
+
+func (c *Config) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func setString(p *string) func(string) error {
+	return func(s string) error {
+		*p = s
+		return nil
+	}
+}
+
+func setDuration(p *time.Duration) func(string) error {
+	return func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("неверная длительность %q", s)
+		}
+		*p = d
+		return nil
+	}
+}
+
+func setInt(p *int) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("неверное число %q", s)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func setInt32(p *int32) func(string) error {
+	return func(s string) error {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("неверное число %q", s)
+		}
+		*p = int32(n)
+		return nil
+	}
+}
+
+func setInt64(p *int64) func(string) error {
+	return func(s string) error {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("неверное число %q", s)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func setStringList(p *[]string) func(string) error {
+	return func(s string) error {
+		list := []string{}
+		for _, part := range strings.Split(s, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				list = append(list, part)
+			}
+		}
+		*p = list
+		return nil
+	}
+}
+
+func setBool(p *bool) func(string) error {
+	return func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("неверное логическое значение %q", s)
+		}
+		*p = b
+		return nil
+	}
+}
+
+func setStringMap(p *map[string]string) func(string) error {
+	return func(s string) error {
+		m := make(map[string]string)
+		for _, part := range strings.Split(s, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(part, "=")
+			if !ok {
+				return fmt.Errorf("неверная пара %q: ожидается ключ=значение", part)
+			}
+			m[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		*p = m
+		return nil
+	}
+}
+
+func setDurationMap(p *map[string]time.Duration) func(string) error {
+	return func(s string) error {
+		pairs := make(map[string]string)
+		if err := setStringMap(&pairs)(s); err != nil {
+			return err
+		}
+		m := make(map[string]time.Duration, len(pairs))
+		for key, value := range pairs {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("неверная длительность %q для %s", value, key)
+			}
+			m[key] = d
+		}
+		*p = m
+		return nil
+	}
+}
+
+func setRateLimits(p *map[string]ratelimit.Rule) func(string) error {
+	return func(s string) error {
+		rules, err := ratelimit.ParseRules(s)
+		if err != nil {
+			return err
+		}
+		*p = rules
+		return nil
+	}
+}
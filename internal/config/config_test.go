@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/ratelimit"
+)
+
+func envOf(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func requiredEnv() map[string]string {
+	return map[string]string{
+		"DATABASE_HOST":     "db",
+		"DATABASE_USER":     "postgres",
+		"DATABASE_PASSWORD": "password",
+		"DATABASE_NAME":     "pvz",
+		"SECRET":            "secret",
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, envOf(requiredEnv()))
+	require.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Server.HTTPPort)
+	assert.Equal(t, 10*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(t, 72*time.Hour, cfg.Auth.TokenTTL)
+	assert.Equal(t, "postgres://postgres:password@db:5432/pvz", cfg.Database.DSN())
+	assert.True(t, cfg.Features.DummyLogin)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+server:
+  http_port: "8081"
+  grpc_port: "3001"
+  read_timeout: 7s
+database:
+  host: file-host
+  max_conns: 20
+auth:
+  token_ttl: 12h
+log:
+  format: json
+rate_limits:
+  POST /products:
+    rate: 10
+    burst: 20
+features:
+  dummy_login: false
+`)
+	env := requiredEnv()
+	env["CONFIG_FILE"] = path
+	env["GRPC_PORT"] = "3002"
+	env["DATABASE_HOST"] = ""
+
+	cfg, err := Load([]string{"-grpc-port", "3003", "-log-level", "debug"}, envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, "8081", cfg.Server.HTTPPort, "значение из файла")
+	assert.Equal(t, "3003", cfg.Server.GRPCPort, "флаг важнее окружения и файла")
+	assert.Equal(t, 7*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, "file-host", cfg.Database.Host, "пустая переменная окружения не переопределяет файл")
+	assert.Equal(t, int32(20), cfg.Database.MaxConns)
+	assert.Equal(t, 12*time.Hour, cfg.Auth.TokenTTL)
+	assert.Equal(t, "json", cfg.Log.Format)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, map[string]ratelimit.Rule{"POST /products": {Rate: 10, Burst: 20}}, cfg.RateLimits)
+	assert.False(t, cfg.Features.DummyLogin)
+
+	env["RATE_LIMITS"] = "*=1:2"
+	cfg, err = Load([]string{"-config", path}, envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ratelimit.Rule{"*": {Rate: 1, Burst: 2}}, cfg.RateLimits)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Run("Unknown field in file", func(t *testing.T) {
+		path := writeFile(t, "server:\n  http_prot: \"8081\"\n")
+		_, err := Load([]string{"-config", path}, envOf(requiredEnv()))
+		assert.ErrorContains(t, err, "http_prot")
+	})
+
+	t.Run("Bad env value", func(t *testing.T) {
+		env := requiredEnv()
+		env["TOKEN_TTL"] = "forever"
+		_, err := Load(nil, envOf(env))
+		assert.EqualError(t, err, `переменная окружения TOKEN_TTL: неверная длительность "forever"`)
+	})
+
+	t.Run("Bad flag value", func(t *testing.T) {
+		_, err := Load([]string{"-feature-grpc", "maybe"}, envOf(requiredEnv()))
+		assert.EqualError(t, err, `флаг -feature-grpc: неверное логическое значение "maybe"`)
+	})
+
+	t.Run("Secrets are not flags", func(t *testing.T) {
+		_, err := Load([]string{"-secret", "x"}, envOf(requiredEnv()))
+		assert.Error(t, err)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		env := map[string]string{
+			"SERVER_PORT":        "http",
+			"PROMETHEUS_PORT":    "3000",
+			"DATABASE_MIN_CONNS": "5",
+			"DATABASE_MAX_CONNS": "2",
+			"LOG_FORMAT":         "xml",
+			"IDEMPOTENCY_TTL":    "0s",
+		}
+		cfg, err := Load(nil, envOf(env))
+		require.Error(t, err)
+		assert.NotNil(t, cfg)
+		for _, msg := range []string{
+			`server.http_port: неверный порт "http"`,
+			"server.metrics_port: совпадает с портом другого сервера",
+			"database.host: значение не задано (DATABASE_HOST)",
+			"database.password: значение не задано (DATABASE_PASSWORD)",
+			"database.min_conns: больше database.max_conns (5 > 2)",
+			"auth.secret: значение не задано (SECRET)",
+			`log.format: ожидается text или json, получено "xml"`,
+			"idempotency.ttl: должно быть больше нуля, получено 0s",
+		} {
+			assert.Contains(t, err.Error(), msg)
+		}
+	})
+}
+
+func TestRedactedYAML(t *testing.T) {
+	cfg, err := Load(nil, envOf(requiredEnv()))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Redacted().WriteYAML(&buf))
+	out := buf.String()
+	assert.NotContains(t, out, "password: password")
+	assert.NotContains(t, out, "secret: secret")
+	assert.Contains(t, out, "password: '******'")
+	assert.Contains(t, out, "write_timeout: 10s")
+	assert.Equal(t, "secret", cfg.Auth.Secret, "исходная конфигурация не должна меняться")
+
+	path := writeFile(t, out)
+	env := requiredEnv()
+	roundTrip, err := Load([]string{"-config", path}, envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, cfg, roundTrip)
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0o600))
+
+	env := requiredEnv()
+	env["GRPC_TLS_CERT_FILE"] = certFile
+	env["GRPC_TLS_KEY_FILE"] = certFile
+	env["GRPC_TLS_CLIENT_CA_FILE"] = certFile
+	env["GRPC_TLS_CLIENT_ROLES"] = "terminal-1=employee, back-office=moderator"
+	cfg, err := Load(nil, envOf(env))
+	require.NoError(t, err)
+	assert.True(t, cfg.Server.GRPCTLS.Enabled())
+	assert.False(t, cfg.Server.HTTPTLS.Enabled())
+	assert.Equal(t, map[string]string{"terminal-1": "employee", "back-office": "moderator"}, cfg.Server.GRPCTLS.ClientRoles)
+
+	env = requiredEnv()
+	env["HTTP_TLS_CERT_FILE"] = filepath.Join(dir, "missing.crt")
+	env["GRPC_TLS_CLIENT_CA_FILE"] = certFile
+	env["GRPC_TLS_CLIENT_ROLES"] = "terminal-1=admin"
+	_, err = Load(nil, envOf(env))
+	require.Error(t, err)
+	for _, msg := range []string{
+		"server.http_tls: cert_file и key_file задаются вместе",
+		"server.http_tls: файл " + filepath.Join(dir, "missing.crt") + " недоступен",
+		"server.grpc_tls.client_ca_file: mTLS требует server.grpc_tls.cert_file",
+		`server.grpc_tls.client_roles.terminal-1: неизвестная роль "admin"`,
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+}
+
+func TestCORSConfig(t *testing.T) {
+	env := requiredEnv()
+	env["CORS_ALLOWED_ORIGINS"] = "https://backoffice.example.com, http://localhost:5173"
+	env["MAX_BODY_BYTES"] = "4096"
+	cfg, err := Load(nil, envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://backoffice.example.com", "http://localhost:5173"}, cfg.Server.CORS.AllowedOrigins)
+	assert.Equal(t, int64(4096), cfg.Server.MaxBodyBytes)
+
+	env["CORS_ALLOWED_ORIGINS"] = "*,backoffice.example.com"
+	env["CORS_ALLOW_CREDENTIALS"] = "true"
+	env["MAX_BODY_BYTES"] = "0"
+	_, err = Load(nil, envOf(env))
+	require.Error(t, err)
+	for _, msg := range []string{
+		`server.cors.allowed_origins: "*" нельзя сочетать с allow_credentials`,
+		`server.cors.allowed_origins: неверный источник "backoffice.example.com", ожидается схема://хост[:порт]`,
+		"server.max_body_bytes: должно быть больше нуля",
+	} {
+		assert.Contains(t, err.Error(), msg)
+	}
+}
+
+func TestStorageConfig(t *testing.T) {
+	cfg, err := Load([]string{"--storage=memory"}, envOf(map[string]string{"SECRET": "secret"}))
+	require.NoError(t, err, "параметры БД не нужны для хранилища в памяти")
+	assert.Equal(t, StorageMemory, cfg.Storage)
+
+	_, err = Load(nil, envOf(map[string]string{"SECRET": "secret", "STORAGE": "redis"}))
+	assert.ErrorContains(t, err, `storage: неизвестное хранилище "redis" (postgres или memory)`)
+}
+
+func TestAutoCloseConfig(t *testing.T) {
+	env := requiredEnv()
+	env["AUTO_CLOSE_CITY_LIMITS"] = "Казань=8h, Москва=10h30m"
+	cfg, err := Load([]string{"-auto-close-max-open", "6h"}, envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, 6*time.Hour, cfg.AutoClose.MaxOpen)
+	assert.Equal(t, 5*time.Minute, cfg.AutoClose.Interval)
+	assert.Equal(t, map[string]time.Duration{"Казань": 8 * time.Hour, "Москва": 10*time.Hour + 30*time.Minute}, cfg.AutoClose.CityLimits)
+
+	path := writeFile(t, "auto_close:\n  city_limits:\n    Санкт-Петербург: 9h\n")
+	cfg, err = Load([]string{"-config", path}, envOf(requiredEnv()))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"Санкт-Петербург": 9 * time.Hour}, cfg.AutoClose.CityLimits)
+
+	env["AUTO_CLOSE_CITY_LIMITS"] = "Казань=8"
+	_, err = Load(nil, envOf(env))
+	assert.EqualError(t, err, `переменная окружения AUTO_CLOSE_CITY_LIMITS: неверная длительность "8" для Казань`)
+
+	env["AUTO_CLOSE_CITY_LIMITS"] = "Тверь=8h,Казань=0s"
+	_, err = Load(nil, envOf(env))
+	assert.ErrorContains(t, err, `auto_close.city_limits: неизвестный город "Тверь"`)
+	assert.ErrorContains(t, err, "auto_close.city_limits.Казань: должно быть больше нуля, получено 0s")
+
+	env["FEATURE_AUTO_CLOSE"] = "false"
+	_, err = Load(nil, envOf(env))
+	assert.NoError(t, err, "лимиты не проверяются, если автозакрытие выключено")
+}
+
+func TestEventsConfig(t *testing.T) {
+	cfg, err := Load(nil, envOf(requiredEnv()))
+	require.NoError(t, err)
+	assert.False(t, cfg.Features.Events, "публикация событий по умолчанию выключена")
+
+	env := requiredEnv()
+	env["FEATURE_EVENTS"] = "true"
+	env["NATS_URL"] = "nats://nats:4222"
+	env["EVENTS_BATCH_SIZE"] = "50"
+	cfg, err = Load(nil, envOf(env))
+	require.NoError(t, err)
+	assert.Equal(t, "nats://nats:4222", cfg.Events.NATSURL)
+	assert.Equal(t, 50, cfg.Events.BatchSize)
+
+	env["EVENTS_PUBLISHER"] = "kafka"
+	env["EVENTS_BATCH_SIZE"] = "0"
+	_, err = Load(nil, envOf(env))
+	assert.ErrorContains(t, err, `events.publisher: неизвестный способ публикации "kafka" (nats или inprocess)`)
+	assert.ErrorContains(t, err, "events.batch_size: должно быть не меньше 1")
+}
+
+func TestPVZCacheConfig(t *testing.T) {
+	cfg, err := Load(nil, envOf(requiredEnv()))
+	require.NoError(t, err)
+	assert.True(t, cfg.Features.PVZCache)
+	assert.Equal(t, time.Minute, cfg.PVZCache.TTL)
+	assert.Equal(t, 1000, cfg.PVZCache.Size)
+
+	env := requiredEnv()
+	env["PVZ_CACHE_TTL"] = "0s"
+	env["PVZ_CACHE_SIZE"] = "0"
+	_, err = Load(nil, envOf(env))
+	assert.ErrorContains(t, err, "pvz_cache.ttl: должно быть больше нуля, получено 0s")
+	assert.ErrorContains(t, err, "pvz_cache.size: должно быть не меньше 1")
+
+	env["FEATURE_PVZ_CACHE"] = "false"
+	_, err = Load(nil, envOf(env))
+	assert.NoError(t, err, "параметры кеша не проверяются, если он выключен")
+}
@@ -3,6 +3,8 @@ package contextkeys
 type contextKey string
 
 const (
-	ContextKeyUserID contextKey = "userID"
-	ContextKeyRole   contextKey = "role"
+	ContextKeyUserID    contextKey = "userID"
+	ContextKeyRole      contextKey = "role"
+	ContextKeyRequestID contextKey = "requestID"
+	ContextKeyLogger    contextKey = "logger"
 )
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/models"
+)
+
+func hasGroup(groupBy []string, group string) bool {
+	for _, g := range groupBy {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// IntakeAnalytics считает объем приёмки по интервалам [From, To). Сначала товары
+// агрегируются по приёмкам, чтобы средняя длительность считалась по приёмкам, а не по товарам.
+func (db *PGXDatabase) IntakeAnalytics(ctx context.Context, query models.IntakeQuery) (rows []models.IntakeRow, err error) {
+	defer db.observe(ctx, "IntakeAnalytics", time.Now(), &err)
+	byCity := hasGroup(query.GroupBy, models.GroupByCity)
+	byPVZ := hasGroup(query.GroupBy, models.GroupByPVZ)
+	byType := hasGroup(query.GroupBy, models.GroupByProductType)
+
+	args := []interface{}{query.Bucket, query.From, query.To}
+	innerSelect := "r.id, r.date_time, r.closed_at, z.id AS pvz_id, z.city, COUNT(p.id) AS products"
+	innerGroup := "r.id, z.id"
+	conditions := []string{"r.date_time >= $2", "r.date_time < $3"}
+	if query.City != "" {
+		conditions = append(conditions, fmt.Sprintf("z.city = $%d", len(args)+1))
+		args = append(args, query.City)
+	}
+	if query.PVZId != nil {
+		conditions = append(conditions, fmt.Sprintf("z.id = $%d", len(args)+1))
+		args = append(args, *query.PVZId)
+	}
+	if byType {
+		innerSelect += ", p.type AS product_type"
+		innerGroup += ", p.type"
+		conditions = append(conditions, "p.type IS NOT NULL")
+	}
+
+	outerSelect := []string{"date_trunc($1, pr.date_time AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket"}
+	groupColumns := []string{"1"}
+	if byCity {
+		outerSelect = append(outerSelect, "pr.city")
+		groupColumns = append(groupColumns, "pr.city")
+	}
+	if byPVZ {
+		outerSelect = append(outerSelect, "pr.pvz_id")
+		groupColumns = append(groupColumns, "pr.pvz_id")
+	}
+	if byType {
+		outerSelect = append(outerSelect, "pr.product_type")
+		groupColumns = append(groupColumns, "pr.product_type")
+	}
+	outerSelect = append(outerSelect,
+		"COUNT(*) AS receptions",
+		"SUM(pr.products)::bigint AS products",
+		"AVG(EXTRACT(EPOCH FROM pr.closed_at - pr.date_time))::double precision AS avg_duration",
+	)
+
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM (
+			SELECT %s
+			FROM receptions r
+			JOIN pvz z ON z.id = r.pvz_id
+			LEFT JOIN products p ON p.reception_id = r.id
+			WHERE %s
+			GROUP BY %s
+		) pr
+		GROUP BY %s
+		ORDER BY %s`,
+		strings.Join(outerSelect, ", "), innerSelect, strings.Join(conditions, " AND "), innerGroup,
+		strings.Join(groupColumns, ", "), strings.Join(groupColumns, ", "),
+	)
+
+	result, err := db.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	for result.Next() {
+		var row models.IntakeRow
+		var pvzId uuid.UUID
+		dest := []interface{}{&row.Bucket}
+		if byCity {
+			dest = append(dest, &row.City)
+		}
+		if byPVZ {
+			dest = append(dest, &pvzId)
+		}
+		if byType {
+			dest = append(dest, &row.ProductType)
+		}
+		dest = append(dest, &row.Receptions, &row.Products, &row.AvgReceptionDurationSeconds)
+		if err := result.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if byPVZ {
+			row.PVZId = &pvzId
+		}
+		row.Bucket = row.Bucket.UTC()
+		rows = append(rows, row)
+	}
+	return rows, result.Err()
+}
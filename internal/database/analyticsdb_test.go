@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/models"
+)
+
+func TestIntakeAnalytics(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	t.Run("Group by city and product type", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		avg := 3600.0
+		mockPool.
+			ExpectQuery(regexp.QuoteMeta("SELECT date_trunc($1, pr.date_time AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket, pr.city, pr.product_type, COUNT(*) AS receptions")).
+			WithArgs(models.BucketDay, from, to, "Москва").
+			WillReturnRows(pgxmock.NewRows([]string{"bucket", "city", "product_type", "receptions", "products", "avg_duration"}).
+				AddRow(from, "Москва", "обувь", 2, 5, &avg).
+				AddRow(from, "Москва", "одежда", 1, 1, (*float64)(nil)))
+
+		db := NewPGXDatabase(mockPool)
+		rows, err := db.IntakeAnalytics(ctx, models.IntakeQuery{
+			From:    from,
+			To:      to,
+			Bucket:  models.BucketDay,
+			GroupBy: []string{models.GroupByCity, models.GroupByProductType},
+			City:    "Москва",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []models.IntakeRow{
+			{Bucket: from, City: "Москва", ProductType: "обувь", Receptions: 2, Products: 5, AvgReceptionDurationSeconds: &avg},
+			{Bucket: from, City: "Москва", ProductType: "одежда", Receptions: 1, Products: 1},
+		}, rows)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Group by PVZ", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		pvzId := uuid.New()
+		mockPool.
+			ExpectQuery(regexp.QuoteMeta("GROUP BY 1, pr.pvz_id")).
+			WithArgs(models.BucketWeek, from, to, pvzId).
+			WillReturnRows(pgxmock.NewRows([]string{"bucket", "pvz_id", "receptions", "products", "avg_duration"}).
+				AddRow(from, pvzId, 1, 0, (*float64)(nil)))
+
+		db := NewPGXDatabase(mockPool)
+		rows, err := db.IntakeAnalytics(ctx, models.IntakeQuery{
+			From:    from,
+			To:      to,
+			Bucket:  models.BucketWeek,
+			GroupBy: []string{models.GroupByPVZ},
+			PVZId:   &pvzId,
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, rows, 1) {
+			assert.Equal(t, &pvzId, rows[0].PVZId)
+		}
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Query error", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		expectedErr := errors.New("query error")
+		mockPool.ExpectQuery("SELECT").WithArgs(models.BucketHour, from, to).WillReturnError(expectedErr)
+
+		db := NewPGXDatabase(mockPool)
+		rows, err := db.IntakeAnalytics(ctx, models.IntakeQuery{From: from, To: to, Bucket: models.BucketHour})
+		assert.Nil(t, rows)
+		assert.EqualError(t, err, expectedErr.Error())
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
@@ -7,7 +7,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
 
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
 	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
 )
@@ -15,15 +18,26 @@ import (
 type Database interface {
 	CreateUser(ctx context.Context, user *models.User) (err error)
 	GetUserByEmail(ctx context.Context, email string) (user *models.User, err error)
+	GetUserByID(ctx context.Context, userID uuid.UUID) (user *models.User, err error)
 	CreatePVZ(ctx context.Context, pvz *models.PVZ) (err error)
 	GetPVZs(ctx context.Context, limit, offset int) (pvzs []models.PVZ, err error)
+	GetPVZVersions(ctx context.Context, pvzIds []uuid.UUID) (versions []models.PVZVersion, err error)
 	GetReceptionsByPVZ(ctx context.Context, pvzId uuid.UUID, startDate, endDate *time.Time) (recs []models.Reception, err error)
 	GetProductsByReception(ctx context.Context, receptionID uuid.UUID) (products []*models.Product, err error)
+	GetReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (recs []models.Reception, err error)
+	GetProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (products []*models.Product, err error)
 	CloseLastReception(ctx context.Context, pvzId uuid.UUID) (rec *models.Reception, err error)
 	DeleteLastProduct(ctx context.Context, pvzId uuid.UUID) (err error)
 	CreateReception(ctx context.Context, pvzId uuid.UUID) (rec *models.Reception, err error)
 	AddProduct(ctx context.Context, pvzId uuid.UUID, productType string) (product *models.Product, err error)
 	GetPVZ(ctx context.Context) (pvzs []*pb.PVZ, err error)
+	GetPVZByID(ctx context.Context, pvzId uuid.UUID) (pvz *models.PVZ, err error)
+	StreamPVZs(ctx context.Context, filter models.PVZFilter, fn func(pvz *models.PVZ) error) (err error)
+	CountProductsByReception(ctx context.Context, receptionID uuid.UUID) (count int, err error)
+	CountOpenReceptionsByCity(ctx context.Context) (counts map[string]int, err error)
+	ExportProducts(ctx context.Context, filter models.ExportFilter, fn func(row *models.ExportRow) error) (err error)
+	IntakeAnalytics(ctx context.Context, query models.IntakeQuery) (rows []models.IntakeRow, err error)
+	AutoCloseReceptions(ctx context.Context, now time.Time, limits models.AutoCloseLimits) (closed []models.AutoClosedReception, acquired bool, err error)
 }
 
 type DBPool interface {
@@ -34,21 +48,78 @@ type DBPool interface {
 }
 
 type PGXDatabase struct {
-	pool DBPool
+	pool               DBPool
+	slowQueryThreshold time.Duration
+	outbox             bool
+	notify             bool
 }
 
-func NewPGXDatabase(pool DBPool) *PGXDatabase {
-	return &PGXDatabase{pool: pool}
+type Option func(db *PGXDatabase)
+
+func WithSlowQueryThreshold(threshold time.Duration) Option {
+	return func(db *PGXDatabase) {
+		db.slowQueryThreshold = threshold
+	}
+}
+
+func NewPGXDatabase(pool DBPool, opts ...Option) *PGXDatabase {
+	db := &PGXDatabase{pool: pool}
+	for _, opt := range opts {
+		opt(db)
+	}
+	return db
+}
+
+// observe вызывается отложенно в каждом методе: записывает длительность запроса
+// и приводит ошибку драйвера в *errp к ошибкам пакета.
+func (db *PGXDatabase) observe(ctx context.Context, method string, start time.Time, errp *error) {
+	*errp = classify(*errp)
+	duration := time.Since(start)
+	metrics.DBQueryDuration.WithLabelValues(method).Observe(duration.Seconds())
+	if db.slowQueryThreshold > 0 && duration >= db.slowQueryThreshold {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"db_method":   method,
+			"duration_ms": duration.Milliseconds(),
+		}).Warn("Медленный запрос к БД")
+	}
 }
 
 func (db *PGXDatabase) CreateUser(ctx context.Context, user *models.User) (err error) {
+	defer db.observe(ctx, "CreateUser", time.Now(), &err)
 	query := `INSERT INTO users (email, password, role) VALUES ($1, $2, $3) RETURNING id`
 	return db.pool.QueryRow(ctx, query, user.Email, user.Password, user.Role).Scan(&user.ID)
 }
 
 func (db *PGXDatabase) GetUserByEmail(ctx context.Context, email string) (user *models.User, err error) {
+	defer db.observe(ctx, "GetUserByEmail", time.Now(), &err)
 	user = &models.User{}
 	query := `SELECT id, email, password, role FROM users WHERE email=$1`
 	err = db.pool.QueryRow(ctx, query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Role)
 	return user, err
 }
+
+func (db *PGXDatabase) GetUserByID(ctx context.Context, userID uuid.UUID) (user *models.User, err error) {
+	defer db.observe(ctx, "GetUserByID", time.Now(), &err)
+	user = &models.User{}
+	query := `SELECT id, email, password, role FROM users WHERE id=$1`
+	err = db.pool.QueryRow(ctx, query, userID).Scan(&user.ID, &user.Email, &user.Password, &user.Role)
+	return user, err
+}
+
+func (db *PGXDatabase) ListUsers(ctx context.Context) (users []models.User, err error) {
+	defer db.observe(ctx, "ListUsers", time.Now(), &err)
+	query := `SELECT id, email, role FROM users ORDER BY email`
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Email, &u.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
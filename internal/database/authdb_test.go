@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"regexp"
 	"testing"
 
 	"github.com/google/uuid"
@@ -76,3 +77,38 @@ func TestGetUserByEmail(t *testing.T) {
 		t.Errorf("Не все ожидания были выполнены: %s", err)
 	}
 }
+
+func TestGetUserByID(t *testing.T) {
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	id := uuid.New()
+	mockPool.
+		ExpectQuery(regexp.QuoteMeta("SELECT id, email, password, role FROM users WHERE id=$1")).
+		WithArgs(id).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "email", "password", "role"}).AddRow(id, "mod@example.com", "hash", "moderator"))
+
+	user, err := NewPGXDatabase(mockPool).GetUserByID(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, "mod@example.com", user.Email)
+	assert.Equal(t, "moderator", user.Role)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestListUsers(t *testing.T) {
+	ctx := context.Background()
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	db := NewPGXDatabase(mockPool)
+	id := uuid.New()
+	mockPool.ExpectQuery(regexp.QuoteMeta("SELECT id, email, role FROM users ORDER BY email")).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "email", "role"}).AddRow(id, "mod@example.com", "moderator"))
+
+	users, err := db.ListUsers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.User{{ID: id, Email: "mod@example.com", Role: "moderator"}}, users)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
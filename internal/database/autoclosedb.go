@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"pvz/internal/models"
+)
+
+// autoCloseLockKey — ключ advisory-блокировки, под которой закрываются забытые приёмки.
+const autoCloseLockKey int64 = 0x7076_7a01
+
+// AutoCloseReceptions закрывает приёмки, открытые дольше лимита города на момент now.
+// Проход выполняется под транзакционной advisory-блокировкой: если ее держит другая
+// реплика, ничего не закрывается и acquired равен false.
+func (db *PGXDatabase) AutoCloseReceptions(ctx context.Context, now time.Time, limits models.AutoCloseLimits) (closed []models.AutoClosedReception, acquired bool, err error) {
+	defer db.observe(ctx, "AutoCloseReceptions", time.Now(), &err)
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, autoCloseLockKey).Scan(&acquired); err != nil {
+		return nil, false, err
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+
+	cities := make([]string, 0, len(limits.ByCity))
+	seconds := make([]float64, 0, len(limits.ByCity))
+	for city, limit := range limits.ByCity {
+		cities = append(cities, city)
+		seconds = append(seconds, limit.Seconds())
+	}
+	query := `
+		UPDATE receptions r
+		SET status='close', closed_at=$1, close_reason='auto_closed'
+		FROM pvz z
+		WHERE z.id = r.pvz_id AND r.status='in_progress'
+			AND r.date_time < $1 - make_interval(secs => COALESCE(
+				(SELECT l.seconds FROM unnest($3::text[], $4::float8[]) AS l(city, seconds) WHERE l.city = z.city),
+				$2))
+		RETURNING r.id, r.pvz_id, z.city, r.date_time, r.closed_at
+	`
+	rows, err := tx.Query(ctx, query, now, limits.Default.Seconds(), cities, seconds)
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec models.AutoClosedReception
+		if err = rows.Scan(&rec.ID, &rec.PVZId, &rec.City, &rec.DateTime, &rec.ClosedAt); err != nil {
+			return nil, true, err
+		}
+		closed = append(closed, rec)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, true, err
+	}
+	rows.Close()
+	for _, rec := range closed {
+		payload := models.ReceptionClosedPayload{
+			Reception:   models.Reception{ID: rec.ID, DateTime: rec.DateTime, PVZId: rec.PVZId, Status: "close"},
+			CloseReason: models.CloseReasonAutoClosed,
+		}
+		if err = db.emit(ctx, tx, models.EventReceptionClosed, rec.PVZId, payload); err != nil {
+			return nil, true, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, true, err
+	}
+	return closed, true, nil
+}
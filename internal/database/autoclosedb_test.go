@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/models"
+)
+
+func TestAutoCloseReceptions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 4, 1, 22, 0, 0, 0, time.UTC)
+	limits := models.AutoCloseLimits{Default: 12 * time.Hour, ByCity: map[string]time.Duration{"Казань": 8 * time.Hour}}
+	lockQuery := regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)
+	updateQuery := regexp.QuoteMeta("UPDATE receptions r")
+
+	t.Run("Closes stale receptions", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		receptionID, pvzId := uuid.New(), uuid.New()
+		opened := now.Add(-9 * time.Hour)
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(lockQuery).WithArgs(autoCloseLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+		mockPool.ExpectQuery(updateQuery).
+			WithArgs(now, float64(12*60*60), []string{"Казань"}, []float64{8 * 60 * 60}).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "pvz_id", "city", "date_time", "closed_at"}).
+				AddRow(receptionID, pvzId, "Казань", opened, now))
+		mockPool.ExpectCommit()
+
+		db := NewPGXDatabase(mockPool)
+		closed, acquired, err := db.AutoCloseReceptions(ctx, now, limits)
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+		assert.Equal(t, []models.AutoClosedReception{
+			{ID: receptionID, PVZId: pvzId, City: "Казань", DateTime: opened, ClosedAt: now},
+		}, closed)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Lock held by another replica", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(lockQuery).WithArgs(autoCloseLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+		mockPool.ExpectRollback()
+
+		db := NewPGXDatabase(mockPool)
+		closed, acquired, err := db.AutoCloseReceptions(ctx, now, limits)
+		assert.NoError(t, err)
+		assert.False(t, acquired)
+		assert.Empty(t, closed)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Update error", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		expectedErr := errors.New("update error")
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(lockQuery).WithArgs(autoCloseLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+		mockPool.ExpectQuery(updateQuery).
+			WithArgs(now, float64(12*60*60), []string{"Казань"}, []float64{8 * 60 * 60}).
+			WillReturnError(expectedErr)
+		mockPool.ExpectRollback()
+
+		db := NewPGXDatabase(mockPool)
+		_, _, err = db.AutoCloseReceptions(ctx, now, limits)
+		assert.EqualError(t, err, expectedErr.Error())
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
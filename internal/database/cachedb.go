@@ -0,0 +1,142 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/metrics"
+	"pvz/internal/models"
+	pb "pvz/internal/pb/pvz_v1"
+)
+
+// CachedDatabase кеширует чтение ПВЗ поверх другого хранилища: GetPVZs, GetPVZ и
+// GetPVZByID. ПВЗ меняются редко, поэтому кеш целиком сбрасывается при CreatePVZ в этом
+// процессе и по Invalidate, который вызывается при изменении ПВЗ в других репликах
+// (см. ListenPVZChanges). Приёмки, товары и версии ПВЗ не кешируются.
+type CachedDatabase struct {
+	Database
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order хранит записи от недавно использованных к давно использованным.
+	order *list.List
+	// generation растет при каждом сбросе: результат чтения, начатого до сброса,
+	// в кеш не попадает.
+	generation uint64
+}
+
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// NewCachedDatabase оборачивает db кешем не более чем на size записей, каждая из
+// которых живет ttl.
+func NewCachedDatabase(db Database, ttl time.Duration, size int) *CachedDatabase {
+	return &CachedDatabase{
+		Database: db,
+		ttl:      ttl,
+		size:     size,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Invalidate сбрасывает кеш.
+func (db *CachedDatabase) Invalidate() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.generation++
+	db.entries = make(map[string]*list.Element)
+	db.order.Init()
+}
+
+func (db *CachedDatabase) CreatePVZ(ctx context.Context, pvz *models.PVZ) (err error) {
+	if err := db.Database.CreatePVZ(ctx, pvz); err != nil {
+		return err
+	}
+	db.Invalidate()
+	return nil
+}
+
+func (db *CachedDatabase) GetPVZs(ctx context.Context, limit, offset int) (pvzs []models.PVZ, err error) {
+	key := "GetPVZs:" + strconv.Itoa(limit) + ":" + strconv.Itoa(offset)
+	value, err := db.cached("GetPVZs", key, func() (interface{}, error) {
+		return db.Database.GetPVZs(ctx, limit, offset)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.PVZ(nil), value.([]models.PVZ)...), nil
+}
+
+func (db *CachedDatabase) GetPVZ(ctx context.Context) (pvzs []*pb.PVZ, err error) {
+	value, err := db.cached("GetPVZ", "GetPVZ", func() (interface{}, error) {
+		return db.Database.GetPVZ(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]*pb.PVZ(nil), value.([]*pb.PVZ)...), nil
+}
+
+func (db *CachedDatabase) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (pvz *models.PVZ, err error) {
+	value, err := db.cached("GetPVZByID", "GetPVZByID:"+pvzId.String(), func() (interface{}, error) {
+		return db.Database.GetPVZByID(ctx, pvzId)
+	})
+	if err != nil {
+		return &models.PVZ{}, err
+	}
+	p := *value.(*models.PVZ)
+	return &p, nil
+}
+
+// cached возвращает значение из кеша или читает его через load. Ошибки не кешируются.
+func (db *CachedDatabase) cached(method, key string, load func() (interface{}, error)) (interface{}, error) {
+	db.mu.Lock()
+	if el, ok := db.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		if db.now().Before(entry.expires) {
+			db.order.MoveToFront(el)
+			db.mu.Unlock()
+			metrics.PVZCacheRequests.WithLabelValues(method, "hit").Inc()
+			return entry.value, nil
+		}
+		db.order.Remove(el)
+		delete(db.entries, key)
+	}
+	generation := db.generation
+	db.mu.Unlock()
+	metrics.PVZCacheRequests.WithLabelValues(method, "miss").Inc()
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if generation != db.generation {
+		return value, nil
+	}
+	if el, ok := db.entries[key]; ok {
+		db.order.Remove(el)
+	}
+	db.entries[key] = db.order.PushFront(&cacheEntry{key: key, value: value, expires: db.now().Add(db.ttl)})
+	for db.order.Len() > db.size {
+		oldest := db.order.Back()
+		db.order.Remove(oldest)
+		delete(db.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return value, nil
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/metrics"
+	"pvz/internal/models"
+	pb "pvz/internal/pb/pvz_v1"
+)
+
+// countingDatabase считает чтения ПВЗ, дошедшие до хранилища.
+type countingDatabase struct {
+	*MemoryDatabase
+	reads     atomic.Int32
+	beforeGet func()
+	err       error
+}
+
+func (db *countingDatabase) GetPVZs(ctx context.Context, limit, offset int) ([]models.PVZ, error) {
+	db.reads.Add(1)
+	if db.beforeGet != nil {
+		db.beforeGet()
+	}
+	if db.err != nil {
+		return nil, db.err
+	}
+	return db.MemoryDatabase.GetPVZs(ctx, limit, offset)
+}
+
+func (db *countingDatabase) GetPVZ(ctx context.Context) ([]*pb.PVZ, error) {
+	db.reads.Add(1)
+	return db.MemoryDatabase.GetPVZ(ctx)
+}
+
+func (db *countingDatabase) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (*models.PVZ, error) {
+	db.reads.Add(1)
+	return db.MemoryDatabase.GetPVZByID(ctx, pvzId)
+}
+
+func newCountingCache(ttl time.Duration, size int) (*CachedDatabase, *countingDatabase) {
+	backend := &countingDatabase{MemoryDatabase: NewMemoryDatabase()}
+	return NewCachedDatabase(backend, ttl, size), backend
+}
+
+func TestCachedDatabaseHits(t *testing.T) {
+	ctx := context.Background()
+	db, backend := newCountingCache(time.Minute, 10)
+	pvz := &models.PVZ{City: "Москва", RegistrationDate: time.Now()}
+	require.NoError(t, db.CreatePVZ(ctx, pvz))
+
+	hits := testutil.ToFloat64(metrics.PVZCacheRequests.WithLabelValues("GetPVZs", "hit"))
+	misses := testutil.ToFloat64(metrics.PVZCacheRequests.WithLabelValues("GetPVZs", "miss"))
+	for i := 0; i < 3; i++ {
+		pvzs, err := db.GetPVZs(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, pvzs, 1)
+		assert.Equal(t, pvz.ID, pvzs[0].ID)
+	}
+	assert.EqualValues(t, 1, backend.reads.Load(), "повторные чтения отдаются из кеша")
+	assert.Equal(t, hits+2, testutil.ToFloat64(metrics.PVZCacheRequests.WithLabelValues("GetPVZs", "hit")))
+	assert.Equal(t, misses+1, testutil.ToFloat64(metrics.PVZCacheRequests.WithLabelValues("GetPVZs", "miss")))
+
+	_, err := db.GetPVZs(ctx, 10, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, backend.reads.Load(), "страницы кешируются отдельно")
+
+	for i := 0; i < 2; i++ {
+		all, err := db.GetPVZ(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		got, err := db.GetPVZByID(ctx, pvz.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Москва", got.City)
+		got.City = "Казань"
+	}
+	assert.EqualValues(t, 4, backend.reads.Load())
+	got, err := db.GetPVZByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Москва", got.City, "изменение результата не портит кеш")
+
+	_, err = db.GetPVZByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCachedDatabaseInvalidation(t *testing.T) {
+	ctx := context.Background()
+	db, backend := newCountingCache(time.Minute, 10)
+
+	pvzs, err := db.GetPVZs(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, pvzs)
+
+	require.NoError(t, db.CreatePVZ(ctx, &models.PVZ{City: "Казань", RegistrationDate: time.Now()}))
+	pvzs, err = db.GetPVZs(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, pvzs, 1, "CreatePVZ сбрасывает кеш")
+
+	// ПВЗ, созданный другой репликой, виден после уведомления.
+	require.NoError(t, backend.MemoryDatabase.CreatePVZ(ctx, &models.PVZ{City: "Москва", RegistrationDate: time.Now()}))
+	pvzs, err = db.GetPVZs(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, pvzs, 1)
+	db.Invalidate()
+	pvzs, err = db.GetPVZs(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, pvzs, 2)
+
+	// Результат чтения, начатого до сброса, в кеш не попадает.
+	backend.beforeGet = db.Invalidate
+	_, err = db.GetPVZs(ctx, 5, 0)
+	require.NoError(t, err)
+	backend.beforeGet = nil
+	reads := backend.reads.Load()
+	_, err = db.GetPVZs(ctx, 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, reads+1, backend.reads.Load())
+}
+
+func TestCachedDatabaseBounds(t *testing.T) {
+	ctx := context.Background()
+	db, backend := newCountingCache(time.Minute, 2)
+	now := time.Now()
+	db.now = func() time.Time { return now }
+
+	read := func(offset int) {
+		t.Helper()
+		_, err := db.GetPVZs(ctx, 1, offset)
+		require.NoError(t, err)
+	}
+	read(0)
+	read(1)
+	read(0)
+	read(2) // вытесняет давно не использованную страницу 1
+	assert.EqualValues(t, 3, backend.reads.Load())
+	read(0)
+	assert.EqualValues(t, 3, backend.reads.Load())
+	read(1)
+	assert.EqualValues(t, 4, backend.reads.Load(), "кеш хранит не больше size записей")
+
+	now = now.Add(time.Minute)
+	read(1)
+	assert.EqualValues(t, 5, backend.reads.Load(), "запись живет ttl")
+
+	backend.err = errors.New("connection refused")
+	_, err := db.GetPVZs(ctx, 1, 5)
+	assert.EqualError(t, err, "connection refused")
+	backend.err = nil
+	read(5)
+	assert.EqualValues(t, 7, backend.reads.Load(), "ошибки не кешируются")
+}
@@ -0,0 +1,671 @@
+// Package dbtest содержит общий набор тестов, которому должна соответствовать любая
+// реализация хранилища: PGXDatabase на Postgres и MemoryDatabase.
+package dbtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/models"
+)
+
+// Storage — database.Database вместе с административными методами и outbox.
+// Хранилище должно записывать доменные события.
+type Storage interface {
+	database.Database
+	ListUsers(ctx context.Context) ([]models.User, error)
+	DeletePVZ(ctx context.Context, pvzId uuid.UUID) error
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, event models.OutboxEvent) error) (int, bool, error)
+}
+
+// Run прогоняет набор тестов; newStorage должен возвращать пустое хранилище.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, db Storage)
+	}{
+		{"Users", testUsers},
+		{"PVZ", testPVZ},
+		{"PVZPagination", testPVZPagination},
+		{"PVZVersions", testPVZVersions},
+		{"StreamPVZs", testStreamPVZs},
+		{"Receptions", testReceptions},
+		{"ReceptionsByPeriod", testReceptionsByPeriod},
+		{"Products", testProducts},
+		{"BatchReads", testBatchReads},
+		{"DeletePVZCascade", testDeletePVZCascade},
+		{"ExportProducts", testExportProducts},
+		{"IntakeAnalytics", testIntakeAnalytics},
+		{"AutoCloseReceptions", testAutoCloseReceptions},
+		{"Outbox", testOutbox},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, context.Background(), newStorage(t))
+		})
+	}
+}
+
+func assertPgCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	if assert.True(t, errors.As(err, &pgErr), "ожидалась ошибка Postgres %s, получено %v", code, err) {
+		assert.Equal(t, code, pgErr.Code)
+	}
+}
+
+func createPVZ(t *testing.T, ctx context.Context, db Storage, city string, registered time.Time) models.PVZ {
+	t.Helper()
+	pvz := models.PVZ{City: city, RegistrationDate: registered}
+	require.NoError(t, db.CreatePVZ(ctx, &pvz))
+	require.NotEqual(t, uuid.Nil, pvz.ID)
+	return pvz
+}
+
+func testUsers(t *testing.T, ctx context.Context, db Storage) {
+	moderator := &models.User{Email: "b@example.com", Password: "hash", Role: "moderator"}
+	require.NoError(t, db.CreateUser(ctx, moderator))
+	assert.NotEqual(t, uuid.Nil, moderator.ID)
+	require.NoError(t, db.CreateUser(ctx, &models.User{Email: "a@example.com", Password: "hash", Role: "employee"}))
+
+	got, err := db.GetUserByEmail(ctx, "b@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, *moderator, *got)
+
+	_, err = db.GetUserByEmail(ctx, "missing@example.com")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	got, err = db.GetUserByID(ctx, moderator.ID)
+	require.NoError(t, err)
+	assert.Equal(t, *moderator, *got)
+
+	_, err = db.GetUserByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	err = db.CreateUser(ctx, &models.User{Email: "b@example.com", Password: "hash", Role: "employee"})
+	assertPgCode(t, err, "23505")
+	assert.ErrorIs(t, err, database.ErrAlreadyExists)
+
+	err = db.CreateUser(ctx, &models.User{Email: "c@example.com", Password: "hash", Role: "admin"})
+	assertPgCode(t, err, "23514")
+
+	users, err := db.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "a@example.com", users[0].Email)
+	assert.Equal(t, "b@example.com", users[1].Email)
+	assert.Empty(t, users[1].Password)
+}
+
+func testPVZ(t *testing.T, ctx context.Context, db Storage) {
+	registered := time.Date(2025, 4, 1, 10, 0, 0, 123456789, time.UTC)
+	created := createPVZ(t, ctx, db, "Казань", registered)
+
+	got, err := db.GetPVZByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, "Казань", got.City)
+	assert.True(t, got.RegistrationDate.Equal(registered.Truncate(time.Microsecond)), "дата хранится с точностью до микросекунды: %v", got.RegistrationDate)
+
+	_, err = db.GetPVZByID(ctx, uuid.New())
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	err = db.CreatePVZ(ctx, &models.PVZ{City: "Новосибирск", RegistrationDate: registered})
+	assertPgCode(t, err, "23514")
+
+	all, err := db.GetPVZ(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, created.ID.String(), all[0].Id)
+	assert.Equal(t, "Казань", all[0].City)
+}
+
+func testPVZPagination(t *testing.T, ctx context.Context, db Storage) {
+	base := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	var ids []uuid.UUID
+	for i := 0; i < 5; i++ {
+		ids = append(ids, createPVZ(t, ctx, db, "Москва", base.Add(time.Duration(i)*time.Hour)).ID)
+	}
+
+	page, err := db.GetPVZs(ctx, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, ids[4], page[0].ID)
+	assert.Equal(t, ids[3], page[1].ID)
+
+	page, err = db.GetPVZs(ctx, 2, 4)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, ids[0], page[0].ID)
+
+	page, err = db.GetPVZs(ctx, 2, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func testPVZVersions(t *testing.T, ctx context.Context, db Storage) {
+	base := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	older := createPVZ(t, ctx, db, "Москва", base)
+	newer := createPVZ(t, ctx, db, "Казань", base.Add(time.Hour))
+
+	version := func(id uuid.UUID) int64 {
+		t.Helper()
+		versions, err := db.GetPVZVersions(ctx, []uuid.UUID{id})
+		require.NoError(t, err)
+		require.Len(t, versions, 1, "нет версии ПВЗ %s", id)
+		assert.Equal(t, id, versions[0].ID)
+		return versions[0].Version
+	}
+
+	versions, err := db.GetPVZVersions(ctx, []uuid.UUID{older.ID, uuid.New(), newer.ID})
+	require.NoError(t, err)
+	require.Len(t, versions, 2, "неизвестные id пропускаются")
+	assert.ElementsMatch(t, []uuid.UUID{older.ID, newer.ID}, []uuid.UUID{versions[0].ID, versions[1].ID})
+
+	versions, err = db.GetPVZVersions(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	initial := version(older.ID)
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"CreateReception", func() error { _, err := db.CreateReception(ctx, older.ID); return err }},
+		{"AddProduct", func() error { _, err := db.AddProduct(ctx, older.ID, "обувь"); return err }},
+		{"DeleteLastProduct", func() error { return db.DeleteLastProduct(ctx, older.ID) }},
+		{"CloseLastReception", func() error { _, err := db.CloseLastReception(ctx, older.ID); return err }},
+	}
+	last := initial
+	for _, step := range steps {
+		require.NoError(t, step.fn(), step.name)
+		current := version(older.ID)
+		assert.Greater(t, current, last, "%s должен менять версию ПВЗ", step.name)
+		last = current
+	}
+
+	_, err = db.CloseLastReception(ctx, older.ID)
+	assert.ErrorIs(t, err, database.ErrNoActiveReception)
+	assert.Equal(t, last, version(older.ID), "неудачная операция не меняет версию")
+	assert.Equal(t, version(newer.ID), initial, "изменения одного ПВЗ не затрагивают другие")
+}
+
+func testStreamPVZs(t *testing.T, ctx context.Context, db Storage) {
+	base := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	third := createPVZ(t, ctx, db, "Москва", base.Add(2*time.Hour))
+	first := createPVZ(t, ctx, db, "Москва", base)
+	second := createPVZ(t, ctx, db, "Казань", base.Add(time.Hour))
+
+	stream := func(filter models.PVZFilter) []uuid.UUID {
+		var ids []uuid.UUID
+		require.NoError(t, db.StreamPVZs(ctx, filter, func(pvz *models.PVZ) error {
+			ids = append(ids, pvz.ID)
+			return nil
+		}))
+		return ids
+	}
+
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID, third.ID}, stream(models.PVZFilter{}))
+	assert.Equal(t, []uuid.UUID{first.ID, third.ID}, stream(models.PVZFilter{City: "Москва"}))
+	from, to := base.Add(time.Hour), base.Add(2*time.Hour)
+	assert.Equal(t, []uuid.UUID{second.ID, third.ID}, stream(models.PVZFilter{From: &from}), "границы включаются")
+	assert.Equal(t, []uuid.UUID{second.ID}, stream(models.PVZFilter{From: &from, To: &from}))
+	assert.Equal(t, []uuid.UUID{third.ID}, stream(models.PVZFilter{City: "Москва", From: &from, To: &to}))
+
+	stop := errors.New("stop")
+	calls := 0
+	err := db.StreamPVZs(ctx, models.PVZFilter{}, func(pvz *models.PVZ) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = db.StreamPVZs(canceled, models.PVZFilter{}, func(pvz *models.PVZ) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func testReceptions(t *testing.T, ctx context.Context, db Storage) {
+	moscow := createPVZ(t, ctx, db, "Москва", time.Now())
+	kazan := createPVZ(t, ctx, db, "Казань", time.Now())
+
+	_, err := db.CloseLastReception(ctx, moscow.ID)
+	assert.ErrorIs(t, err, database.ErrNoActiveReception)
+
+	rec, err := db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, rec.ID)
+	assert.Equal(t, moscow.ID, rec.PVZId)
+	assert.Equal(t, "in_progress", rec.Status)
+
+	_, err = db.CreateReception(ctx, moscow.ID)
+	assert.ErrorIs(t, err, database.ErrReceptionAlreadyOpen, "одновременно у ПВЗ может быть только одна открытая приёмка")
+	assertPgCode(t, err, "23505")
+
+	_, err = db.CreateReception(ctx, kazan.ID)
+	require.NoError(t, err)
+
+	_, err = db.CreateReception(ctx, uuid.New())
+	assertPgCode(t, err, "23503")
+	assert.ErrorIs(t, err, database.ErrNotFound, "приёмку нельзя создать у несуществующего ПВЗ")
+
+	counts, err := db.CountOpenReceptionsByCity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Москва": 1, "Казань": 1}, counts)
+
+	closed, err := db.CloseLastReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rec.ID, closed.ID)
+	assert.Equal(t, "close", closed.Status)
+	assert.WithinDuration(t, rec.DateTime, closed.DateTime, time.Microsecond)
+
+	_, err = db.CloseLastReception(ctx, moscow.ID)
+	assert.ErrorIs(t, err, database.ErrNoActiveReception)
+	_, err = db.CloseLastReception(ctx, uuid.New())
+	assert.ErrorIs(t, err, database.ErrNotFound, "у несуществующего ПВЗ нет и приёмки")
+
+	counts, err = db.CountOpenReceptionsByCity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Казань": 1}, counts)
+
+	reopened, err := db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, rec.ID, reopened.ID)
+}
+
+func testReceptionsByPeriod(t *testing.T, ctx context.Context, db Storage) {
+	pvz := createPVZ(t, ctx, db, "Санкт-Петербург", time.Now())
+	var recs []*models.Reception
+	for i := 0; i < 3; i++ {
+		rec, err := db.CreateReception(ctx, pvz.ID)
+		require.NoError(t, err)
+		_, err = db.CloseLastReception(ctx, pvz.ID)
+		require.NoError(t, err)
+		recs = append(recs, rec)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	got, err := db.GetReceptionsByPVZ(ctx, pvz.ID, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, recs[2].ID, got[0].ID)
+	assert.Equal(t, recs[0].ID, got[2].ID)
+
+	start, end := recs[1].DateTime, recs[1].DateTime
+	got, err = db.GetReceptionsByPVZ(ctx, pvz.ID, &start, &end)
+	require.NoError(t, err)
+	require.Len(t, got, 1, "границы периода включаются")
+	assert.Equal(t, recs[1].ID, got[0].ID)
+
+	got, err = db.GetReceptionsByPVZ(ctx, uuid.New(), nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func testProducts(t *testing.T, ctx context.Context, db Storage) {
+	pvz := createPVZ(t, ctx, db, "Москва", time.Now())
+
+	_, err := db.AddProduct(ctx, pvz.ID, "обувь")
+	assert.ErrorIs(t, err, database.ErrNoActiveReception, "товар нельзя добавить без открытой приёмки")
+	assert.ErrorIs(t, db.DeleteLastProduct(ctx, pvz.ID), database.ErrNoActiveReception)
+	_, err = db.AddProduct(ctx, uuid.New(), "обувь")
+	assert.ErrorIs(t, err, database.ErrNotFound, "товар нельзя добавить в несуществующий ПВЗ")
+	assert.ErrorIs(t, db.DeleteLastProduct(ctx, uuid.New()), database.ErrNotFound)
+
+	rec, err := db.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, db.DeleteLastProduct(ctx, pvz.ID), database.ErrNoProductToDelete)
+
+	var added []*models.Product
+	for _, productType := range []string{"электроника", "одежда", "обувь"} {
+		product, err := db.AddProduct(ctx, pvz.ID, productType)
+		require.NoError(t, err)
+		assert.Equal(t, rec.ID, product.ReceptionId)
+		assert.Equal(t, productType, product.Type)
+		added = append(added, product)
+	}
+
+	_, err = db.AddProduct(ctx, pvz.ID, "мебель")
+	assertPgCode(t, err, "23514")
+
+	count, err := db.CountProductsByReception(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	require.NoError(t, db.DeleteLastProduct(ctx, pvz.ID))
+	products, err := db.GetProductsByReception(ctx, rec.ID)
+	require.NoError(t, err)
+	require.Len(t, products, 2, "удаляется только последний добавленный товар")
+	assert.Equal(t, added[0].ID, products[0].ID)
+	assert.Equal(t, added[1].ID, products[1].ID)
+
+	_, err = db.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, db.DeleteLastProduct(ctx, pvz.ID), database.ErrNoActiveReception, "из закрытой приёмки товары не удаляются")
+
+	count, err = db.CountProductsByReception(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func testBatchReads(t *testing.T, ctx context.Context, db Storage) {
+	moscow := createPVZ(t, ctx, db, "Москва", time.Now())
+	kazan := createPVZ(t, ctx, db, "Казань", time.Now())
+	other := createPVZ(t, ctx, db, "Санкт-Петербург", time.Now())
+
+	first, err := db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	shoes, err := db.AddProduct(ctx, moscow.ID, "обувь")
+	require.NoError(t, err)
+	_, err = db.CloseLastReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	second, err := db.CreateReception(ctx, kazan.ID)
+	require.NoError(t, err)
+	clothes, err := db.AddProduct(ctx, kazan.ID, "одежда")
+	require.NoError(t, err)
+	electronics, err := db.AddProduct(ctx, kazan.ID, "электроника")
+	require.NoError(t, err)
+	_, err = db.CreateReception(ctx, other.ID)
+	require.NoError(t, err)
+
+	recs, err := db.GetReceptionsByPVZs(ctx, []uuid.UUID{moscow.ID, kazan.ID}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	assert.Equal(t, second.ID, recs[0].ID, "сначала новые приёмки")
+	assert.Equal(t, first.ID, recs[1].ID)
+	assert.Equal(t, moscow.ID, recs[1].PVZId)
+
+	recs, err = db.GetReceptionsByPVZs(ctx, []uuid.UUID{moscow.ID, kazan.ID}, &second.DateTime, nil)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Equal(t, second.ID, recs[0].ID)
+
+	recs, err = db.GetReceptionsByPVZs(ctx, []uuid.UUID{}, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+
+	products, err := db.GetProductsByReceptions(ctx, []uuid.UUID{first.ID, second.ID})
+	require.NoError(t, err)
+	require.Len(t, products, 3)
+	assert.Equal(t, shoes.ID, products[0].ID)
+	assert.Equal(t, clothes.ID, products[1].ID)
+	assert.Equal(t, electronics.ID, products[2].ID)
+	assert.Equal(t, second.ID, products[2].ReceptionId)
+}
+
+func testDeletePVZCascade(t *testing.T, ctx context.Context, db Storage) {
+	deleted := createPVZ(t, ctx, db, "Москва", time.Now())
+	kept := createPVZ(t, ctx, db, "Казань", time.Now())
+
+	rec, err := db.CreateReception(ctx, deleted.ID)
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, deleted.ID, "одежда")
+	require.NoError(t, err)
+	keptRec, err := db.CreateReception(ctx, kept.ID)
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, kept.ID, "обувь")
+	require.NoError(t, err)
+
+	require.NoError(t, db.DeletePVZ(ctx, deleted.ID))
+	assert.ErrorIs(t, db.DeletePVZ(ctx, deleted.ID), database.ErrNotFound)
+
+	_, err = db.GetPVZByID(ctx, deleted.ID)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+	recs, err := db.GetReceptionsByPVZ(ctx, deleted.ID, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+	count, err := db.CountProductsByReception(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	count, err = db.CountProductsByReception(ctx, keptRec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	counts, err := db.CountOpenReceptionsByCity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Казань": 1}, counts)
+}
+
+func testExportProducts(t *testing.T, ctx context.Context, db Storage) {
+	moscow := createPVZ(t, ctx, db, "Москва", time.Now())
+	kazan := createPVZ(t, ctx, db, "Казань", time.Now())
+
+	first, err := db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, moscow.ID, "обувь")
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, moscow.ID, "одежда")
+	require.NoError(t, err)
+	_, err = db.CloseLastReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = db.CreateReception(ctx, kazan.ID)
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, kazan.ID, "электроника")
+	require.NoError(t, err)
+	_, err = db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+
+	export := func(filter models.ExportFilter) []models.ExportRow {
+		var rows []models.ExportRow
+		require.NoError(t, db.ExportProducts(ctx, filter, func(row *models.ExportRow) error {
+			rows = append(rows, *row)
+			return nil
+		}))
+		return rows
+	}
+
+	rows := export(models.ExportFilter{})
+	require.Len(t, rows, 3, "приёмки без товаров в выгрузку не попадают")
+	assert.Equal(t, "обувь", rows[0].Product.Type)
+	assert.Equal(t, "одежда", rows[1].Product.Type)
+	assert.Equal(t, "электроника", rows[2].Product.Type)
+	assert.Equal(t, first.ID, rows[0].Reception.ID)
+	assert.Equal(t, "close", rows[0].Reception.Status)
+	assert.Equal(t, moscow.ID, rows[0].PVZ.ID)
+	assert.Equal(t, "Москва", rows[0].PVZ.City)
+	assert.Equal(t, rows[0].Reception.ID, rows[0].Product.ReceptionId)
+
+	assert.Len(t, export(models.ExportFilter{City: "Казань"}), 1)
+	assert.Len(t, export(models.ExportFilter{PVZId: &moscow.ID}), 2)
+	assert.Empty(t, export(models.ExportFilter{City: "Казань", PVZId: &moscow.ID}))
+
+	after := first.DateTime.Add(time.Millisecond)
+	rows = export(models.ExportFilter{From: &after})
+	require.Len(t, rows, 1)
+	assert.Equal(t, "электроника", rows[0].Product.Type)
+	rows = export(models.ExportFilter{To: &first.DateTime})
+	assert.Len(t, rows, 2, "границы периода включаются")
+
+	stop := errors.New("stop")
+	calls := 0
+	err = db.ExportProducts(ctx, models.ExportFilter{}, func(row *models.ExportRow) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func testIntakeAnalytics(t *testing.T, ctx context.Context, db Storage) {
+	moscow := createPVZ(t, ctx, db, "Москва", time.Now())
+	kazan := createPVZ(t, ctx, db, "Казань", time.Now())
+
+	_, err := db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	for _, productType := range []string{"обувь", "обувь", "одежда"} {
+		_, err = db.AddProduct(ctx, moscow.ID, productType)
+		require.NoError(t, err)
+	}
+	_, err = db.CloseLastReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	_, err = db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	_, err = db.CreateReception(ctx, kazan.ID)
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, kazan.ID, "электроника")
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	query := models.IntakeQuery{
+		From:    day.Add(-24 * time.Hour),
+		To:      now.Add(time.Hour),
+		Bucket:  models.BucketDay,
+		GroupBy: []string{models.GroupByCity},
+	}
+
+	rows, err := db.IntakeAnalytics(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.True(t, rows[0].Bucket.Equal(day), "интервал начинается с полуночи UTC: %v", rows[0].Bucket)
+	assert.Equal(t, "Казань", rows[0].City)
+	assert.Equal(t, 1, rows[0].Receptions)
+	assert.Equal(t, 1, rows[0].Products)
+	assert.Nil(t, rows[0].AvgReceptionDurationSeconds, "в Казани нет закрытых приёмок")
+	assert.Equal(t, "Москва", rows[1].City)
+	assert.Nil(t, rows[1].PVZId)
+	assert.Equal(t, 2, rows[1].Receptions)
+	assert.Equal(t, 3, rows[1].Products)
+	if assert.NotNil(t, rows[1].AvgReceptionDurationSeconds) {
+		assert.GreaterOrEqual(t, *rows[1].AvgReceptionDurationSeconds, 0.0)
+	}
+
+	query.GroupBy = []string{models.GroupByPVZ, models.GroupByProductType}
+	query.City = "Москва"
+	rows, err = db.IntakeAnalytics(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, rows, 2, "приёмки без товаров не попадают в разбивку по типам")
+	assert.Equal(t, "обувь", rows[0].ProductType)
+	assert.Equal(t, 2, rows[0].Products)
+	assert.Equal(t, "одежда", rows[1].ProductType)
+	assert.Equal(t, 1, rows[1].Receptions)
+	if assert.NotNil(t, rows[1].PVZId) {
+		assert.Equal(t, moscow.ID, *rows[1].PVZId)
+	}
+	assert.Empty(t, rows[1].City)
+
+	query = models.IntakeQuery{From: day.Add(-48 * time.Hour), To: day.Add(-24 * time.Hour), Bucket: models.BucketHour}
+	rows, err = db.IntakeAnalytics(ctx, query)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func testAutoCloseReceptions(t *testing.T, ctx context.Context, db Storage) {
+	moscow := createPVZ(t, ctx, db, "Москва", time.Now())
+	kazan := createPVZ(t, ctx, db, "Казань", time.Now())
+	stale, err := db.CreateReception(ctx, moscow.ID)
+	require.NoError(t, err)
+	_, err = db.CreateReception(ctx, kazan.ID)
+	require.NoError(t, err)
+
+	now := time.Now().Add(2 * time.Hour)
+	limits := models.AutoCloseLimits{Default: time.Hour, ByCity: map[string]time.Duration{"Казань": 3 * time.Hour}}
+	closed, acquired, err := db.AutoCloseReceptions(ctx, now, limits)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	require.Len(t, closed, 1)
+	assert.Equal(t, stale.ID, closed[0].ID)
+	assert.Equal(t, moscow.ID, closed[0].PVZId)
+	assert.Equal(t, "Москва", closed[0].City)
+	assert.WithinDuration(t, now, closed[0].ClosedAt, time.Microsecond)
+
+	counts, err := db.CountOpenReceptionsByCity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"Казань": 1}, counts)
+
+	closed, _, err = db.AutoCloseReceptions(ctx, now, limits)
+	require.NoError(t, err)
+	assert.Empty(t, closed, "закрытые приёмки не закрываются повторно")
+
+	_, err = db.CreateReception(ctx, moscow.ID)
+	assert.NoError(t, err, "после автозакрытия можно открыть новую приёмку")
+}
+
+func testOutbox(t *testing.T, ctx context.Context, db Storage) {
+	relay := func(limit int, fail error) []models.OutboxEvent {
+		t.Helper()
+		var events []models.OutboxEvent
+		_, acquired, err := db.RelayOutbox(ctx, limit, func(ctx context.Context, event models.OutboxEvent) error {
+			if fail != nil && len(events) == 1 {
+				return fail
+			}
+			events = append(events, event)
+			return nil
+		})
+		assert.True(t, acquired)
+		if fail != nil {
+			require.ErrorIs(t, err, fail)
+		} else {
+			require.NoError(t, err)
+		}
+		return events
+	}
+	types := func(events []models.OutboxEvent) []string {
+		var result []string
+		for _, e := range events {
+			result = append(result, e.Type)
+		}
+		return result
+	}
+
+	pvz := createPVZ(t, ctx, db, "Москва", time.Now())
+	rec, err := db.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	product, err := db.AddProduct(ctx, pvz.ID, "обувь")
+	require.NoError(t, err)
+	require.NoError(t, db.DeleteLastProduct(ctx, pvz.ID))
+	_, err = db.AddProduct(ctx, pvz.ID, "одежда")
+	require.NoError(t, err)
+	_, err = db.CloseLastReception(ctx, pvz.ID)
+	require.NoError(t, err)
+
+	events := relay(100, nil)
+	assert.Equal(t, []string{
+		models.EventPVZCreated, models.EventReceptionOpened, models.EventProductAdded,
+		models.EventProductRemoved, models.EventProductAdded, models.EventReceptionClosed,
+	}, types(events))
+	for i, e := range events {
+		assert.Equal(t, pvz.ID, e.PVZId)
+		if i > 0 {
+			assert.Greater(t, e.ID, events[i-1].ID)
+		}
+	}
+	var removed models.Product
+	require.NoError(t, json.Unmarshal(events[3].Payload, &removed))
+	assert.Equal(t, product.ID, removed.ID)
+	var closed models.ReceptionClosedPayload
+	require.NoError(t, json.Unmarshal(events[5].Payload, &closed))
+	assert.Equal(t, rec.ID, closed.ID)
+	assert.Equal(t, "close", closed.Status)
+	assert.Equal(t, models.CloseReasonManual, closed.CloseReason)
+
+	assert.Empty(t, relay(100, nil), "опубликованные события удаляются")
+
+	_, err = db.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, pvz.ID, "электроника")
+	require.NoError(t, err)
+	_, err = db.AddProduct(ctx, pvz.ID, "обувь")
+	require.NoError(t, err)
+
+	failure := errors.New("broker unavailable")
+	assert.Equal(t, []string{models.EventReceptionOpened}, types(relay(100, failure)))
+	assert.Equal(t, []string{models.EventProductAdded}, types(relay(1, nil)), "после ошибки публикация продолжается с неопубликованного события")
+	assert.Equal(t, []string{models.EventProductAdded}, types(relay(100, nil)))
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Ошибки БД, по которым сервисный слой выбирает ответ. Ошибка драйвера
+// оборачивается через %w и остается доступной для журнала.
+var (
+	ErrNotFound             = errors.New("запись не найдена")
+	ErrAlreadyExists        = errors.New("запись уже существует")
+	ErrUnavailable          = errors.New("база данных недоступна")
+	ErrReceptionAlreadyOpen = errors.New("Активная приёмка уже существует")
+	ErrNoActiveReception    = errors.New("Нет активной приёмки для данного ПВЗ")
+	ErrNoProductToDelete    = errors.New("Нет товаров для удаления")
+)
+
+// OpenReceptionIndex — частичный уникальный индекс, который допускает не больше одной
+// открытой приёмки на ПВЗ.
+const OpenReceptionIndex = "receptions_one_open_per_pvz_idx"
+
+// classify приводит ошибку драйвера к ошибкам пакета. Нарушение внешнего ключа
+// означает ссылку на несуществующую запись, поэтому тоже считается ErrNotFound.
+func classify(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, pgx.ErrNoRows), hasPgCode(err, "23503"):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case violatesConstraint(err, OpenReceptionIndex):
+		return fmt.Errorf("%w: %w", ErrReceptionAlreadyOpen, err)
+	case hasPgCode(err, "23505"):
+		return fmt.Errorf("%w: %w", ErrAlreadyExists, err)
+	case isUnavailable(err):
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
+
+func hasPgCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+func violatesConstraint(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// isUnavailable сообщает, что запрос не выполнен из-за связи с БД: соединение не
+// установлено или разорвано, сервер перезапускается или исчерпал соединения.
+func isUnavailable(err error) bool {
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &connectErr), errors.As(err, &netErr):
+		return true
+	case errors.As(err, &pgErr):
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "53300" || strings.HasPrefix(pgErr.Code, "57P")
+	}
+	return false
+}
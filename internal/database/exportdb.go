@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"pvz/internal/models"
+)
+
+// exportFetchSize — сколько строк выгрузки читается из курсора за один FETCH.
+const exportFetchSize = 500
+
+// ExportProducts читает товары с приёмками и ПВЗ через серверный курсор и передает их в fn
+// по одной строке, поэтому память не растет вместе с периодом выгрузки.
+func (db *PGXDatabase) ExportProducts(ctx context.Context, filter models.ExportFilter, fn func(row *models.ExportRow) error) (err error) {
+	defer db.observe(ctx, "ExportProducts", time.Now(), &err)
+	query := `
+		SELECT z.id, z.registration_date, z.city, r.id, r.date_time, r.status, p.id, p.date_time, p.type
+		FROM products p
+		JOIN receptions r ON r.id = p.reception_id
+		JOIN pvz z ON z.id = r.pvz_id`
+	args := []interface{}{}
+
+	conditions := []string{}
+	if filter.From != nil {
+		conditions = append(conditions, fmt.Sprintf("r.date_time >= $%d", len(args)+1))
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, fmt.Sprintf("r.date_time <= $%d", len(args)+1))
+		args = append(args, *filter.To)
+	}
+	if filter.City != "" {
+		conditions = append(conditions, fmt.Sprintf("z.city = $%d", len(args)+1))
+		args = append(args, filter.City)
+	}
+	if filter.PVZId != nil {
+		conditions = append(conditions, fmt.Sprintf("z.id = $%d", len(args)+1))
+		args = append(args, *filter.PVZId)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY r.date_time, p.date_time"
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Выгрузка только читает данные, транзакция нужна лишь для курсора.
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `DECLARE export_cursor NO SCROLL CURSOR FOR `+query, args...); err != nil {
+		return err
+	}
+	fetchQuery := fmt.Sprintf(`FETCH %d FROM export_cursor`, exportFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetchQuery)
+		if err != nil {
+			return err
+		}
+		fetched := 0
+		for rows.Next() {
+			fetched++
+			var row models.ExportRow
+			if err := rows.Scan(
+				&row.PVZ.ID, &row.PVZ.RegistrationDate, &row.PVZ.City,
+				&row.Reception.ID, &row.Reception.DateTime, &row.Reception.Status,
+				&row.Product.ID, &row.Product.DateTime, &row.Product.Type,
+			); err != nil {
+				rows.Close()
+				return err
+			}
+			row.Reception.PVZId = row.PVZ.ID
+			row.Product.ReceptionId = row.Reception.ID
+			if err := fn(&row); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/models"
+)
+
+func TestExportProducts(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{"id", "registration_date", "city", "id", "date_time", "status", "id", "date_time", "type"}
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	pvzId := uuid.New()
+
+	t.Run("Reads cursor in batches", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		full := pgxmock.NewRows(columns)
+		for i := 0; i < exportFetchSize; i++ {
+			full.AddRow(pvzId, from, "Москва", uuid.New(), from, "close", uuid.New(), from, "обувь")
+		}
+		lastReception, lastProduct := uuid.New(), uuid.New()
+		mockPool.ExpectBegin()
+		mockPool.
+			ExpectExec(regexp.QuoteMeta("DECLARE export_cursor NO SCROLL CURSOR FOR")).
+			WithArgs(from, "Москва").
+			WillReturnResult(pgxmock.NewResult("DECLARE CURSOR", 0))
+		mockPool.ExpectQuery(regexp.QuoteMeta("FETCH 500 FROM export_cursor")).WillReturnRows(full)
+		mockPool.ExpectQuery(regexp.QuoteMeta("FETCH 500 FROM export_cursor")).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(pvzId, from, "Москва", lastReception, from, "close", lastProduct, from, "одежда"))
+		mockPool.ExpectRollback()
+
+		db := NewPGXDatabase(mockPool)
+		var got []*models.ExportRow
+		err = db.ExportProducts(ctx, models.ExportFilter{From: &from, City: "Москва"}, func(row *models.ExportRow) error {
+			got = append(got, row)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, got, exportFetchSize+1)
+		last := got[len(got)-1]
+		assert.Equal(t, lastProduct, last.Product.ID)
+		assert.Equal(t, lastReception, last.Product.ReceptionId)
+		assert.Equal(t, pvzId, last.Reception.PVZId)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Callback error stops export", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.
+			ExpectExec(regexp.QuoteMeta("WHERE z.id = $1 ORDER BY r.date_time, p.date_time")).
+			WithArgs(pvzId).
+			WillReturnResult(pgxmock.NewResult("DECLARE CURSOR", 0))
+		mockPool.ExpectQuery(regexp.QuoteMeta("FETCH 500 FROM export_cursor")).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(pvzId, from, "Казань", uuid.New(), from, "close", uuid.New(), from, "обувь").
+				AddRow(pvzId, from, "Казань", uuid.New(), from, "close", uuid.New(), from, "обувь"))
+		mockPool.ExpectRollback()
+
+		expectedErr := errors.New("write error")
+		calls := 0
+		db := NewPGXDatabase(mockPool)
+		err = db.ExportProducts(ctx, models.ExportFilter{PVZId: &pvzId}, func(row *models.ExportRow) error {
+			calls++
+			return expectedErr
+		})
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Declare error", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		expectedErr := errors.New("declare error")
+		mockPool.ExpectBegin()
+		mockPool.ExpectExec(regexp.QuoteMeta("DECLARE export_cursor")).WillReturnError(expectedErr)
+		mockPool.ExpectRollback()
+
+		db := NewPGXDatabase(mockPool)
+		err = db.ExportProducts(ctx, models.ExportFilter{}, func(row *models.ExportRow) error { return nil })
+		assert.EqualError(t, err, expectedErr.Error())
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
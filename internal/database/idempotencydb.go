@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"pvz/internal/idempotency"
+)
+
+func (db *PGXDatabase) ReserveIdempotencyKey(ctx context.Context, userID, key, requestHash string, ttl time.Duration) (existing *idempotency.Record, err error) {
+	defer db.observe(ctx, "ReserveIdempotencyKey", time.Now(), &err)
+	now := time.Now()
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET request_hash=EXCLUDED.request_hash, status_code=NULL, content_type=NULL, response_body=NULL,
+			created_at=EXCLUDED.created_at, expires_at=EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < EXCLUDED.created_at
+	`
+	tag, err := db.pool.Exec(ctx, query, userID, key, requestHash, now, now.Add(ttl))
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	existing = &idempotency.Record{}
+	var statusCode *int
+	var contentType *string
+	selectQuery := `
+		SELECT request_hash, status_code, content_type, response_body
+		FROM idempotency_keys
+		WHERE user_id=$1 AND idempotency_key=$2
+	`
+	err = db.pool.QueryRow(ctx, selectQuery, userID, key).Scan(&existing.RequestHash, &statusCode, &contentType, &existing.Body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &idempotency.Record{RequestHash: requestHash}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != nil {
+		existing.Completed = true
+		existing.StatusCode = *statusCode
+	}
+	if contentType != nil {
+		existing.ContentType = *contentType
+	}
+	return existing, nil
+}
+
+func (db *PGXDatabase) CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) (err error) {
+	defer db.observe(ctx, "CompleteIdempotencyKey", time.Now(), &err)
+	query := `
+		UPDATE idempotency_keys
+		SET status_code=$3, content_type=$4, response_body=$5
+		WHERE user_id=$1 AND idempotency_key=$2
+	`
+	_, err = db.pool.Exec(ctx, query, userID, key, statusCode, contentType, body)
+	return err
+}
+
+func (db *PGXDatabase) ReleaseIdempotencyKey(ctx context.Context, userID, key string) (err error) {
+	defer db.observe(ctx, "ReleaseIdempotencyKey", time.Now(), &err)
+	query := `DELETE FROM idempotency_keys WHERE user_id=$1 AND idempotency_key=$2 AND status_code IS NULL`
+	_, err = db.pool.Exec(ctx, query, userID, key)
+	return err
+}
+
+func (db *PGXDatabase) DeleteExpiredIdempotencyKeys(ctx context.Context) (deleted int64, err error) {
+	defer db.observe(ctx, "DeleteExpiredIdempotencyKeys", time.Now(), &err)
+	query := `DELETE FROM idempotency_keys WHERE expires_at < $1`
+	tag, err := db.pool.Exec(ctx, query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
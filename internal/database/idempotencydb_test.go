@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	const userID, key, hash = "user-1", "key-1", "hash-1"
+
+	t.Run("Reserved", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		db := NewPGXDatabase(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).
+			WithArgs(userID, key, hash, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		existing, err := db.ReserveIdempotencyKey(ctx, userID, key, hash, time.Hour)
+		assert.NoError(t, err)
+		assert.Nil(t, existing)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Completed", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		db := NewPGXDatabase(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).
+			WithArgs(userID, key, hash, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		statusCode, contentType := 201, "application/json"
+		mockPool.ExpectQuery(regexp.QuoteMeta("SELECT request_hash, status_code, content_type, response_body")).
+			WithArgs(userID, key).
+			WillReturnRows(pgxmock.NewRows([]string{"request_hash", "status_code", "content_type", "response_body"}).
+				AddRow(hash, &statusCode, &contentType, []byte(`{"id":"1"}`)))
+
+		existing, err := db.ReserveIdempotencyKey(ctx, userID, key, hash, time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, existing.Completed)
+		assert.Equal(t, 201, existing.StatusCode)
+		assert.Equal(t, contentType, existing.ContentType)
+		assert.Equal(t, []byte(`{"id":"1"}`), existing.Body)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Pending", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		db := NewPGXDatabase(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).
+			WithArgs(userID, key, "other", pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		mockPool.ExpectQuery(regexp.QuoteMeta("SELECT request_hash, status_code, content_type, response_body")).
+			WithArgs(userID, key).
+			WillReturnRows(pgxmock.NewRows([]string{"request_hash", "status_code", "content_type", "response_body"}).
+				AddRow(hash, nil, nil, nil))
+
+		existing, err := db.ReserveIdempotencyKey(ctx, userID, key, "other", time.Hour)
+		assert.NoError(t, err)
+		assert.False(t, existing.Completed)
+		assert.Equal(t, hash, existing.RequestHash)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Released concurrently", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		db := NewPGXDatabase(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).
+			WithArgs(userID, key, hash, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		mockPool.ExpectQuery(regexp.QuoteMeta("SELECT request_hash, status_code, content_type, response_body")).
+			WithArgs(userID, key).
+			WillReturnError(pgx.ErrNoRows)
+
+		existing, err := db.ReserveIdempotencyKey(ctx, userID, key, hash, time.Hour)
+		assert.NoError(t, err)
+		assert.False(t, existing.Completed)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Exec Error", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		db := NewPGXDatabase(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).
+			WithArgs(userID, key, hash, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("exec error"))
+
+		existing, err := db.ReserveIdempotencyKey(ctx, userID, key, hash, time.Hour)
+		assert.EqualError(t, err, "exec error")
+		assert.Nil(t, existing)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
+
+func TestCompleteAndReleaseIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	db := NewPGXDatabase(mockPool)
+	mockPool.ExpectExec(regexp.QuoteMeta("UPDATE idempotency_keys")).
+		WithArgs("user-1", "key-1", 201, "application/json", []byte("{}")).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE user_id=$1 AND idempotency_key=$2 AND status_code IS NULL")).
+		WithArgs("user-1", "key-2").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mockPool.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE expires_at < $1")).
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+
+	assert.NoError(t, db.CompleteIdempotencyKey(ctx, "user-1", "key-1", 201, "application/json", []byte("{}")))
+	assert.NoError(t, db.ReleaseIdempotencyKey(ctx, "user-1", "key-2"))
+	deleted, err := db.DeleteExpiredIdempotencyKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
@@ -0,0 +1,695 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"pvz/internal/models"
+	pb "pvz/internal/pb/pvz_v1"
+)
+
+// MemoryDatabase хранит данные в памяти процесса и повторяет поведение PGXDatabase:
+// ограничения схемы, каскадное удаление, сортировку и ошибки пакета. Используется для
+// локального запуска без Postgres (STORAGE=memory) и в тестах.
+type MemoryDatabase struct {
+	mu         sync.RWMutex
+	users      map[uuid.UUID]models.User
+	emails     map[string]uuid.UUID
+	pvzs       []models.PVZ
+	versions   map[uuid.UUID]int64
+	receptions []models.Reception
+	closures   map[uuid.UUID]closure
+	products   []models.Product
+
+	outbox      bool
+	notify      func(event models.OutboxEvent)
+	events      []models.OutboxEvent
+	nextEventID int64
+	relayMu     sync.Mutex
+}
+
+// closure хранит колонки closed_at и close_reason закрытой приёмки.
+type closure struct {
+	at     time.Time
+	reason string
+}
+
+var _ Database = (*MemoryDatabase)(nil)
+
+type MemoryOption func(db *MemoryDatabase)
+
+// WithMemoryOutbox включает запись доменных событий, как WithOutbox для PGXDatabase.
+func WithMemoryOutbox() MemoryOption {
+	return func(db *MemoryDatabase) {
+		db.outbox = true
+	}
+}
+
+// WithMemoryNotify передает каждое доменное событие в fn, заменяя LISTEN/NOTIFY:
+// хранилище в памяти работает в одном процессе. fn не должна обращаться к хранилищу.
+func WithMemoryNotify(fn func(event models.OutboxEvent)) MemoryOption {
+	return func(db *MemoryDatabase) {
+		db.notify = fn
+	}
+}
+
+func NewMemoryDatabase(opts ...MemoryOption) *MemoryDatabase {
+	db := &MemoryDatabase{
+		users:    make(map[uuid.UUID]models.User),
+		emails:   make(map[string]uuid.UUID),
+		closures: make(map[uuid.UUID]closure),
+		versions: make(map[uuid.UUID]int64),
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+	return db
+}
+
+// storedTime приводит время к точности timestamptz: pgx отбрасывает наносекунды.
+func storedTime(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+func checkViolation(constraint string) error {
+	return &pgconn.PgError{Code: "23514", Message: "new row violates check constraint", ConstraintName: constraint}
+}
+
+func (db *MemoryDatabase) CreateUser(ctx context.Context, user *models.User) (err error) {
+	if user.Role != "employee" && user.Role != "moderator" {
+		return checkViolation("users_role_check")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.emails[user.Email]; ok {
+		return classify(&pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint", ConstraintName: "users_email_key"})
+	}
+	user.ID = uuid.New()
+	db.users[user.ID] = *user
+	db.emails[user.Email] = user.ID
+	return nil
+}
+
+func (db *MemoryDatabase) GetUserByEmail(ctx context.Context, email string) (user *models.User, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	id, ok := db.emails[email]
+	if !ok {
+		return &models.User{}, ErrNotFound
+	}
+	u := db.users[id]
+	return &u, nil
+}
+
+func (db *MemoryDatabase) GetUserByID(ctx context.Context, userID uuid.UUID) (user *models.User, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	u, ok := db.users[userID]
+	if !ok {
+		return &models.User{}, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (db *MemoryDatabase) ListUsers(ctx context.Context) (users []models.User, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, u := range db.users {
+		u.Password = ""
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+func (db *MemoryDatabase) CreatePVZ(ctx context.Context, pvz *models.PVZ) (err error) {
+	if pvz.City != "Москва" && pvz.City != "Санкт-Петербург" && pvz.City != "Казань" {
+		return checkViolation("pvz_city_check")
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	pvz.ID = uuid.New()
+	stored := *pvz
+	stored.RegistrationDate = storedTime(pvz.RegistrationDate)
+	db.pvzs = append(db.pvzs, stored)
+	db.versions[stored.ID] = 1
+	db.emit(models.EventPVZCreated, stored.ID, stored)
+	return nil
+}
+
+func (db *MemoryDatabase) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (pvz *models.PVZ, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if i := db.pvzIndex(pvzId); i >= 0 {
+		p := db.pvzs[i]
+		return &p, nil
+	}
+	return &models.PVZ{}, ErrNotFound
+}
+
+func (db *MemoryDatabase) DeletePVZ(ctx context.Context, pvzId uuid.UUID) (err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	i := db.pvzIndex(pvzId)
+	if i < 0 {
+		return ErrNotFound
+	}
+	db.pvzs = append(db.pvzs[:i], db.pvzs[i+1:]...)
+	delete(db.versions, pvzId)
+
+	deleted := make(map[uuid.UUID]bool)
+	receptions := db.receptions[:0]
+	for _, rec := range db.receptions {
+		if rec.PVZId == pvzId {
+			deleted[rec.ID] = true
+			delete(db.closures, rec.ID)
+			continue
+		}
+		receptions = append(receptions, rec)
+	}
+	db.receptions = receptions
+	products := db.products[:0]
+	for _, prod := range db.products {
+		if !deleted[prod.ReceptionId] {
+			products = append(products, prod)
+		}
+	}
+	db.products = products
+	return nil
+}
+
+func (db *MemoryDatabase) GetPVZs(ctx context.Context, limit, offset int) (pvzs []models.PVZ, err error) {
+	db.mu.RLock()
+	sorted := append([]models.PVZ(nil), db.pvzs...)
+	db.mu.RUnlock()
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].RegistrationDate.After(sorted[j].RegistrationDate)
+	})
+	if offset >= len(sorted) {
+		return nil, nil
+	}
+	sorted = sorted[offset:]
+	if limit < len(sorted) {
+		sorted = sorted[:limit]
+	}
+	return sorted, nil
+}
+
+func (db *MemoryDatabase) GetPVZVersions(ctx context.Context, pvzIds []uuid.UUID) (versions []models.PVZVersion, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, id := range pvzIds {
+		if v, ok := db.versions[id]; ok {
+			versions = append(versions, models.PVZVersion{ID: id, Version: v})
+		}
+	}
+	return versions, nil
+}
+
+func (db *MemoryDatabase) GetPVZ(ctx context.Context) (pvzs []*pb.PVZ, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, p := range db.pvzs {
+		pvzs = append(pvzs, &pb.PVZ{
+			Id:               p.ID.String(),
+			RegistrationDate: timestamppb.New(p.RegistrationDate),
+			City:             p.City,
+		})
+	}
+	return pvzs, nil
+}
+
+func (db *MemoryDatabase) StreamPVZs(ctx context.Context, filter models.PVZFilter, fn func(pvz *models.PVZ) error) (err error) {
+	db.mu.RLock()
+	var pvzs []models.PVZ
+	for _, p := range db.pvzs {
+		if filter.City != "" && p.City != filter.City {
+			continue
+		}
+		if filter.From != nil && p.RegistrationDate.Before(storedTime(*filter.From)) {
+			continue
+		}
+		if filter.To != nil && p.RegistrationDate.After(storedTime(*filter.To)) {
+			continue
+		}
+		pvzs = append(pvzs, p)
+	}
+	db.mu.RUnlock()
+
+	sort.SliceStable(pvzs, func(i, j int) bool {
+		if !pvzs[i].RegistrationDate.Equal(pvzs[j].RegistrationDate) {
+			return pvzs[i].RegistrationDate.Before(pvzs[j].RegistrationDate)
+		}
+		return bytes.Compare(pvzs[i].ID[:], pvzs[j].ID[:]) < 0
+	})
+	for i := range pvzs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&pvzs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *MemoryDatabase) GetReceptionsByPVZ(ctx context.Context, pvzId uuid.UUID, startDate, endDate *time.Time) (recs []models.Reception, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, rec := range db.receptions {
+		if rec.PVZId != pvzId {
+			continue
+		}
+		if startDate != nil && rec.DateTime.Before(storedTime(*startDate)) {
+			continue
+		}
+		if endDate != nil && rec.DateTime.After(storedTime(*endDate)) {
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].DateTime.After(recs[j].DateTime) })
+	return recs, nil
+}
+
+func (db *MemoryDatabase) GetReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (recs []models.Reception, err error) {
+	wanted := make(map[uuid.UUID]bool, len(pvzIds))
+	for _, id := range pvzIds {
+		wanted[id] = true
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, rec := range db.receptions {
+		if !wanted[rec.PVZId] {
+			continue
+		}
+		if startDate != nil && rec.DateTime.Before(storedTime(*startDate)) {
+			continue
+		}
+		if endDate != nil && rec.DateTime.After(storedTime(*endDate)) {
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].DateTime.After(recs[j].DateTime) })
+	return recs, nil
+}
+
+func (db *MemoryDatabase) GetProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (products []*models.Product, err error) {
+	wanted := make(map[uuid.UUID]bool, len(receptionIds))
+	for _, id := range receptionIds {
+		wanted[id] = true
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, prod := range db.products {
+		if wanted[prod.ReceptionId] {
+			p := prod
+			products = append(products, &p)
+		}
+	}
+	sort.SliceStable(products, func(i, j int) bool { return products[i].DateTime.Before(products[j].DateTime) })
+	return products, nil
+}
+
+func (db *MemoryDatabase) GetProductsByReception(ctx context.Context, receptionID uuid.UUID) (products []*models.Product, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, prod := range db.products {
+		if prod.ReceptionId == receptionID {
+			p := prod
+			products = append(products, &p)
+		}
+	}
+	sort.SliceStable(products, func(i, j int) bool { return products[i].DateTime.Before(products[j].DateTime) })
+	return products, nil
+}
+
+func (db *MemoryDatabase) CreateReception(ctx context.Context, pvzId uuid.UUID) (rec *models.Reception, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.pvzIndex(pvzId) < 0 {
+		return rec, classify(&pgconn.PgError{Code: "23503", Message: "insert or update on table \"receptions\" violates foreign key constraint", ConstraintName: "receptions_pvz_id_fkey"})
+	}
+	if db.activeReception(pvzId) >= 0 {
+		return rec, classify(&pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint", ConstraintName: OpenReceptionIndex})
+	}
+	rec = &models.Reception{
+		ID:       uuid.New(),
+		DateTime: storedTime(time.Now()),
+		PVZId:    pvzId,
+		Status:   "in_progress",
+	}
+	db.receptions = append(db.receptions, *rec)
+	db.versions[pvzId]++
+	db.emit(models.EventReceptionOpened, pvzId, rec)
+	return rec, nil
+}
+
+func (db *MemoryDatabase) CloseLastReception(ctx context.Context, pvzId uuid.UUID) (rec *models.Reception, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	i := db.activeReception(pvzId)
+	if i < 0 {
+		return &models.Reception{}, db.noActiveReception(pvzId)
+	}
+	db.receptions[i].Status = "close"
+	db.closures[db.receptions[i].ID] = closure{at: storedTime(time.Now()), reason: models.CloseReasonManual}
+	closed := db.receptions[i]
+	db.versions[pvzId]++
+	db.emit(models.EventReceptionClosed, pvzId, models.ReceptionClosedPayload{Reception: closed, CloseReason: models.CloseReasonManual})
+	return &closed, nil
+}
+
+func (db *MemoryDatabase) AutoCloseReceptions(ctx context.Context, now time.Time, limits models.AutoCloseLimits) (closed []models.AutoClosedReception, acquired bool, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	closedAt := storedTime(now)
+	for i, rec := range db.receptions {
+		if rec.Status != "in_progress" {
+			continue
+		}
+		city := db.pvzs[db.pvzIndex(rec.PVZId)].City
+		if !rec.DateTime.Before(now.Add(-limits.For(city))) {
+			continue
+		}
+		db.receptions[i].Status = "close"
+		db.closures[rec.ID] = closure{at: closedAt, reason: models.CloseReasonAutoClosed}
+		db.versions[rec.PVZId]++
+		closed = append(closed, models.AutoClosedReception{ID: rec.ID, PVZId: rec.PVZId, City: city, DateTime: rec.DateTime, ClosedAt: closedAt})
+		db.emit(models.EventReceptionClosed, rec.PVZId, models.ReceptionClosedPayload{Reception: db.receptions[i], CloseReason: models.CloseReasonAutoClosed})
+	}
+	return closed, true, nil
+}
+
+func (db *MemoryDatabase) CountOpenReceptionsByCity(ctx context.Context) (counts map[string]int, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	counts = make(map[string]int)
+	for _, rec := range db.receptions {
+		if rec.Status != "in_progress" {
+			continue
+		}
+		if i := db.pvzIndex(rec.PVZId); i >= 0 {
+			counts[db.pvzs[i].City]++
+		}
+	}
+	return counts, nil
+}
+
+func (db *MemoryDatabase) AddProduct(ctx context.Context, pvzId uuid.UUID, productType string) (product *models.Product, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	i := db.activeReception(pvzId)
+	if i < 0 {
+		return product, db.noActiveReception(pvzId)
+	}
+	if productType != "электроника" && productType != "одежда" && productType != "обувь" {
+		return product, checkViolation("products_type_check")
+	}
+	product = &models.Product{
+		ID:          uuid.New(),
+		DateTime:    storedTime(time.Now()),
+		Type:        productType,
+		ReceptionId: db.receptions[i].ID,
+	}
+	db.products = append(db.products, *product)
+	db.versions[pvzId]++
+	db.emit(models.EventProductAdded, pvzId, product)
+	return product, nil
+}
+
+func (db *MemoryDatabase) DeleteLastProduct(ctx context.Context, pvzId uuid.UUID) (err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	i := db.activeReception(pvzId)
+	if i < 0 {
+		return db.noActiveReception(pvzId)
+	}
+	last := -1
+	for j, prod := range db.products {
+		if prod.ReceptionId != db.receptions[i].ID {
+			continue
+		}
+		if last < 0 || !prod.DateTime.Before(db.products[last].DateTime) {
+			last = j
+		}
+	}
+	if last < 0 {
+		return ErrNoProductToDelete
+	}
+	removed := db.products[last]
+	db.products = append(db.products[:last], db.products[last+1:]...)
+	db.versions[pvzId]++
+	db.emit(models.EventProductRemoved, pvzId, removed)
+	return nil
+}
+
+func (db *MemoryDatabase) CountProductsByReception(ctx context.Context, receptionID uuid.UUID) (count int, err error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, prod := range db.products {
+		if prod.ReceptionId == receptionID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (db *MemoryDatabase) ExportProducts(ctx context.Context, filter models.ExportFilter, fn func(row *models.ExportRow) error) (err error) {
+	db.mu.RLock()
+	var rows []models.ExportRow
+	for _, rec := range db.receptions {
+		if filter.From != nil && rec.DateTime.Before(storedTime(*filter.From)) {
+			continue
+		}
+		if filter.To != nil && rec.DateTime.After(storedTime(*filter.To)) {
+			continue
+		}
+		if filter.PVZId != nil && rec.PVZId != *filter.PVZId {
+			continue
+		}
+		i := db.pvzIndex(rec.PVZId)
+		if i < 0 || (filter.City != "" && db.pvzs[i].City != filter.City) {
+			continue
+		}
+		for _, prod := range db.products {
+			if prod.ReceptionId == rec.ID {
+				rows = append(rows, models.ExportRow{PVZ: db.pvzs[i], Reception: rec, Product: prod})
+			}
+		}
+	}
+	db.mu.RUnlock()
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].Reception.DateTime.Equal(rows[j].Reception.DateTime) {
+			return rows[i].Reception.DateTime.Before(rows[j].Reception.DateTime)
+		}
+		return rows[i].Product.DateTime.Before(rows[j].Product.DateTime)
+	})
+	for i := range rows {
+		if err := fn(&rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncateBucket повторяет date_trunc по UTC; неделя начинается с понедельника.
+func truncateBucket(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	switch bucket {
+	case models.BucketHour:
+		return t.Truncate(time.Hour)
+	case models.BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func (db *MemoryDatabase) IntakeAnalytics(ctx context.Context, query models.IntakeQuery) (rows []models.IntakeRow, err error) {
+	byCity := hasGroup(query.GroupBy, models.GroupByCity)
+	byPVZ := hasGroup(query.GroupBy, models.GroupByPVZ)
+	byType := hasGroup(query.GroupBy, models.GroupByProductType)
+
+	type groupKey struct {
+		bucket      time.Time
+		city        string
+		pvzId       uuid.UUID
+		productType string
+	}
+	type group struct {
+		row           models.IntakeRow
+		durationSum   float64
+		durationCount int
+	}
+	groups := make(map[groupKey]*group)
+
+	db.mu.RLock()
+	for _, rec := range db.receptions {
+		if rec.DateTime.Before(storedTime(query.From)) || !rec.DateTime.Before(storedTime(query.To)) {
+			continue
+		}
+		if query.PVZId != nil && rec.PVZId != *query.PVZId {
+			continue
+		}
+		i := db.pvzIndex(rec.PVZId)
+		if i < 0 || (query.City != "" && db.pvzs[i].City != query.City) {
+			continue
+		}
+		productsByType := make(map[string]int)
+		if !byType {
+			productsByType[""] = 0
+		}
+		for _, prod := range db.products {
+			if prod.ReceptionId != rec.ID {
+				continue
+			}
+			if byType {
+				productsByType[prod.Type]++
+			} else {
+				productsByType[""]++
+			}
+		}
+		closing, closed := db.closures[rec.ID]
+		for productType, products := range productsByType {
+			key := groupKey{bucket: truncateBucket(rec.DateTime, query.Bucket), productType: productType}
+			if byCity {
+				key.city = db.pvzs[i].City
+			}
+			if byPVZ {
+				key.pvzId = rec.PVZId
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &group{row: models.IntakeRow{Bucket: key.bucket, City: key.city, ProductType: key.productType}}
+				if byPVZ {
+					pvzId := key.pvzId
+					g.row.PVZId = &pvzId
+				}
+				groups[key] = g
+			}
+			g.row.Receptions++
+			g.row.Products += products
+			if closed {
+				g.durationSum += closing.at.Sub(rec.DateTime).Seconds()
+				g.durationCount++
+			}
+		}
+	}
+	db.mu.RUnlock()
+
+	for _, g := range groups {
+		if g.durationCount > 0 {
+			avg := g.durationSum / float64(g.durationCount)
+			g.row.AvgReceptionDurationSeconds = &avg
+		}
+		rows = append(rows, g.row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if !a.Bucket.Equal(b.Bucket) {
+			return a.Bucket.Before(b.Bucket)
+		}
+		if a.City != b.City {
+			return a.City < b.City
+		}
+		if a.PVZId != nil && b.PVZId != nil && *a.PVZId != *b.PVZId {
+			return a.PVZId.String() < b.PVZId.String()
+		}
+		return a.ProductType < b.ProductType
+	})
+	return rows, nil
+}
+
+// emit вызывается под db.mu, поэтому notify получает события в порядке изменений.
+func (db *MemoryDatabase) emit(eventType string, pvzId uuid.UUID, payload interface{}) {
+	if !db.outbox && db.notify == nil {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+	db.nextEventID++
+	event := models.OutboxEvent{
+		ID:        db.nextEventID,
+		Type:      eventType,
+		PVZId:     pvzId,
+		Payload:   data,
+		CreatedAt: storedTime(time.Now()),
+	}
+	if db.outbox {
+		db.events = append(db.events, event)
+	}
+	if db.notify != nil {
+		db.notify(event)
+	}
+}
+
+// RelayOutbox повторяет PGXDatabase.RelayOutbox; вместо advisory-блокировки проходы
+// разделяет relayMu, а публикация идет без db.mu, чтобы не блокировать запись.
+func (db *MemoryDatabase) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, event models.OutboxEvent) error) (published int, acquired bool, err error) {
+	if !db.relayMu.TryLock() {
+		return 0, false, nil
+	}
+	defer db.relayMu.Unlock()
+
+	db.mu.RLock()
+	events := append([]models.OutboxEvent(nil), db.events[:min(limit, len(db.events))]...)
+	db.mu.RUnlock()
+
+	for _, event := range events {
+		if err = publish(ctx, event); err != nil {
+			break
+		}
+		published++
+	}
+	if published > 0 {
+		db.mu.Lock()
+		db.events = db.events[published:]
+		db.mu.Unlock()
+	}
+	return published, true, err
+}
+
+func (db *MemoryDatabase) pvzIndex(pvzId uuid.UUID) int {
+	for i, p := range db.pvzs {
+		if p.ID == pvzId {
+			return i
+		}
+	}
+	return -1
+}
+
+// activeReception возвращает индекс последней открытой приёмки ПВЗ или -1.
+func (db *MemoryDatabase) activeReception(pvzId uuid.UUID) int {
+	found := -1
+	for i, rec := range db.receptions {
+		if rec.PVZId != pvzId || rec.Status != "in_progress" {
+			continue
+		}
+		if found < 0 || !rec.DateTime.Before(db.receptions[found].DateTime) {
+			found = i
+		}
+	}
+	return found
+}
+
+// noActiveReception — ошибка для ПВЗ без открытой приёмки, как в PGXDatabase.
+func (db *MemoryDatabase) noActiveReception(pvzId uuid.UUID) error {
+	if db.pvzIndex(pvzId) < 0 {
+		return ErrNotFound
+	}
+	return ErrNoActiveReception
+}
@@ -0,0 +1,45 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/database/dbtest"
+	"pvz/internal/models"
+)
+
+func TestMemoryDatabaseConformance(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) dbtest.Storage {
+		return database.NewMemoryDatabase(database.WithMemoryOutbox())
+	})
+}
+
+func TestMemoryDatabaseConcurrentReceptions(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	pvz := &models.PVZ{City: "Москва", RegistrationDate: time.Now()}
+	require.NoError(t, db.CreatePVZ(ctx, pvz))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := db.CreateReception(ctx, pvz.ID); err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, created)
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/models"
+)
+
+// EventsChannel — канал LISTEN/NOTIFY, в который пишутся доменные события.
+const EventsChannel = "pvz_events"
+
+// notifyQuery отправляет событие с id из общей последовательности, чтобы у всех реплик
+// одно и то же событие имело один id. NOTIFY доставляется слушателям после фиксации
+// транзакции в порядке фиксации.
+const notifyQuery = `
+	SELECT pg_notify($1, json_build_object(
+		'id', nextval('pvz_events_seq'),
+		'type', $2::text,
+		'pvzId', $3::uuid,
+		'payload', $4::jsonb,
+		'createdAt', now()
+	)::text)`
+
+// WithNotify включает отправку доменных событий через NOTIFY в транзакциях изменений.
+func WithNotify() Option {
+	return func(db *PGXDatabase) {
+		db.notify = true
+	}
+}
+
+// PVZChangesChannel — канал NOTIFY, в который триггер таблицы pvz сообщает о
+// добавлении, удалении или изменении ПВЗ.
+const PVZChangesChannel = "pvz_changes"
+
+// ListenEvents слушает EventsChannel на отдельном соединении и передает события в fn
+// до отмены ctx. При потере соединения переподключается; события, отправленные
+// за время переподключения, теряются.
+func ListenEvents(ctx context.Context, dsn string, fn func(event models.OutboxEvent)) {
+	listenChannel(ctx, dsn, EventsChannel, nil, func(payload string) {
+		var event models.OutboxEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			logrus.WithError(err).Warn("Не удалось разобрать событие из NOTIFY")
+			return
+		}
+		fn(event)
+	})
+}
+
+// ListenPVZChanges вызывает fn при каждом изменении ПВЗ в любой реплике до отмены ctx.
+// fn вызывается и после каждого подключения: уведомления, отправленные, пока
+// соединения не было, теряются.
+func ListenPVZChanges(ctx context.Context, dsn string, fn func()) {
+	listenChannel(ctx, dsn, PVZChangesChannel, fn, func(string) { fn() })
+}
+
+func listenChannel(ctx context.Context, dsn, channel string, subscribed func(), fn func(payload string)) {
+	const retryDelay = 5 * time.Second
+	for {
+		err := listen(ctx, dsn, channel, subscribed, fn)
+		if ctx.Err() != nil {
+			return
+		}
+		logrus.WithError(err).WithField("channel", channel).Error("Потеряно соединение LISTEN, переподключение")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func listen(ctx context.Context, dsn, channel string, subscribed func(), fn func(payload string)) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	logrus.WithField("channel", channel).Info("Подписка на уведомления БД установлена")
+	if subscribed != nil {
+		subscribed()
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		fn(notification.Payload)
+	}
+}
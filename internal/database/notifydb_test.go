@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/models"
+)
+
+func TestCreateReceptionWithNotify(t *testing.T) {
+	ctx := context.Background()
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	pvzId, receptionID := uuid.New(), uuid.New()
+	mockPool.ExpectBegin()
+	mockPool.ExpectQuery(regexp.QuoteMeta(`INSERT INTO receptions (date_time, pvz_id, status) VALUES ($1, $2, $3) RETURNING id`)).
+		WithArgs(pgxmock.AnyArg(), pvzId, "in_progress").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(receptionID))
+	mockPool.ExpectExec(regexp.QuoteMeta(notifyQuery)).
+		WithArgs(EventsChannel, models.EventReceptionOpened, pvzId, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mockPool.ExpectCommit()
+
+	db := NewPGXDatabase(mockPool, WithNotify())
+	rec, err := db.CreateReception(ctx, pvzId)
+	assert.NoError(t, err)
+	assert.Equal(t, receptionID, rec.ID)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
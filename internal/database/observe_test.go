@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
+)
+
+func querySamples(t *testing.T, method string) uint64 {
+	var m dto.Metric
+	assert.NoError(t, metrics.DBQueryDuration.WithLabelValues(method).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestObserve(t *testing.T) {
+	base, hook := test.NewNullLogger()
+	ctx := logger.WithContext(context.Background(), logrus.NewEntry(base))
+
+	t.Run("Fast query", func(t *testing.T) {
+		hook.Reset()
+		db := NewPGXDatabase(nil, WithSlowQueryThreshold(time.Hour))
+		before := querySamples(t, "FastMethod")
+
+		db.observe(ctx, "FastMethod", time.Now(), new(error))
+
+		assert.Equal(t, before+1, querySamples(t, "FastMethod"))
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("Slow query", func(t *testing.T) {
+		hook.Reset()
+		db := NewPGXDatabase(nil, WithSlowQueryThreshold(10*time.Millisecond))
+
+		db.observe(ctx, "SlowMethod", time.Now().Add(-time.Second), new(error))
+
+		entry := hook.LastEntry()
+		assert.NotNil(t, entry)
+		assert.Equal(t, logrus.WarnLevel, entry.Level)
+		assert.Equal(t, "SlowMethod", entry.Data["db_method"])
+	})
+
+	t.Run("Threshold disabled", func(t *testing.T) {
+		hook.Reset()
+		db := NewPGXDatabase(nil)
+
+		db.observe(ctx, "SlowMethod", time.Now().Add(-time.Hour), new(error))
+
+		assert.Empty(t, hook.AllEntries())
+	})
+}
+
+func TestObserveClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"no rows", pgx.ErrNoRows, ErrNotFound},
+		{"foreign key", &pgconn.PgError{Code: "23503"}, ErrNotFound},
+		{"unique", &pgconn.PgError{Code: "23505"}, ErrAlreadyExists},
+		{"too many connections", &pgconn.PgError{Code: "53300"}, ErrUnavailable},
+		{"network", &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, ErrUnavailable},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, ErrUnavailable},
+		{"domain error", ErrNoActiveReception, ErrNoActiveReception},
+	}
+	db := NewPGXDatabase(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err
+			db.observe(context.Background(), "Method", time.Now(), &err)
+			assert.ErrorIs(t, err, tt.expected)
+			assert.ErrorIs(t, err, tt.err, "исходная ошибка должна оставаться в цепочке")
+		})
+	}
+
+	var err error
+	db.observe(context.Background(), "Method", time.Now(), &err)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"pvz/internal/models"
+)
+
+const (
+	// outboxLockKey — ключ advisory-блокировки ретранслятора: события публикует одна реплика.
+	outboxLockKey int64 = 0x7076_7a02
+	// outboxPVZLockClass — класс блокировок записи событий одного ПВЗ.
+	outboxPVZLockClass int32 = 0x7076_7a03
+)
+
+// WithOutbox включает запись доменных событий в таблицу outbox в тех же транзакциях,
+// что и изменения данных.
+func WithOutbox() Option {
+	return func(db *PGXDatabase) {
+		db.outbox = true
+	}
+}
+
+func (db *PGXDatabase) emitsEvents() bool {
+	return db.outbox || db.notify
+}
+
+// emit записывает событие в outbox и отправляет NOTIFY внутри tx, если это включено.
+// Перед вставкой в outbox берется блокировка ПВЗ до конца транзакции, поэтому события
+// одного ПВЗ получают id в порядке фиксации.
+func (db *PGXDatabase) emit(ctx context.Context, tx pgx.Tx, eventType string, pvzId uuid.UUID, payload interface{}) error {
+	if !db.emitsEvents() {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if db.outbox {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, outboxPVZLockClass, pvzId.String()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO outbox (event_type, pvz_id, payload) VALUES ($1, $2, $3)`, eventType, pvzId, data); err != nil {
+			return err
+		}
+	}
+	if db.notify {
+		if _, err := tx.Exec(ctx, notifyQuery, EventsChannel, eventType, pvzId, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RelayOutbox передает в publish до limit самых старых событий по порядку и удаляет
+// опубликованные. На первой ошибке публикации проход останавливается, и оставшиеся события
+// ждут следующего прохода, поэтому доставка — не менее одного раза с сохранением порядка.
+// Если проход уже выполняет другая реплика, acquired равен false.
+func (db *PGXDatabase) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, event models.OutboxEvent) error) (published int, acquired bool, err error) {
+	defer db.observe(ctx, "RelayOutbox", time.Now(), &err)
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&acquired); err != nil {
+		return 0, false, err
+	}
+	if !acquired {
+		return 0, false, nil
+	}
+
+	query := `SELECT id, event_type, pvz_id, payload, created_at FROM outbox ORDER BY id LIMIT $1`
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return 0, true, err
+	}
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err = rows.Scan(&event.ID, &event.Type, &event.PVZId, &event.Payload, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, true, err
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, true, err
+	}
+
+	var ids []int64
+	var publishErr error
+	for _, event := range events {
+		if publishErr = publish(ctx, event); publishErr != nil {
+			break
+		}
+		ids = append(ids, event.ID)
+	}
+	if len(ids) > 0 {
+		if _, err = tx.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids); err != nil {
+			return 0, true, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return 0, true, err
+		}
+	}
+	return len(ids), true, publishErr
+}
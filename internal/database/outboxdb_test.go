@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/models"
+)
+
+func TestCreatePVZWithOutbox(t *testing.T) {
+	ctx := context.Background()
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	pvzId := uuid.New()
+	pvz := &models.PVZ{RegistrationDate: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), City: "Москва"}
+	mockPool.ExpectBegin()
+	mockPool.ExpectQuery(regexp.QuoteMeta(`INSERT INTO pvz (registration_date, city) VALUES ($1, $2) RETURNING id`)).
+		WithArgs(pvz.RegistrationDate, pvz.City).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(pvzId))
+	mockPool.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1, hashtext($2))`)).
+		WithArgs(outboxPVZLockClass, pvzId.String()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mockPool.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox (event_type, pvz_id, payload) VALUES ($1, $2, $3)`)).
+		WithArgs(models.EventPVZCreated, pvzId, []byte(`{"id":"`+pvzId.String()+`","registrationDate":"2025-04-01T00:00:00Z","city":"Москва"}`)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectCommit()
+
+	db := NewPGXDatabase(mockPool, WithOutbox())
+	assert.NoError(t, db.CreatePVZ(ctx, pvz))
+	assert.Equal(t, pvzId, pvz.ID)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestRelayOutbox(t *testing.T) {
+	ctx := context.Background()
+	lockQuery := regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)
+	selectQuery := regexp.QuoteMeta(`SELECT id, event_type, pvz_id, payload, created_at FROM outbox ORDER BY id LIMIT $1`)
+	deleteQuery := regexp.QuoteMeta(`DELETE FROM outbox WHERE id = ANY($1)`)
+	pvzId := uuid.New()
+	created := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	outboxRows := func() *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"id", "event_type", "pvz_id", "payload", "created_at"}).
+			AddRow(int64(1), models.EventReceptionOpened, pvzId, []byte(`{}`), created).
+			AddRow(int64(2), models.EventProductAdded, pvzId, []byte(`{}`), created).
+			AddRow(int64(3), models.EventProductAdded, pvzId, []byte(`{}`), created)
+	}
+
+	t.Run("Publishes and deletes events", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(lockQuery).WithArgs(outboxLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+		mockPool.ExpectQuery(selectQuery).WithArgs(100).WillReturnRows(outboxRows())
+		mockPool.ExpectExec(deleteQuery).WithArgs([]int64{1, 2, 3}).WillReturnResult(pgxmock.NewResult("DELETE", 3))
+		mockPool.ExpectCommit()
+
+		db := NewPGXDatabase(mockPool)
+		var ids []int64
+		published, acquired, err := db.RelayOutbox(ctx, 100, func(ctx context.Context, event models.OutboxEvent) error {
+			ids = append(ids, event.ID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+		assert.Equal(t, 3, published)
+		assert.Equal(t, []int64{1, 2, 3}, ids)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Publish error keeps the rest", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		publishErr := errors.New("broker unavailable")
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(lockQuery).WithArgs(outboxLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+		mockPool.ExpectQuery(selectQuery).WithArgs(100).WillReturnRows(outboxRows())
+		mockPool.ExpectExec(deleteQuery).WithArgs([]int64{1}).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockPool.ExpectCommit()
+
+		db := NewPGXDatabase(mockPool)
+		published, _, err := db.RelayOutbox(ctx, 100, func(ctx context.Context, event models.OutboxEvent) error {
+			if event.ID == 2 {
+				return publishErr
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, publishErr)
+		assert.Equal(t, 1, published)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Lock held by another replica", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(lockQuery).WithArgs(outboxLockKey).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+		mockPool.ExpectRollback()
+
+		db := NewPGXDatabase(mockPool)
+		published, acquired, err := db.RelayOutbox(ctx, 100, func(ctx context.Context, event models.OutboxEvent) error {
+			t.Fatal("события не должны публиковаться без блокировки")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.False(t, acquired)
+		assert.Zero(t, published)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
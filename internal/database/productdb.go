@@ -6,11 +6,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 
 	"pvz/internal/models"
 )
 
 func (db *PGXDatabase) DeleteLastProduct(ctx context.Context, pvzId uuid.UUID) (err error) {
+	defer db.observe(ctx, "DeleteLastProduct", time.Now(), &err)
 	tx, err := db.pool.Begin(ctx)
 	if err != nil {
 		return err
@@ -29,6 +31,9 @@ func (db *PGXDatabase) DeleteLastProduct(ctx context.Context, pvzId uuid.UUID) (
 		LIMIT 1
 	`
 	err = tx.QueryRow(ctx, query, pvzId).Scan(&receptionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = noActiveReception(ctx, tx, pvzId)
+	}
 	if err != nil {
 		tx.Rollback(ctx)
 		return err
@@ -42,11 +47,26 @@ func (db *PGXDatabase) DeleteLastProduct(ctx context.Context, pvzId uuid.UUID) (
 		LIMIT 1
 	`
 	err = tx.QueryRow(ctx, productQuery, receptionID).Scan(&productID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrNoProductToDelete
+	}
 	if err != nil {
 		tx.Rollback(ctx)
 		return err
 	}
 	deleteQuery := `DELETE FROM products WHERE id=$1`
+	if db.emitsEvents() {
+		deleteQuery += ` RETURNING id, date_time, type, reception_id`
+		product := &models.Product{}
+		err = tx.QueryRow(ctx, deleteQuery, productID).Scan(&product.ID, &product.DateTime, &product.Type, &product.ReceptionId)
+		if err != nil {
+			return err
+		}
+		if err = db.emit(ctx, tx, models.EventProductRemoved, pvzId, product); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
 	_, err = tx.Exec(ctx, deleteQuery, productID)
 	if err != nil {
 		tx.Rollback(ctx)
@@ -56,6 +76,7 @@ func (db *PGXDatabase) DeleteLastProduct(ctx context.Context, pvzId uuid.UUID) (
 }
 
 func (db *PGXDatabase) AddProduct(ctx context.Context, pvzId uuid.UUID, productType string) (product *models.Product, err error) {
+	defer db.observe(ctx, "AddProduct", time.Now(), &err)
 	tx, err := db.pool.Begin(ctx)
 	if err != nil {
 		return product, err
@@ -74,9 +95,12 @@ func (db *PGXDatabase) AddProduct(ctx context.Context, pvzId uuid.UUID, productT
 		LIMIT 1
 	`
 	err = tx.QueryRow(ctx, query, pvzId).Scan(&receptionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = noActiveReception(ctx, tx, pvzId)
+	}
 	if err != nil {
 		tx.Rollback(ctx)
-		return product, errors.New("Нет активной приёмки для данного ПВЗ")
+		return product, err
 	}
 	product = &models.Product{
 		DateTime:    time.Now(),
@@ -89,6 +113,17 @@ func (db *PGXDatabase) AddProduct(ctx context.Context, pvzId uuid.UUID, productT
 		tx.Rollback(ctx)
 		return product, err
 	}
+	err = db.emit(ctx, tx, models.EventProductAdded, pvzId, product)
+	if err != nil {
+		return product, err
+	}
 	err = tx.Commit(ctx)
 	return product, err
 }
+
+func (db *PGXDatabase) CountProductsByReception(ctx context.Context, receptionID uuid.UUID) (count int, err error) {
+	defer db.observe(ctx, "CountProductsByReception", time.Now(), &err)
+	query := `SELECT COUNT(*) FROM products WHERE reception_id=$1`
+	err = db.pool.QueryRow(ctx, query, receptionID).Scan(&count)
+	return count, err
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
 )
@@ -236,7 +237,6 @@ func TestAddProduct(t *testing.T) {
 		defer mockPool.Close()
 
 		mockPool.ExpectBegin()
-		expectedErr := errors.New("query error")
 		mockPool.
 			ExpectQuery(regexp.QuoteMeta(`SELECT id
 		FROM receptions
@@ -244,13 +244,42 @@ func TestAddProduct(t *testing.T) {
 		ORDER BY date_time DESC
 		LIMIT 1`)).
 			WithArgs(pvzId).
-			WillReturnError(expectedErr)
+			WillReturnError(pgx.ErrNoRows)
+		mockPool.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM pvz WHERE id=$1)`)).
+			WithArgs(pvzId).
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+		mockPool.ExpectRollback()
+
+		db := NewPGXDatabase(mockPool)
+		product, err := db.AddProduct(ctx, pvzId, productType)
+		assert.Nil(t, product)
+		assert.ErrorIs(t, err, ErrNoActiveReception)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("unknown pvz", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.
+			ExpectQuery(regexp.QuoteMeta(`SELECT id
+		FROM receptions
+		WHERE pvz_id=$1 AND status='in_progress'
+		ORDER BY date_time DESC
+		LIMIT 1`)).
+			WithArgs(pvzId).
+			WillReturnError(pgx.ErrNoRows)
+		mockPool.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM pvz WHERE id=$1)`)).
+			WithArgs(pvzId).
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
 		mockPool.ExpectRollback()
 
 		db := NewPGXDatabase(mockPool)
 		product, err := db.AddProduct(ctx, pvzId, productType)
 		assert.Nil(t, product)
-		assert.EqualError(t, err, "Нет активной приёмки для данного ПВЗ")
+		assert.ErrorIs(t, err, ErrNotFound)
 		assert.NoError(t, mockPool.ExpectationsWereMet())
 	})
 
@@ -353,3 +382,23 @@ func TestAddProduct(t *testing.T) {
 		assert.NoError(t, mockPool.ExpectationsWereMet())
 	})
 }
+
+func TestCountProductsByReception(t *testing.T) {
+	ctx := context.Background()
+	receptionID := uuid.New()
+
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	mockPool.
+		ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM products WHERE reception_id=$1")).
+		WithArgs(receptionID).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(7))
+
+	db := NewPGXDatabase(mockPool)
+	count, err := db.CountProductsByReception(ctx, receptionID)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
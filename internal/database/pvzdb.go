@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"pvz/internal/models"
@@ -14,11 +15,48 @@ import (
 )
 
 func (db *PGXDatabase) CreatePVZ(ctx context.Context, pvz *models.PVZ) (err error) {
+	defer db.observe(ctx, "CreatePVZ", time.Now(), &err)
 	query := `INSERT INTO pvz (registration_date, city) VALUES ($1, $2) RETURNING id`
-	return db.pool.QueryRow(ctx, query, pvz.RegistrationDate, pvz.City).Scan(&pvz.ID)
+	if !db.emitsEvents() {
+		return db.pool.QueryRow(ctx, query, pvz.RegistrationDate, pvz.City).Scan(&pvz.ID)
+	}
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err = tx.QueryRow(ctx, query, pvz.RegistrationDate, pvz.City).Scan(&pvz.ID); err != nil {
+		return err
+	}
+	if err = db.emit(ctx, tx, models.EventPVZCreated, pvz.ID, pvz); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (db *PGXDatabase) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (pvz *models.PVZ, err error) {
+	defer db.observe(ctx, "GetPVZByID", time.Now(), &err)
+	pvz = &models.PVZ{}
+	query := `SELECT id, registration_date, city FROM pvz WHERE id=$1`
+	err = db.pool.QueryRow(ctx, query, pvzId).Scan(&pvz.ID, &pvz.RegistrationDate, &pvz.City)
+	return pvz, err
+}
+
+func (db *PGXDatabase) DeletePVZ(ctx context.Context, pvzId uuid.UUID) (err error) {
+	defer db.observe(ctx, "DeletePVZ", time.Now(), &err)
+	query := `DELETE FROM pvz WHERE id=$1`
+	tag, err := db.pool.Exec(ctx, query, pvzId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
 }
 
 func (db *PGXDatabase) GetPVZs(ctx context.Context, limit, offset int) (pvzs []models.PVZ, err error) {
+	defer db.observe(ctx, "GetPVZs", time.Now(), &err)
 	query := `SELECT id, registration_date, city FROM pvz ORDER BY registration_date DESC LIMIT $1 OFFSET $2`
 	rows, err := db.pool.Query(ctx, query, limit, offset)
 	if err != nil {
@@ -35,7 +73,27 @@ func (db *PGXDatabase) GetPVZs(ctx context.Context, limit, offset int) (pvzs []m
 	return pvzs, nil
 }
 
+// GetPVZVersions возвращает версии переданных ПВЗ в произвольном порядке; неизвестные id пропускаются.
+func (db *PGXDatabase) GetPVZVersions(ctx context.Context, pvzIds []uuid.UUID) (versions []models.PVZVersion, err error) {
+	defer db.observe(ctx, "GetPVZVersions", time.Now(), &err)
+	query := `SELECT id, version FROM pvz WHERE id = ANY($1)`
+	rows, err := db.pool.Query(ctx, query, pvzIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v models.PVZVersion
+		if err := rows.Scan(&v.ID, &v.Version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
 func (db *PGXDatabase) GetReceptionsByPVZ(ctx context.Context, pvzId uuid.UUID, startDate, endDate *time.Time) (recs []models.Reception, err error) {
+	defer db.observe(ctx, "GetReceptionsByPVZ", time.Now(), &err)
 	query := `SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id=$1`
 	args := []interface{}{pvzId}
 
@@ -68,7 +126,39 @@ func (db *PGXDatabase) GetReceptionsByPVZ(ctx context.Context, pvzId uuid.UUID,
 	return recs, nil
 }
 
+// GetReceptionsByPVZs — пакетный вариант GetReceptionsByPVZ: приёмки всех переданных ПВЗ одним запросом.
+func (db *PGXDatabase) GetReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (recs []models.Reception, err error) {
+	defer db.observe(ctx, "GetReceptionsByPVZs", time.Now(), &err)
+	query := `SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = ANY($1)`
+	args := []interface{}{pvzIds}
+
+	if startDate != nil {
+		query += fmt.Sprintf(" AND date_time >= $%d", len(args)+1)
+		args = append(args, *startDate)
+	}
+	if endDate != nil {
+		query += fmt.Sprintf(" AND date_time <= $%d", len(args)+1)
+		args = append(args, *endDate)
+	}
+	query += " ORDER BY date_time DESC"
+
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec models.Reception
+		if err := rows.Scan(&rec.ID, &rec.DateTime, &rec.PVZId, &rec.Status); err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
 func (db *PGXDatabase) GetProductsByReception(ctx context.Context, receptionID uuid.UUID) (products []*models.Product, err error) {
+	defer db.observe(ctx, "GetProductsByReception", time.Now(), &err)
 	query := `SELECT id, date_time, type, reception_id FROM products WHERE reception_id=$1 ORDER BY date_time ASC`
 	rows, err := db.pool.Query(ctx, query, receptionID)
 	if err != nil {
@@ -85,7 +175,27 @@ func (db *PGXDatabase) GetProductsByReception(ctx context.Context, receptionID u
 	return products, nil
 }
 
+// GetProductsByReceptions — пакетный вариант GetProductsByReception.
+func (db *PGXDatabase) GetProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (products []*models.Product, err error) {
+	defer db.observe(ctx, "GetProductsByReceptions", time.Now(), &err)
+	query := `SELECT id, date_time, type, reception_id FROM products WHERE reception_id = ANY($1) ORDER BY date_time ASC`
+	rows, err := db.pool.Query(ctx, query, receptionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var prod models.Product
+		if err := rows.Scan(&prod.ID, &prod.DateTime, &prod.Type, &prod.ReceptionId); err != nil {
+			return nil, err
+		}
+		products = append(products, &prod)
+	}
+	return products, rows.Err()
+}
+
 func (db *PGXDatabase) GetPVZ(ctx context.Context) (pvzs []*pb.PVZ, err error) {
+	defer db.observe(ctx, "GetPVZ", time.Now(), &err)
 	query := `SELECT id, registration_date, city FROM pvz`
 	rows, err := db.pool.Query(ctx, query)
 	if err != nil {
@@ -114,3 +224,71 @@ func (db *PGXDatabase) GetPVZ(ctx context.Context) (pvzs []*pb.PVZ, err error) {
 
 	return pvzs, nil
 }
+
+// pvzFetchSize — сколько ПВЗ читается из курсора за один FETCH.
+const pvzFetchSize = 500
+
+// StreamPVZs читает ПВЗ через серверный курсор в порядке даты регистрации и передает их в fn
+// по одному. Ошибка fn или отмена ctx прерывает чтение.
+func (db *PGXDatabase) StreamPVZs(ctx context.Context, filter models.PVZFilter, fn func(pvz *models.PVZ) error) (err error) {
+	defer db.observe(ctx, "StreamPVZs", time.Now(), &err)
+	query := `SELECT id, registration_date, city FROM pvz`
+	args := []interface{}{}
+
+	conditions := []string{}
+	if filter.City != "" {
+		conditions = append(conditions, fmt.Sprintf("city = $%d", len(args)+1))
+		args = append(args, filter.City)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, fmt.Sprintf("registration_date >= $%d", len(args)+1))
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, fmt.Sprintf("registration_date <= $%d", len(args)+1))
+		args = append(args, *filter.To)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY registration_date, id"
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Транзакция нужна лишь для курсора. Откат не зависит от ctx: после отключения клиента
+	// соединение вернется в пул, а не будет закрыто из-за незавершенной транзакции.
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if _, err = tx.Exec(ctx, `DECLARE pvz_cursor NO SCROLL CURSOR FOR `+query, args...); err != nil {
+		return err
+	}
+	fetchQuery := fmt.Sprintf(`FETCH %d FROM pvz_cursor`, pvzFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetchQuery)
+		if err != nil {
+			return err
+		}
+		fetched := 0
+		for rows.Next() {
+			fetched++
+			var pvz models.PVZ
+			if err := rows.Scan(&pvz.ID, &pvz.RegistrationDate, &pvz.City); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := fn(&pvz); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if fetched < pvzFetchSize {
+			return nil
+		}
+	}
+}
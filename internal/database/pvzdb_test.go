@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
 
@@ -130,6 +131,33 @@ func TestGetPVZs(t *testing.T) {
 	})
 }
 
+func TestGetPVZVersions(t *testing.T) {
+	ctx := context.Background()
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	db := NewPGXDatabase(mockPool)
+	id1, id2 := uuid.New(), uuid.New()
+	query := regexp.QuoteMeta("SELECT id, version FROM pvz WHERE id = ANY($1)")
+
+	mockPool.ExpectQuery(query).
+		WithArgs([]uuid.UUID{id1, id2}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "version"}).AddRow(id1, int64(3)).AddRow(id2, int64(1)))
+	versions, err := db.GetPVZVersions(ctx, []uuid.UUID{id1, id2})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.PVZVersion{{ID: id1, Version: 3}, {ID: id2, Version: 1}}, versions)
+
+	mockPool.ExpectQuery(query).
+		WithArgs([]uuid.UUID{id1}).
+		WillReturnError(errors.New("select error"))
+	versions, err = db.GetPVZVersions(ctx, []uuid.UUID{id1})
+	assert.Nil(t, versions)
+	assert.EqualError(t, err, "select error")
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
 func TestGetReceptionsByPVZ(t *testing.T) {
 	ctx := context.Background()
 	pvzId := uuid.New()
@@ -238,6 +266,47 @@ func TestGetProductsByReception(t *testing.T) {
 	})
 }
 
+func TestBatchReads(t *testing.T) {
+	ctx := context.Background()
+	pvzIds := []uuid.UUID{uuid.New(), uuid.New()}
+	start := time.Now().Add(-time.Hour)
+
+	t.Run("GetReceptionsByPVZs", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		recID := uuid.New()
+		mockPool.
+			ExpectQuery(regexp.QuoteMeta("SELECT id, date_time, pvz_id, status FROM receptions WHERE pvz_id = ANY($1) AND date_time >= $2 ORDER BY date_time DESC")).
+			WithArgs(pvzIds, start).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "date_time", "pvz_id", "status"}).AddRow(recID, start, pvzIds[1], "in_progress"))
+
+		recs, err := NewPGXDatabase(mockPool).GetReceptionsByPVZs(ctx, pvzIds, &start, nil)
+		assert.NoError(t, err)
+		assert.Len(t, recs, 1)
+		assert.Equal(t, recID, recs[0].ID)
+		assert.Equal(t, pvzIds[1], recs[0].PVZId)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("GetProductsByReceptions", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		receptionIds := []uuid.UUID{uuid.New()}
+		mockPool.
+			ExpectQuery(regexp.QuoteMeta("SELECT id, date_time, type, reception_id FROM products WHERE reception_id = ANY($1) ORDER BY date_time ASC")).
+			WithArgs(receptionIds).
+			WillReturnError(errors.New("db error"))
+
+		_, err = NewPGXDatabase(mockPool).GetProductsByReceptions(ctx, receptionIds)
+		assert.EqualError(t, err, "db error")
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
+
 func TestGetPVZ(t *testing.T) {
 	ctx := context.Background()
 
@@ -279,3 +348,143 @@ func TestGetPVZ(t *testing.T) {
 		assert.NoError(t, mockPool.ExpectationsWereMet())
 	})
 }
+
+func TestStreamPVZs(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{"id", "registration_date", "city"}
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Reads cursor in batches", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		full := pgxmock.NewRows(columns)
+		for i := 0; i < pvzFetchSize; i++ {
+			full.AddRow(uuid.New(), from, "Москва")
+		}
+		last := uuid.New()
+		mockPool.ExpectBegin()
+		mockPool.
+			ExpectExec(regexp.QuoteMeta("DECLARE pvz_cursor NO SCROLL CURSOR FOR SELECT id, registration_date, city FROM pvz WHERE city = $1 AND registration_date >= $2 ORDER BY registration_date, id")).
+			WithArgs("Москва", from).
+			WillReturnResult(pgxmock.NewResult("DECLARE CURSOR", 0))
+		mockPool.ExpectQuery(regexp.QuoteMeta("FETCH 500 FROM pvz_cursor")).WillReturnRows(full)
+		mockPool.ExpectQuery(regexp.QuoteMeta("FETCH 500 FROM pvz_cursor")).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(last, from, "Москва"))
+		mockPool.ExpectRollback()
+
+		db := NewPGXDatabase(mockPool)
+		var got []uuid.UUID
+		err = db.StreamPVZs(ctx, models.PVZFilter{City: "Москва", From: &from}, func(pvz *models.PVZ) error {
+			got = append(got, pvz.ID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, got, pvzFetchSize+1)
+		assert.Equal(t, last, got[len(got)-1])
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Callback error stops reading", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.
+			ExpectExec(regexp.QuoteMeta("FROM pvz WHERE registration_date <= $1 ORDER BY")).
+			WithArgs(from).
+			WillReturnResult(pgxmock.NewResult("DECLARE CURSOR", 0))
+		mockPool.ExpectQuery(regexp.QuoteMeta("FETCH 500 FROM pvz_cursor")).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(uuid.New(), from, "Казань").
+				AddRow(uuid.New(), from, "Казань"))
+		mockPool.ExpectRollback()
+
+		expectedErr := errors.New("send error")
+		calls := 0
+		db := NewPGXDatabase(mockPool)
+		err = db.StreamPVZs(ctx, models.PVZFilter{To: &from}, func(pvz *models.PVZ) error {
+			calls++
+			return expectedErr
+		})
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
+
+func TestGetPVZByID(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		regDate := time.Now()
+		mockPool.
+			ExpectQuery(regexp.QuoteMeta("SELECT id, registration_date, city FROM pvz WHERE id=$1")).
+			WithArgs(pvzId).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "registration_date", "city"}).AddRow(pvzId.String(), regDate, "Казань"))
+
+		db := NewPGXDatabase(mockPool)
+		pvz, err := db.GetPVZByID(ctx, pvzId)
+		assert.NoError(t, err)
+		assert.Equal(t, pvzId, pvz.ID)
+		assert.Equal(t, "Казань", pvz.City)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Query Error", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		expectedErr := errors.New("query error")
+		mockPool.
+			ExpectQuery(regexp.QuoteMeta("SELECT id, registration_date, city FROM pvz WHERE id=$1")).
+			WithArgs(pvzId).
+			WillReturnError(expectedErr)
+
+		db := NewPGXDatabase(mockPool)
+		_, err = db.GetPVZByID(ctx, pvzId)
+		assert.EqualError(t, err, expectedErr.Error())
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
+
+func TestDeletePVZ(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		db := NewPGXDatabase(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta("DELETE FROM pvz WHERE id=$1")).
+			WithArgs(pvzId).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		assert.NoError(t, db.DeletePVZ(ctx, pvzId))
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		db := NewPGXDatabase(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta("DELETE FROM pvz WHERE id=$1")).
+			WithArgs(pvzId).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+		assert.ErrorIs(t, db.DeletePVZ(ctx, pvzId), pgx.ErrNoRows)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
@@ -6,11 +6,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 
 	"pvz/internal/models"
 )
 
 func (db *PGXDatabase) CloseLastReception(ctx context.Context, pvzId uuid.UUID) (rec *models.Reception, err error) {
+	defer db.observe(ctx, "CloseLastReception", time.Now(), &err)
 	tx, err := db.pool.Begin(ctx)
 	if err != nil {
 		return rec, err
@@ -29,13 +31,16 @@ func (db *PGXDatabase) CloseLastReception(ctx context.Context, pvzId uuid.UUID)
 	`
 	rec = &models.Reception{}
 	err = tx.QueryRow(ctx, query, pvzId).Scan(&rec.ID, &rec.DateTime, &rec.PVZId, &rec.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = noActiveReception(ctx, tx, pvzId)
+	}
 	if err != nil {
 		tx.Rollback(ctx)
 		return rec, err
 	}
 	updateQuery := `
 		UPDATE receptions
-		SET status='close'
+		SET status='close', closed_at=now(), close_reason='manual'
 		WHERE id=$1
 		RETURNING id, date_time, pvz_id, status
 	`
@@ -44,11 +49,29 @@ func (db *PGXDatabase) CloseLastReception(ctx context.Context, pvzId uuid.UUID)
 		tx.Rollback(ctx)
 		return rec, err
 	}
+	err = db.emit(ctx, tx, models.EventReceptionClosed, rec.PVZId, models.ReceptionClosedPayload{Reception: *rec, CloseReason: models.CloseReasonManual})
+	if err != nil {
+		return rec, err
+	}
 	err = tx.Commit(ctx)
 	return rec, err
 }
 
+// noActiveReception уточняет, почему у ПВЗ не нашлось открытой приёмки: ПВЗ нет вовсе
+// (ErrNotFound) или в нем просто нет открытой приёмки (ErrNoActiveReception).
+func noActiveReception(ctx context.Context, tx pgx.Tx, pvzId uuid.UUID) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pvz WHERE id=$1)`, pvzId).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrNoActiveReception
+}
+
 func (db *PGXDatabase) CreateReception(ctx context.Context, pvzId uuid.UUID) (rec *models.Reception, err error) {
+	defer db.observe(ctx, "CreateReception", time.Now(), &err)
 	tx, err := db.pool.Begin(ctx)
 	if err != nil {
 		return rec, err
@@ -58,17 +81,8 @@ func (db *PGXDatabase) CreateReception(ctx context.Context, pvzId uuid.UUID) (re
 			tx.Rollback(ctx)
 		}
 	}()
-	var count int
-	checkQuery := `SELECT COUNT(*) FROM receptions WHERE pvz_id=$1 AND status='in_progress'`
-	err = tx.QueryRow(ctx, checkQuery, pvzId).Scan(&count)
-	if err != nil {
-		tx.Rollback(ctx)
-		return rec, err
-	}
-	if count > 0 {
-		tx.Rollback(ctx)
-		return rec, errors.New("Активная приёмка уже существует")
-	}
+	// Вторую открытую приёмку не даст вставить частичный уникальный индекс
+	// receptions_one_open_per_pvz_idx; его нарушение classify переводит в ErrReceptionAlreadyOpen.
 	rec = &models.Reception{
 		DateTime: time.Now(),
 		PVZId:    pvzId,
@@ -80,6 +94,39 @@ func (db *PGXDatabase) CreateReception(ctx context.Context, pvzId uuid.UUID) (re
 		tx.Rollback(ctx)
 		return rec, err
 	}
+	err = db.emit(ctx, tx, models.EventReceptionOpened, rec.PVZId, rec)
+	if err != nil {
+		return rec, err
+	}
 	err = tx.Commit(ctx)
 	return rec, err
 }
+
+func (db *PGXDatabase) CountOpenReceptionsByCity(ctx context.Context) (counts map[string]int, err error) {
+	defer db.observe(ctx, "CountOpenReceptionsByCity", time.Now(), &err)
+	query := `
+		SELECT p.city, COUNT(*)
+		FROM receptions r
+		JOIN pvz p ON p.id = r.pvz_id
+		WHERE r.status='in_progress'
+		GROUP BY p.city
+	`
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts = make(map[string]int)
+	for rows.Next() {
+		var city string
+		var count int
+		if err := rows.Scan(&city, &count); err != nil {
+			return nil, err
+		}
+		counts[city] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
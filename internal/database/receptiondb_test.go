@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
 )
@@ -59,6 +61,32 @@ func TestCloseLastReception(t *testing.T) {
 			assert.NoError(t, mockPool.ExpectationsWereMet())
 		})
 
+		t.Run("Unknown PVZ", func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			assert.NoError(t, err)
+			defer mockPool.Close()
+
+			mockPool.ExpectBegin()
+			mockPool.
+				ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, date_time, pvz_id, status
+		FROM receptions
+		WHERE pvz_id=$1 AND status='in_progress'
+		ORDER BY date_time DESC
+		LIMIT 1`)).
+				WithArgs(pvzId).
+				WillReturnError(pgx.ErrNoRows)
+			mockPool.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM pvz WHERE id=$1)`)).
+				WithArgs(pvzId).
+				WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(false))
+			mockPool.ExpectRollback()
+
+			db := NewPGXDatabase(mockPool)
+			_, err = db.CloseLastReception(ctx, pvzId)
+			assert.ErrorIs(t, err, ErrNotFound)
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+
 		t.Run("Update query error", func(t *testing.T) {
 			mockPool, err := pgxmock.NewPool()
 			assert.NoError(t, err)
@@ -82,7 +110,7 @@ func TestCloseLastReception(t *testing.T) {
 			mockPool.
 				ExpectQuery(regexp.QuoteMeta(`
 		UPDATE receptions
-		SET status='close'
+		SET status='close', closed_at=now(), close_reason='manual'
 		WHERE id=$1
 		RETURNING id, date_time, pvz_id, status`)).
 				WithArgs(receptionID).
@@ -123,7 +151,7 @@ func TestCloseLastReception(t *testing.T) {
 			mockPool.
 				ExpectQuery(regexp.QuoteMeta(`
 		UPDATE receptions
-		SET status='close'
+		SET status='close', closed_at=now(), close_reason='manual'
 		WHERE id=$1
 		RETURNING id, date_time, pvz_id, status`)).
 				WithArgs(receptionID).
@@ -167,7 +195,7 @@ func TestCloseLastReception(t *testing.T) {
 			mockPool.
 				ExpectQuery(regexp.QuoteMeta(`
 		UPDATE receptions
-		SET status='close'
+		SET status='close', closed_at=now(), close_reason='manual'
 		WHERE id=$1
 		RETURNING id, date_time, pvz_id, status`)).
 				WithArgs(receptionID).
@@ -209,44 +237,22 @@ func TestCreateReception(t *testing.T) {
 			assert.NoError(t, mockPool.ExpectationsWereMet())
 		})
 
-		t.Run("Count query error", func(t *testing.T) {
-			mockPool, err := pgxmock.NewPool()
-			assert.NoError(t, err)
-			defer mockPool.Close()
-
-			mockPool.ExpectBegin()
-			expectedErr := errors.New("count query error")
-			mockPool.
-				ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM receptions WHERE pvz_id=$1 AND status='in_progress'")).
-				WithArgs(pvzId).
-				WillReturnError(expectedErr)
-			mockPool.ExpectRollback()
-
-			db := NewPGXDatabase(mockPool)
-			rec, err := db.CreateReception(ctx, pvzId)
-			assert.Nil(t, rec)
-			assert.EqualError(t, err, expectedErr.Error())
-			assert.NoError(t, mockPool.ExpectationsWereMet())
-		})
-
 		t.Run("Active reception exists", func(t *testing.T) {
 			mockPool, err := pgxmock.NewPool()
 			assert.NoError(t, err)
 			defer mockPool.Close()
 
 			mockPool.ExpectBegin()
-
-			rows := pgxmock.NewRows([]string{"count"}).AddRow(1)
 			mockPool.
-				ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM receptions WHERE pvz_id=$1 AND status='in_progress'")).
-				WithArgs(pvzId).
-				WillReturnRows(rows)
+				ExpectQuery(regexp.QuoteMeta("INSERT INTO receptions (date_time, pvz_id, status) VALUES ($1, $2, $3) RETURNING id")).
+				WithArgs(pgxmock.AnyArg(), pvzId, "in_progress").
+				WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: OpenReceptionIndex})
 			mockPool.ExpectRollback()
 
 			db := NewPGXDatabase(mockPool)
-			rec, err := db.CreateReception(ctx, pvzId)
-			assert.Nil(t, rec)
-			assert.EqualError(t, err, "Активная приёмка уже существует")
+			_, err = db.CreateReception(ctx, pvzId)
+			assert.ErrorIs(t, err, ErrReceptionAlreadyOpen)
+			assert.NotErrorIs(t, err, ErrAlreadyExists)
 			assert.NoError(t, mockPool.ExpectationsWereMet())
 		})
 
@@ -257,11 +263,6 @@ func TestCreateReception(t *testing.T) {
 
 			mockPool.ExpectBegin()
 
-			rowsCount := pgxmock.NewRows([]string{"count"}).AddRow(0)
-			mockPool.
-				ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM receptions WHERE pvz_id=$1 AND status='in_progress'")).
-				WithArgs(pvzId).
-				WillReturnRows(rowsCount)
 			expectedErr := errors.New("insert error")
 
 			mockPool.
@@ -286,12 +287,6 @@ func TestCreateReception(t *testing.T) {
 
 			mockPool.ExpectBegin()
 
-			rowsCount := pgxmock.NewRows([]string{"count"}).AddRow(0)
-			mockPool.
-				ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM receptions WHERE pvz_id=$1 AND status='in_progress'")).
-				WithArgs(pvzId).
-				WillReturnRows(rowsCount)
-
 			newReceptionID := uuid.New()
 			rowsInsert := pgxmock.NewRows([]string{"id"}).AddRow(newReceptionID.String())
 			mockPool.
@@ -317,12 +312,6 @@ func TestCreateReception(t *testing.T) {
 
 			mockPool.ExpectBegin()
 
-			rowsCount := pgxmock.NewRows([]string{"count"}).AddRow(0)
-			mockPool.
-				ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM receptions WHERE pvz_id=$1 AND status='in_progress'")).
-				WithArgs(pvzId).
-				WillReturnRows(rowsCount)
-
 			newReceptionID := uuid.New()
 
 			rowsInsert := pgxmock.NewRows([]string{"id"}).AddRow(newReceptionID.String())
@@ -345,3 +334,45 @@ func TestCreateReception(t *testing.T) {
 		})
 	})
 }
+
+func TestCountOpenReceptionsByCity(t *testing.T) {
+	ctx := context.Background()
+	query := regexp.QuoteMeta(`
+		SELECT p.city, COUNT(*)
+		FROM receptions r
+		JOIN pvz p ON p.id = r.pvz_id
+		WHERE r.status='in_progress'
+		GROUP BY p.city`)
+
+	t.Run("Success", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(query).
+			WillReturnRows(pgxmock.NewRows([]string{"city", "count"}).
+				AddRow("Москва", 2).
+				AddRow("Казань", 1))
+
+		db := NewPGXDatabase(mockPool)
+		counts, err := db.CountOpenReceptionsByCity(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"Москва": 2, "Казань": 1}, counts)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("Query Error", func(t *testing.T) {
+		mockPool, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockPool.Close()
+
+		expectedErr := errors.New("query error")
+		mockPool.ExpectQuery(query).WillReturnError(expectedErr)
+
+		db := NewPGXDatabase(mockPool)
+		counts, err := db.CountOpenReceptionsByCity(ctx)
+		assert.Nil(t, counts)
+		assert.EqualError(t, err, expectedErr.Error())
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+}
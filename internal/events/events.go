@@ -0,0 +1,51 @@
+// Package events доставляет доменные события из outbox внешним системам.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"pvz/internal/models"
+)
+
+// Publisher отправляет событие брокеру. Publish возвращает nil только после того, как
+// брокер принял событие: ретранслятор удаляет событие из outbox сразу после этого.
+type Publisher interface {
+	Publish(ctx context.Context, event models.OutboxEvent) error
+	Close() error
+}
+
+// InProcessPublisher доставляет события подписчикам внутри процесса. Используется
+// в тестах и при локальном запуске без брокера.
+type InProcessPublisher struct {
+	mu          sync.Mutex
+	subscribers []func(ctx context.Context, event models.OutboxEvent) error
+}
+
+func NewInProcessPublisher() *InProcessPublisher {
+	return &InProcessPublisher{}
+}
+
+// Subscribe добавляет подписчика. Ошибка подписчика возвращается из Publish,
+// и событие будет доставлено повторно.
+func (p *InProcessPublisher) Subscribe(fn func(ctx context.Context, event models.OutboxEvent) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscribers = append(p.subscribers, fn)
+}
+
+func (p *InProcessPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	p.mu.Lock()
+	subscribers := append([]func(ctx context.Context, event models.OutboxEvent) error(nil), p.subscribers...)
+	p.mu.Unlock()
+	for _, fn := range subscribers {
+		if err := fn(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *InProcessPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/models"
+)
+
+// subscriberBuffer — сколько событий может ждать медленный подписчик, прежде чем
+// его отключат; переподключившись с Last-Event-ID, он дочитает их из буфера ленты.
+const subscriberBuffer = 64
+
+// Feed раздает доменные события подписчикам по ПВЗ и хранит последние события
+// в кольцевом буфере фиксированного размера для продолжения по Last-Event-ID.
+type Feed struct {
+	mu          sync.Mutex
+	buffer      []models.OutboxEvent
+	next        int
+	full        bool
+	subscribers map[uuid.UUID]map[*Subscription]struct{}
+}
+
+// Subscription — подписка на события одного ПВЗ. Backlog содержит события после
+// Last-Event-ID; Reset означает, что событие Last-Event-ID уже вытеснено из буфера
+// и клиенту нужно перечитать состояние. Events закрывается при отставании подписчика.
+type Subscription struct {
+	Backlog []models.OutboxEvent
+	Reset   bool
+	Events  <-chan models.OutboxEvent
+
+	feed  *Feed
+	pvzId uuid.UUID
+	ch    chan models.OutboxEvent
+}
+
+// IsFeedEvent сообщает, попадает ли событие в ленту ПВЗ: туда идут только события приёмки.
+func IsFeedEvent(eventType string) bool {
+	switch eventType {
+	case models.EventReceptionOpened, models.EventReceptionClosed, models.EventProductAdded, models.EventProductRemoved:
+		return true
+	}
+	return false
+}
+
+func NewFeed(size int) *Feed {
+	return &Feed{
+		buffer:      make([]models.OutboxEvent, size),
+		subscribers: make(map[uuid.UUID]map[*Subscription]struct{}),
+	}
+}
+
+// Publish добавляет событие в буфер и рассылает подписчикам ПВЗ без ожидания.
+func (f *Feed) Publish(event models.OutboxEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.buffer) > 0 {
+		f.buffer[f.next] = event
+		f.next = (f.next + 1) % len(f.buffer)
+		f.full = f.full || f.next == 0
+	}
+	for sub := range f.subscribers[event.PVZId] {
+		select {
+		case sub.ch <- event:
+		default:
+			f.remove(sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe подписывает на события ПВЗ. Если lastEventID не nil, Backlog содержит
+// события ПВЗ из буфера после него. Снимок буфера и регистрация выполняются под одной
+// блокировкой, поэтому события между ними не теряются.
+func (f *Feed) Subscribe(pvzId uuid.UUID, lastEventID *int64) *Subscription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub := &Subscription{feed: f, pvzId: pvzId, ch: make(chan models.OutboxEvent, subscriberBuffer)}
+	sub.Events = sub.ch
+	if lastEventID != nil {
+		found := false
+		for _, event := range f.buffered() {
+			if found && event.PVZId == pvzId {
+				sub.Backlog = append(sub.Backlog, event)
+			}
+			if event.ID == *lastEventID {
+				found = true
+			}
+		}
+		sub.Reset = !found
+	}
+	if f.subscribers[pvzId] == nil {
+		f.subscribers[pvzId] = make(map[*Subscription]struct{})
+	}
+	f.subscribers[pvzId][sub] = struct{}{}
+	return sub
+}
+
+// Close отменяет подписку; повторный вызов безопасен.
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if _, ok := s.feed.subscribers[s.pvzId][s]; ok {
+		s.feed.remove(s)
+		close(s.ch)
+	}
+}
+
+func (f *Feed) remove(sub *Subscription) {
+	delete(f.subscribers[sub.pvzId], sub)
+	if len(f.subscribers[sub.pvzId]) == 0 {
+		delete(f.subscribers, sub.pvzId)
+	}
+}
+
+// buffered возвращает события буфера от старых к новым; вызывается под f.mu.
+func (f *Feed) buffered() []models.OutboxEvent {
+	if !f.full {
+		return f.buffer[:f.next]
+	}
+	return append(append([]models.OutboxEvent(nil), f.buffer[f.next:]...), f.buffer[:f.next]...)
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/models"
+)
+
+func TestFeed(t *testing.T) {
+	moscow, kazan := uuid.New(), uuid.New()
+	ids := func(events []models.OutboxEvent) []int64 {
+		var result []int64
+		for _, e := range events {
+			result = append(result, e.ID)
+		}
+		return result
+	}
+
+	t.Run("backlog after Last-Event-ID", func(t *testing.T) {
+		feed := NewFeed(4)
+		for id, pvzId := range []uuid.UUID{moscow, kazan, moscow, moscow, kazan} {
+			feed.Publish(models.OutboxEvent{ID: int64(id + 1), PVZId: pvzId})
+		}
+
+		last := int64(2)
+		sub := feed.Subscribe(moscow, &last)
+		defer sub.Close()
+		assert.False(t, sub.Reset)
+		assert.Equal(t, []int64{3, 4}, ids(sub.Backlog), "в backlog только события своего ПВЗ")
+
+		evicted := int64(1)
+		sub = feed.Subscribe(moscow, &evicted)
+		defer sub.Close()
+		assert.True(t, sub.Reset, "событие 1 вытеснено из буфера")
+		assert.Empty(t, sub.Backlog)
+
+		sub = feed.Subscribe(kazan, nil)
+		defer sub.Close()
+		assert.False(t, sub.Reset)
+		assert.Empty(t, sub.Backlog)
+	})
+
+	t.Run("live events by PVZ", func(t *testing.T) {
+		feed := NewFeed(4)
+		sub := feed.Subscribe(moscow, nil)
+		feed.Publish(models.OutboxEvent{ID: 1, PVZId: kazan})
+		feed.Publish(models.OutboxEvent{ID: 2, PVZId: moscow})
+		assert.Equal(t, int64(2), (<-sub.Events).ID)
+
+		sub.Close()
+		sub.Close()
+		_, ok := <-sub.Events
+		assert.False(t, ok)
+	})
+
+	t.Run("slow subscriber is dropped", func(t *testing.T) {
+		feed := NewFeed(0)
+		sub := feed.Subscribe(moscow, nil)
+		for i := 0; i <= subscriberBuffer; i++ {
+			feed.Publish(models.OutboxEvent{ID: int64(i + 1), PVZId: moscow})
+		}
+		received := 0
+		for range sub.Events {
+			received++
+		}
+		require.Equal(t, subscriberBuffer, received)
+		sub.Close()
+	})
+}
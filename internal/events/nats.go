@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"pvz/internal/models"
+)
+
+// duplicatesWindow — окно, в котором JetStream отбрасывает повторы по Nats-Msg-Id.
+const duplicatesWindow = 10 * time.Minute
+
+// NATSPublisher публикует события в JetStream в тему <prefix>.<тип события>, например
+// pvz.events.reception.closed. Id события из outbox передается как Nats-Msg-Id, поэтому
+// повторная отправка после сбоя отбрасывается брокером в пределах duplicatesWindow.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewNATSPublisher подключается к NATS и создает или обновляет поток stream,
+// в который попадают все темы с префиксом prefix.
+func NewNATSPublisher(ctx context.Context, url, stream, prefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("pvz"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       stream,
+		Subjects:   []string{prefix + ".>"},
+		Duplicates: duplicatesWindow,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка создания потока %s: %w", stream, err)
+	}
+	return &NATSPublisher{conn: conn, js: js, prefix: prefix}, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(Subject(p.prefix, event))
+	msg.Data = data
+	msg.Header.Set("Pvz-Id", event.PVZId.String())
+	_, err = p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(strconv.FormatInt(event.ID, 10)))
+	return err
+}
+
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+func Subject(prefix string, event models.OutboxEvent) string {
+	return prefix + "." + event.Type
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/models"
+)
+
+// OutboxStore — хранилище с таблицей outbox (PGXDatabase или MemoryDatabase).
+type OutboxStore interface {
+	RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, event models.OutboxEvent) error) (published int, acquired bool, err error)
+}
+
+// Relay периодически переносит события из outbox в Publisher.
+type Relay struct {
+	store     OutboxStore
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+type RelayOption func(r *Relay)
+
+func WithInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+func WithBatchSize(size int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+func NewRelay(store OutboxStore, publisher Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{store: store, publisher: publisher, interval: time.Second, batchSize: 100}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Drain публикует события, пока outbox не опустеет, не случится ошибка или проход
+// не окажется занят другой репликой. Возвращает число опубликованных событий.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, acquired, err := r.store.RelayOutbox(ctx, r.batchSize, r.publisher.Publish)
+		total += published
+		if err != nil || !acquired || published < r.batchSize {
+			return total, err
+		}
+	}
+}
+
+// Run выполняет Drain раз в interval до отмены ctx.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		published, err := r.Drain(ctx)
+		if err != nil {
+			logrus.WithError(err).WithField("published", published).Error("Ошибка публикации событий")
+		} else if published > 0 {
+			logrus.WithField("published", published).Debug("События опубликованы")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
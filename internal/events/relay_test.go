@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/models"
+)
+
+func TestRelayDrain(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase(database.WithMemoryOutbox())
+	pvz := &models.PVZ{City: "Казань", RegistrationDate: time.Now()}
+	require.NoError(t, db.CreatePVZ(ctx, pvz))
+	_, err := db.CreateReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	for _, productType := range []string{"обувь", "одежда", "электроника"} {
+		_, err = db.AddProduct(ctx, pvz.ID, productType)
+		require.NoError(t, err)
+	}
+
+	publisher := NewInProcessPublisher()
+	var received []string
+	failures := 1
+	publisher.Subscribe(func(ctx context.Context, event models.OutboxEvent) error {
+		if event.Type == models.EventProductAdded && failures > 0 {
+			failures--
+			return errors.New("broker unavailable")
+		}
+		received = append(received, Subject("pvz.events", event))
+		return nil
+	})
+	relay := NewRelay(db, publisher, WithBatchSize(2))
+
+	published, err := relay.Drain(ctx)
+	assert.EqualError(t, err, "broker unavailable")
+	assert.Equal(t, 2, published)
+
+	published, err = relay.Drain(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, published, "события публикуются порциями, пока outbox не опустеет")
+	assert.Equal(t, []string{
+		"pvz.events.pvz.created",
+		"pvz.events.reception.opened",
+		"pvz.events.product.added",
+		"pvz.events.product.added",
+		"pvz.events.product.added",
+	}, received)
+
+	published, err = relay.Drain(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+}
@@ -0,0 +1,103 @@
+// Package export записывает табличные выгрузки в CSV и XLSX построчно, не накапливая их в памяти.
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+const (
+	ContentTypeCSV  = "text/csv; charset=utf-8"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return ContentTypeXLSX
+	}
+	return ContentTypeCSV
+}
+
+// Writer записывает строки выгрузки; Close дописывает служебные данные формата.
+type Writer interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	if format == FormatXLSX {
+		return NewXLSXWriter(w)
+	}
+	return NewCSVWriter(w)
+}
+
+// Negotiate выбирает формат по заголовку Accept с учетом q-значений.
+// Пустой заголовок и */* означают CSV.
+func Negotiate(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return FormatCSV, true
+	}
+	type candidate struct {
+		format Format
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		switch mediaType {
+		case "text/csv", "text/*", "*/*":
+			candidates = append(candidates, candidate{FormatCSV, q})
+		case ContentTypeXLSX:
+			candidates = append(candidates, candidate{FormatXLSX, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].format, true
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter пишет CSV с UTF-8 BOM, чтобы Excel правильно показывал кириллицу.
+func NewCSVWriter(w io.Writer) (Writer, error) {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) WriteRow(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
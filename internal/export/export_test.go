@@ -0,0 +1,87 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+		ok     bool
+	}{
+		{"", FormatCSV, true},
+		{"*/*", FormatCSV, true},
+		{"text/csv", FormatCSV, true},
+		{ContentTypeXLSX, FormatXLSX, true},
+		{"text/csv;q=0.5, " + ContentTypeXLSX, FormatXLSX, true},
+		{ContentTypeXLSX + ";q=0.1, */*;q=0.9", FormatCSV, true},
+		{"text/csv;q=0, " + ContentTypeXLSX, FormatXLSX, true},
+		{"application/json", "", false},
+		{"text/csv;q=0", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			got, ok := Negotiate(tt.accept)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]string{"city", "type"}))
+	require.NoError(t, w.WriteRow([]string{"Москва", `обувь, "зимняя"`}))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "\uFEFFcity,type\nМосква,\"обувь, \"\"зимняя\"\"\"\n", buf.String())
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]string{"city", "type"}))
+	require.NoError(t, w.WriteRow([]string{"Москва", "<обувь & одежда>"}))
+	require.NoError(t, w.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = content
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		require.Contains(t, files, name)
+		assert.NoError(t, xml.Unmarshal(files[name], new(struct{})), "%s должен быть корректным XML", name)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type  string `xml:"t,attr"`
+				Value string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 2)
+	assert.Equal(t, "inlineStr", sheet.Rows[1].Cells[0].Type)
+	assert.Equal(t, "Москва", sheet.Rows[1].Cells[0].Value)
+	assert.Equal(t, "<обувь & одежда>", sheet.Rows[1].Cells[1].Value)
+}
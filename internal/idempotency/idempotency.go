@@ -0,0 +1,112 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderName   = "Idempotency-Key"
+	ReplayHeader = "Idempotent-Replayed"
+	MaxKeyLength = 255
+)
+
+type Record struct {
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type Store interface {
+	ReserveIdempotencyKey(ctx context.Context, userID, key, requestHash string, ttl time.Duration) (existing *Record, err error)
+	CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (deleted int64, err error)
+}
+
+func ValidKey(key string) bool {
+	return key != "" && len(key) <= MaxKeyLength
+}
+
+func Hash(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type memoryEntry struct {
+	record    Record
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry), now: time.Now}
+}
+
+func memoryKey(userID, key string) string {
+	return userID + "\x00" + key
+}
+
+func (s *MemoryStore) ReserveIdempotencyKey(ctx context.Context, userID, key, requestHash string, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if entry, ok := s.entries[memoryKey(userID, key)]; ok && now.Before(entry.expiresAt) {
+		existing := entry.record
+		return &existing, nil
+	}
+	s.entries[memoryKey(userID, key)] = &memoryEntry{
+		record:    Record{RequestHash: requestHash},
+		expiresAt: now.Add(ttl),
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[memoryKey(userID, key)]; ok {
+		entry.record.Completed = true
+		entry.record.StatusCode = statusCode
+		entry.record.ContentType = contentType
+		entry.record.Body = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (s *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[memoryKey(userID, key)]; ok && !entry.record.Completed {
+		delete(s.entries, memoryKey(userID, key))
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var deleted int64
+	for k, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,59 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	existing, err := store.ReserveIdempotencyKey(ctx, "u1", "k", "h1", time.Hour)
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	existing, err = store.ReserveIdempotencyKey(ctx, "u1", "k", "h1", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, existing.Completed)
+
+	existing, err = store.ReserveIdempotencyKey(ctx, "u2", "k", "h1", time.Hour)
+	assert.NoError(t, err)
+	assert.Nil(t, existing, "Ключи разных пользователей не должны пересекаться")
+
+	assert.NoError(t, store.CompleteIdempotencyKey(ctx, "u1", "k", 201, "application/json", []byte("{}")))
+	existing, err = store.ReserveIdempotencyKey(ctx, "u1", "k", "h2", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, existing.Completed)
+	assert.Equal(t, "h1", existing.RequestHash)
+	assert.Equal(t, 201, existing.StatusCode)
+	assert.Equal(t, []byte("{}"), existing.Body)
+
+	assert.NoError(t, store.ReleaseIdempotencyKey(ctx, "u1", "k"))
+	existing, _ = store.ReserveIdempotencyKey(ctx, "u1", "k", "h1", time.Hour)
+	assert.NotNil(t, existing, "Завершённый ключ не должен освобождаться")
+
+	assert.NoError(t, store.ReleaseIdempotencyKey(ctx, "u2", "k"))
+	existing, _ = store.ReserveIdempotencyKey(ctx, "u2", "k", "h1", time.Hour)
+	assert.Nil(t, existing)
+
+	now = now.Add(2 * time.Hour)
+	deleted, err := store.DeleteExpiredIdempotencyKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	existing, _ = store.ReserveIdempotencyKey(ctx, "u1", "k", "h2", time.Hour)
+	assert.Nil(t, existing)
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, Hash([]byte("POST"), []byte("/pvz")), Hash([]byte("POST"), []byte("/pvz")))
+	assert.NotEqual(t, Hash([]byte("POST"), []byte("/pvz")), Hash([]byte("POST/"), []byte("pvz")))
+	assert.True(t, ValidKey("abc"))
+	assert.False(t, ValidKey(""))
+}
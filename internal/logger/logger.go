@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/contextkeys"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// ValidRequestID сообщает, можно ли принять request id клиента: он попадает в логи и
+// заголовки ответа, поэтому допускаются только короткие значения без спецсимволов.
+// Правило общее для REST и gRPC.
+func ValidRequestID(requestID string) bool {
+	return validRequestID.MatchString(requestID)
+}
+
+func SetFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+		})
+	case FormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		logrus.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+		})
+		return fmt.Errorf("неизвестный формат логов: %s", format)
+	}
+	return nil
+}
+
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(contextkeys.ContextKeyLogger).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+func WithContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, contextkeys.ContextKeyLogger, entry)
+}
+
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return WithContext(ctx, FromContext(ctx).WithFields(fields))
+}
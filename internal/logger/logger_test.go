@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFormat(t *testing.T) {
+	defer logrus.SetFormatter(&logrus.TextFormatter{})
+
+	assert.NoError(t, SetFormat("json"))
+	assert.IsType(t, &logrus.JSONFormatter{}, logrus.StandardLogger().Formatter)
+
+	assert.NoError(t, SetFormat(""))
+	assert.IsType(t, &logrus.TextFormatter{}, logrus.StandardLogger().Formatter)
+
+	assert.Error(t, SetFormat("xml"))
+	assert.IsType(t, &logrus.TextFormatter{}, logrus.StandardLogger().Formatter)
+}
+
+func TestFromContext(t *testing.T) {
+	t.Run("Default logger", func(t *testing.T) {
+		entry := FromContext(context.Background())
+		assert.NotNil(t, entry)
+		assert.Empty(t, entry.Data)
+	})
+
+	t.Run("Fields accumulate", func(t *testing.T) {
+		var buf bytes.Buffer
+		base := logrus.New()
+		base.SetOutput(&buf)
+		base.SetFormatter(&logrus.JSONFormatter{})
+
+		ctx := WithContext(context.Background(), logrus.NewEntry(base).WithField("request_id", "abc"))
+		ctx = WithFields(ctx, logrus.Fields{"user_id": "u1"})
+		FromContext(ctx).Info("test")
+
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		assert.Equal(t, "abc", line["request_id"])
+		assert.Equal(t, "u1", line["user_id"])
+		assert.Equal(t, "test", line["msg"])
+	})
+}
+
+func TestValidRequestID(t *testing.T) {
+	for requestID, want := range map[string]bool{
+		"req-1":                                true,
+		"3f2b1c9e-8a7d-4e6f-9b0a-1c2d3e4f5a6b": true,
+		"svc.gateway:42_a":                     true,
+		"":                                     false,
+		"bad id":                               false,
+		"id\nInjected: header":                 false,
+		strings.Repeat("a", 129):               false,
+	} {
+		assert.Equal(t, want, ValidRequestID(requestID), "request id %q", requestID)
+	}
+}
@@ -23,33 +23,123 @@ var (
 		},
 		[]string{"handler", "method", "code"},
 	)
+	HTTPRequestSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "Размер тела HTTP-запросов в байтах.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		[]string{"handler", "method", "code"},
+	)
+	HTTPResponseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "Размер тела HTTP-ответов в байтах.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		[]string{"handler", "method", "code"},
+	)
+	HTTPRequestsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Количество HTTP-запросов, обрабатываемых в данный момент.",
+		},
+	)
+
+	RateLimitDecisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limit_decisions_total",
+			Help: "Количество решений ограничителя частоты запросов.",
+		},
+		[]string{"route", "decision"},
+	)
+
+	DBQueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Время выполнения запросов к БД в секундах по методам PGXDatabase.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+	PVZCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pvz_cache_requests_total",
+			Help: "Количество обращений к кешу ПВЗ по методам хранилища: hit или miss.",
+		},
+		[]string{"method", "result"},
+	)
 
-	CreatedPVZTotal = prometheus.NewCounter(
+	CreatedPVZTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "business_created_pvz_total",
 			Help: "Количество успешно созданных ПВЗ.",
 		},
+		[]string{"city"},
 	)
-	CreatedReceptionTotal = prometheus.NewCounter(
+	CreatedReceptionTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "business_created_reception_total",
 			Help: "Количество успешно созданных приемок заказов.",
 		},
+		[]string{"city"},
 	)
-	AddedProductsTotal = prometheus.NewCounter(
+	AddedProductsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "business_added_products_total",
 			Help: "Количество успешно добавленных товаров.",
 		},
+		[]string{"city", "product_type"},
+	)
+	DeletedProductsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "business_deleted_products_total",
+			Help: "Количество удаленных товаров.",
+		},
+		[]string{"city"},
+	)
+	OpenReceptions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "business_open_receptions",
+			Help: "Количество открытых приемок.",
+		},
+		[]string{"city"},
+	)
+	AutoClosedReceptionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "business_auto_closed_receptions_total",
+			Help: "Количество приемок, закрытых автоматически по истечении лимита.",
+		},
+		[]string{"city"},
+	)
+	ReceptionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "business_reception_duration_seconds",
+			Help:    "Длительность приемки от открытия до закрытия в секундах.",
+			Buckets: prometheus.ExponentialBuckets(60, 2, 12),
+		},
+		[]string{"city"},
+	)
+	ProductsPerReception = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "business_products_per_reception",
+			Help:    "Количество товаров в закрытой приемке.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"city"},
 	)
 )
 
-func NewRegistry() *prometheus.Registry {
+func NewRegistry(collectors ...prometheus.Collector) *prometheus.Registry {
 	reg := prometheus.NewRegistry()
-	reg.MustRegister(HTTPRequestTotal, HTTPResponseDuration, CreatedPVZTotal, CreatedReceptionTotal, AddedProductsTotal)
+	reg.MustRegister(HTTPRequestTotal, HTTPResponseDuration, HTTPRequestSize, HTTPResponseSize, HTTPRequestsInFlight,
+		RateLimitDecisions, DBQueryDuration, PVZCacheRequests,
+		CreatedPVZTotal, CreatedReceptionTotal, AddedProductsTotal, DeletedProductsTotal,
+		OpenReceptions, AutoClosedReceptionsTotal, ReceptionDuration, ProductsPerReception)
+	reg.MustRegister(collectors...)
 	return reg
 }
 
-func Handler() http.Handler {
-	return promhttp.HandlerFor(NewRegistry(), promhttp.HandlerOpts{})
+func Handler(collectors ...prometheus.Collector) http.Handler {
+	return promhttp.HandlerFor(NewRegistry(collectors...), promhttp.HandlerOpts{})
 }
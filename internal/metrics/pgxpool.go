@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type PoolStatter interface {
+	Stat() *pgxpool.Stat
+}
+
+type PoolCollector struct {
+	pool PoolStatter
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	emptyAcquireWaitTime *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+}
+
+func NewPoolCollector(pool PoolStatter) *PoolCollector {
+	return &PoolCollector{
+		pool:                 pool,
+		acquiredConns:        prometheus.NewDesc("pgxpool_acquired_conns", "Количество соединений, занятых в данный момент.", nil, nil),
+		idleConns:            prometheus.NewDesc("pgxpool_idle_conns", "Количество простаивающих соединений.", nil, nil),
+		constructingConns:    prometheus.NewDesc("pgxpool_constructing_conns", "Количество устанавливаемых соединений.", nil, nil),
+		totalConns:           prometheus.NewDesc("pgxpool_total_conns", "Общее количество соединений в пуле.", nil, nil),
+		maxConns:             prometheus.NewDesc("pgxpool_max_conns", "Максимальный размер пула.", nil, nil),
+		acquireCount:         prometheus.NewDesc("pgxpool_acquire_count_total", "Количество успешных получений соединения.", nil, nil),
+		acquireDuration:      prometheus.NewDesc("pgxpool_acquire_duration_seconds_total", "Суммарное время получения соединений в секундах.", nil, nil),
+		emptyAcquireCount:    prometheus.NewDesc("pgxpool_empty_acquire_count_total", "Количество получений соединения, которым пришлось ждать свободного соединения.", nil, nil),
+		emptyAcquireWaitTime: prometheus.NewDesc("pgxpool_empty_acquire_wait_seconds_total", "Суммарное время ожидания свободного соединения в секундах.", nil, nil),
+		canceledAcquireCount: prometheus.NewDesc("pgxpool_canceled_acquire_count_total", "Количество получений соединения, отмененных контекстом.", nil, nil),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.constructingConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireCount
+	ch <- c.emptyAcquireWaitTime
+	ch <- c.canceledAcquireCount
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireWaitTime, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
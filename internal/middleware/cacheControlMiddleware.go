@@ -0,0 +1,14 @@
+package middleware
+
+import "net/http"
+
+// CacheControlMiddleware выставляет политику кеширования ответа. Middleware вложенного
+// маршрута выполняется позже и переопределяет политику по умолчанию, как и сам обработчик.
+func (m *Middleware) CacheControlMiddleware(policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", policy)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheControlMiddleware(t *testing.T) {
+	mw := NewMiddleware(nil)
+	handler := mw.CacheControlMiddleware("no-store")(
+		mw.CacheControlMiddleware("private, no-cache")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+	)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/pvz", nil))
+	assert.Equal(t, "private, no-cache", rr.Header().Get("Cache-Control"), "политика маршрута важнее политики по умолчанию")
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	mw := NewMiddleware(nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/pvz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }).Methods("GET")
+	opts := CORSOptions{
+		AllowedOrigins: []string{"https://backoffice.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	}
+	handler := mw.CORSMiddleware(opts)(router)
+
+	send := func(method, origin, requestMethod string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/pvz", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Preflight", func(t *testing.T) {
+		rr := send(http.MethodOptions, "https://backoffice.example.com", "POST")
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "https://backoffice.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", rr.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Authorization, Content-Type", rr.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("Preflight with disallowed method", func(t *testing.T) {
+		rr := send(http.MethodOptions, "https://backoffice.example.com", "DELETE")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Preflight from unknown origin", func(t *testing.T) {
+		rr := send(http.MethodOptions, "https://evil.example.com", "GET")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Simple request", func(t *testing.T) {
+		rr := send(http.MethodGet, "https://backoffice.example.com", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "https://backoffice.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "X-Request-ID", rr.Header().Get("Access-Control-Expose-Headers"))
+		assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("Request from unknown origin", func(t *testing.T) {
+		rr := send(http.MethodGet, "https://evil.example.com", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Wildcard origin", func(t *testing.T) {
+		wildcard := mw.CORSMiddleware(CORSOptions{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})(router)
+		req := httptest.NewRequest(http.MethodGet, "/pvz", nil)
+		req.Header.Set("Origin", "https://any.example.com")
+		rr := httptest.NewRecorder()
+		wildcard.ServeHTTP(rr, req)
+		assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Disabled without origins", func(t *testing.T) {
+		disabled := mw.CORSMiddleware(CORSOptions{})(router)
+		req := httptest.NewRequest(http.MethodGet, "/pvz", nil)
+		req.Header.Set("Origin", "https://backoffice.example.com")
+		rr := httptest.NewRecorder()
+		disabled.ServeHTTP(rr, req)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/idempotency"
+	"pvz/internal/logger"
+	"pvz/internal/problem"
+)
+
+type recordingWriter struct {
+	*responseWriter
+	body bytes.Buffer
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.responseWriter.Write(b)
+}
+
+func (m *Middleware) IdempotencyMiddleware(store idempotency.Store, ttl time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotency.HeaderName)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !idempotency.ValidKey(key) {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeIdempotencyKeyInvalid, "Неверный ключ идемпотентности")
+				return
+			}
+			userID, _ := r.Context().Value(contextkeys.ContextKeyUserID).(string)
+			log := logger.FromContext(r.Context()).WithField("idempotency_key", key)
+
+			body, err := io.ReadAll(r.Body)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "Слишком большое тело запроса")
+				return
+			}
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Неверный запрос")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			requestHash := idempotency.Hash([]byte(r.Method), []byte(r.URL.Path), body)
+
+			existing, err := store.ReserveIdempotencyKey(r.Context(), userID, key, requestHash, ttl)
+			if err != nil {
+				log.WithError(err).Error("Ошибка резервирования ключа идемпотентности")
+				problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "Внутренняя ошибка сервера")
+				return
+			}
+			if existing != nil {
+				switch {
+				case existing.RequestHash != requestHash:
+					log.Warn("Ключ идемпотентности использован с другим запросом")
+					problem.Write(w, r, http.StatusConflict, problem.CodeIdempotencyKeyReused, "Ключ идемпотентности уже использован с другим запросом")
+				case !existing.Completed:
+					problem.Write(w, r, http.StatusConflict, problem.CodeIdempotencyInProgress, "Запрос с этим ключом идемпотентности ещё выполняется")
+				default:
+					log.Info("Повтор сохранённого ответа по ключу идемпотентности")
+					if existing.ContentType != "" {
+						w.Header().Set("Content-Type", existing.ContentType)
+					}
+					w.Header().Set(idempotency.ReplayHeader, "true")
+					w.WriteHeader(existing.StatusCode)
+					w.Write(existing.Body)
+				}
+				return
+			}
+
+			rw := &recordingWriter{responseWriter: newResponseWriter(w)}
+			defer func() {
+				if rec := recover(); rec != nil {
+					store.ReleaseIdempotencyKey(r.Context(), userID, key)
+					panic(rec)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+
+			if rw.statusCode >= http.StatusInternalServerError {
+				if err := store.ReleaseIdempotencyKey(r.Context(), userID, key); err != nil {
+					log.WithError(err).Error("Ошибка освобождения ключа идемпотентности")
+				}
+				return
+			}
+			err = store.CompleteIdempotencyKey(r.Context(), userID, key, rw.statusCode, rw.Header().Get("Content-Type"), rw.body.Bytes())
+			if err != nil {
+				log.WithError(err).Error("Ошибка сохранения ответа по ключу идемпотентности")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/idempotency"
+	"pvz/internal/models"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	mw := NewMiddleware(nil)
+	calls := 0
+	status := http.StatusCreated
+	handler := mw.IdempotencyMiddleware(idempotency.NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d}`, calls)
+	}))
+
+	send := func(userID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotency.HeaderName, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.ContextKeyUserID, userID))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Replays stored response", func(t *testing.T) {
+		first := send("u1", "k1", `{"type":"обувь"}`)
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		second := send("u1", "k1", `{"type":"обувь"}`)
+		assert.Equal(t, http.StatusCreated, second.Code)
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+		assert.Equal(t, "true", second.Header().Get(idempotency.ReplayHeader))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Rejects different body", func(t *testing.T) {
+		rr := send("u1", "k1", `{"type":"электроника"}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		var errResp models.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, "Ключ идемпотентности уже использован с другим запросом", errResp.Message)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Keys are scoped per user", func(t *testing.T) {
+		rr := send("u2", "k1", `{"type":"электроника"}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Without key", func(t *testing.T) {
+		send("u1", "", `{}`)
+		send("u1", "", `{}`)
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("Server errors are not stored", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		assert.Equal(t, http.StatusInternalServerError, send("u1", "k2", `{}`).Code)
+		status = http.StatusCreated
+		assert.Equal(t, http.StatusCreated, send("u1", "k2", `{}`).Code)
+		assert.Equal(t, 6, calls)
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		rr := send("u1", strings.Repeat("a", idempotency.MaxKeyLength+1), `{}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestIdempotencyMiddlewareInProgress(t *testing.T) {
+	mw := NewMiddleware(nil)
+	store := idempotency.NewMemoryStore()
+	_, err := store.ReserveIdempotencyKey(context.Background(), "u1", "k1", idempotency.Hash([]byte(http.MethodPost), []byte("/pvz"), []byte(`{}`)), time.Hour)
+	assert.NoError(t, err)
+
+	handler := mw.IdempotencyMiddleware(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("обработчик не должен вызываться")
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/pvz", strings.NewReader(`{}`))
+	req.Header.Set(idempotency.HeaderName, "k1")
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.ContextKeyUserID, "u1"))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
@@ -7,8 +7,11 @@ import (
 	"strings"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
 
 	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+	"pvz/internal/problem"
 )
 
 type Middleware struct {
@@ -23,44 +26,72 @@ func (m *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Отсутствует заголовок Authorization", http.StatusUnauthorized)
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Отсутствует заголовок Authorization")
 			return
 		}
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Неверный формат заголовка", http.StatusUnauthorized)
+		ctx, msg := m.authenticate(r.Context(), authHeader)
+		if msg != "" {
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, msg)
 			return
 		}
-		tokenString := parts[1]
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
-			}
-			return m.jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Неверный токен", http.StatusUnauthorized)
-			return
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Неверные claims", http.StatusUnauthorized)
+// OptionalAuthMiddleware пропускает запросы без заголовка Authorization без роли в контексте,
+// а переданный токен проверяет так же строго, как AuthMiddleware. Роль проверяет обработчик.
+func (m *Middleware) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
-		idStr, ok := claims["id"].(string)
-		if !ok {
-			http.Error(w, "Неверный id", http.StatusUnauthorized)
+		ctx, msg := m.authenticate(r.Context(), authHeader)
+		if msg != "" {
+			problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, msg)
 			return
 		}
-		role, ok := claims["role"].(string)
-		if !ok {
-			http.Error(w, "Неверная роль", http.StatusUnauthorized)
-			return
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate проверяет Bearer-токен и кладет id пользователя и роль в контекст.
+// При ошибке возвращает сообщение для ответа 401.
+func (m *Middleware) authenticate(ctx context.Context, authHeader string) (context.Context, string) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ctx, "Неверный формат заголовка"
+	}
+	tokenString := parts[1]
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
 		}
+		return m.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return ctx, "Неверный токен"
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ctx, "Неверные claims"
+	}
+	idStr, ok := claims["id"].(string)
+	if !ok {
+		return ctx, "Неверный id"
+	}
+	role, ok := claims["role"].(string)
+	if !ok {
+		return ctx, "Неверная роль"
+	}
 
-		ctx := context.WithValue(r.Context(), contextkeys.ContextKeyUserID, idStr)
-		ctx = context.WithValue(ctx, contextkeys.ContextKeyRole, role)
-		next.ServeHTTP(w, r.WithContext(ctx))
+	ctx = context.WithValue(ctx, contextkeys.ContextKeyUserID, idStr)
+	ctx = context.WithValue(ctx, contextkeys.ContextKeyRole, role)
+	ctx = logger.WithFields(ctx, logrus.Fields{
+		"user_id": idStr,
+		"role":    role,
 	})
+	return ctx, ""
 }
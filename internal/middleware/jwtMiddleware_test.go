@@ -124,3 +124,44 @@ func TestAuthMiddleware(t *testing.T) {
 		assert.True(t, strings.Contains(response, "role:employee"))
 	})
 }
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	secret := []byte("testsecret")
+	handlerToTest := NewMiddleware(secret).OptionalAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(contextkeys.ContextKeyRole).(string)
+		w.Write([]byte("role:" + role))
+	}))
+
+	t.Run("Without Authorization Header", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handlerToTest.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "role:", rr.Body.String())
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "Bearer invalid.token.value")
+		rr := httptest.NewRecorder()
+		handlerToTest.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Неверный токен")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"id":   "user123",
+			"role": "moderator",
+			"exp":  time.Now().Add(time.Hour).Unix(),
+		})
+		tokenString, err := token.SignedString(secret)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+		rr := httptest.NewRecorder()
+		handlerToTest.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "role:moderator", rr.Body.String())
+	})
+}
@@ -1,36 +1,110 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
+
 	"pvz/internal/metrics"
 )
 
+const unmatchedRoute = "unmatched"
+
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	wroteHeader bool
+	bytes       int
 }
 
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{w, http.StatusOK}
+	return &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if !rw.wroteHeader {
+			rw.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+type countingBody struct {
+	io.ReadCloser
+	bytes int
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += n
+	return n, err
+}
+
+func routeName(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedRoute
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return tpl
+}
+
 func (m *Middleware) MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
 		start := time.Now()
 		rw := newResponseWriter(w)
+		var body *countingBody
+		if r.Body != nil {
+			body = &countingBody{ReadCloser: r.Body}
+			r.Body = body
+		}
 		next.ServeHTTP(rw, r)
 		duration := time.Since(start).Seconds()
+
+		requestSize := 0
+		if body != nil {
+			requestSize = body.bytes
+		}
+		if r.ContentLength > int64(requestSize) {
+			requestSize = int(r.ContentLength)
+		}
+
 		codeStr := strconv.Itoa(rw.statusCode)
-		handlerName := r.URL.Path
+		handlerName := routeName(r)
 		metrics.HTTPRequestTotal.WithLabelValues(handlerName, r.Method, codeStr).Inc()
 		metrics.HTTPResponseDuration.WithLabelValues(handlerName, r.Method, codeStr).Observe(duration)
+		metrics.HTTPRequestSize.WithLabelValues(handlerName, r.Method, codeStr).Observe(float64(requestSize))
+		metrics.HTTPResponseSize.WithLabelValues(handlerName, r.Method, codeStr).Observe(float64(rw.bytes))
 	})
 }
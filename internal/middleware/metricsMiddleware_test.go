@@ -4,9 +4,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
@@ -15,6 +17,15 @@ import (
 	"pvz/internal/metrics"
 )
 
+func histogramOf(t *testing.T, o prometheus.Observer) *dto.Histogram {
+	m, ok := o.(prometheus.Metric)
+	assert.True(t, ok, "Невозможно привести histogram к prometheus.Metric")
+
+	var histMetric dto.Metric
+	assert.NoError(t, m.Write(&histMetric))
+	return histMetric.GetHistogram()
+}
+
 func TestMetricsMiddleware(t *testing.T) {
 	dummyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(10 * time.Millisecond)
@@ -36,16 +47,67 @@ func TestMetricsMiddleware(t *testing.T) {
 
 	codeStr := strconv.Itoa(rr.Code)
 
-	count := testutil.ToFloat64(metrics.HTTPRequestTotal.WithLabelValues("/dummyTest", "GET", codeStr))
-	assert.Equal(t, 1.0, count, "HTTPRequestTotal для /dummyTest должен быть равен 1")
+	count := testutil.ToFloat64(metrics.HTTPRequestTotal.WithLabelValues(unmatchedRoute, "GET", codeStr))
+	assert.Equal(t, 1.0, count, "HTTPRequestTotal без маршрута должен учитываться как unmatched")
 
-	m, ok := metrics.HTTPResponseDuration.WithLabelValues("/dummyTest", "GET", codeStr).(prometheus.Metric)
-	assert.True(t, ok, "Невозможно привести histogram к prometheus.Metric")
+	hist := histogramOf(t, metrics.HTTPResponseDuration.WithLabelValues(unmatchedRoute, "GET", codeStr))
+	assert.Equal(t, uint64(1), hist.GetSampleCount(), "Количество наблюдений в гистограмме должно быть 1")
+}
 
-	var histMetric dto.Metric
-	err := m.Write(&histMetric)
-	assert.NoError(t, err)
+func TestMetricsMiddlewareRouteTemplate(t *testing.T) {
+	mw := &Middleware{}
 
-	hist := histMetric.GetHistogram()
-	assert.Equal(t, uint64(1), hist.GetSampleCount(), "Количество наблюдений в гистограмме должно быть 1")
+	var inFlight float64
+	router := mux.NewRouter()
+	router.Use(mw.MetricsMiddleware)
+	router.HandleFunc("/metrics-test/{pvzId}/close", func(w http.ResponseWriter, r *http.Request) {
+		inFlight = testutil.ToFloat64(metrics.HTTPRequestsInFlight)
+		buf := make([]byte, 64)
+		for {
+			if _, err := r.Body.Read(buf); err != nil {
+				break
+			}
+		}
+		w.Write([]byte("hello"))
+	}).Methods("POST")
+
+	for _, id := range []string{"a", "b", "c"} {
+		req := httptest.NewRequest(http.MethodPost, "/metrics-test/"+id+"/close", strings.NewReader("payload"))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	assert.Equal(t, 1.0, inFlight, "Во время обработки должен учитываться один запрос")
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.HTTPRequestsInFlight))
+
+	template := "/metrics-test/{pvzId}/close"
+	count := testutil.ToFloat64(metrics.HTTPRequestTotal.WithLabelValues(template, "POST", "200"))
+	assert.Equal(t, 3.0, count, "Все запросы должны попасть в один ряд по шаблону маршрута")
+
+	reqSize := histogramOf(t, metrics.HTTPRequestSize.WithLabelValues(template, "POST", "200"))
+	assert.Equal(t, uint64(3), reqSize.GetSampleCount())
+	assert.Equal(t, float64(3*len("payload")), reqSize.GetSampleSum())
+
+	respSize := histogramOf(t, metrics.HTTPResponseSize.WithLabelValues(template, "POST", "200"))
+	assert.Equal(t, uint64(3), respSize.GetSampleCount())
+	assert.Equal(t, float64(3*len("hello")), respSize.GetSampleSum())
+}
+
+func TestResponseWriterStatus(t *testing.T) {
+	t.Run("Implicit status", func(t *testing.T) {
+		rw := newResponseWriter(httptest.NewRecorder())
+		rw.Write([]byte("x"))
+		rw.WriteHeader(http.StatusTeapot)
+		assert.Equal(t, http.StatusOK, rw.statusCode)
+		assert.Equal(t, 1, rw.bytes)
+	})
+
+	t.Run("Explicit status", func(t *testing.T) {
+		rw := newResponseWriter(httptest.NewRecorder())
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte("missing"))
+		assert.Equal(t, http.StatusNotFound, rw.statusCode)
+		assert.Equal(t, len("missing"), rw.bytes)
+	})
 }
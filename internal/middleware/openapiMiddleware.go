@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"pvz/internal/logger"
+	"pvz/internal/problem"
+)
+
+// Форматы uuid и email kin-openapi по умолчанию не проверяет.
+func init() {
+	openapi3.DefineStringFormatCallback("uuid", func(value string) error {
+		_, err := uuid.Parse(value)
+		return err
+	})
+	openapi3.DefineStringFormatValidator("email", openapi3.NewRegexpFormatValidator(openapi3.FormatOfStringForEmail))
+}
+
+type OpenAPIOptions struct {
+	// ValidateResponses включает проверку ответов: расхождение со спецификацией
+	// заменяет ответ на 500, чтобы дрейф спецификации ронял тесты.
+	ValidateResponses bool
+}
+
+type bufferingWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (bw *bufferingWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferingWriter) WriteHeader(code int) {
+	if bw.statusCode == 0 {
+		bw.statusCode = code
+	}
+}
+
+func (bw *bufferingWriter) Write(b []byte) (int, error) {
+	if bw.statusCode == 0 {
+		bw.statusCode = http.StatusOK
+	}
+	return bw.body.Write(b)
+}
+
+// jsonResponses сообщает, что все ответы операции — JSON. Потоковые ответы
+// (SSE, выгрузки) не буферизуются и не проверяются.
+func jsonResponses(operation *openapi3.Operation) bool {
+	for _, response := range operation.Responses.Map() {
+		for contentType := range response.Value.Content {
+			if contentType != "application/json" && contentType != problem.ContentType {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func joinViolation(prefix, reason string) string {
+	if prefix == "" {
+		return reason
+	}
+	return prefix + ": " + reason
+}
+
+func openAPIViolations(prefix string, err error) []string {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var violations []string
+		for _, inner := range e {
+			violations = append(violations, openAPIViolations(prefix, inner)...)
+		}
+		return violations
+	case *openapi3filter.RequestError:
+		switch {
+		case e.Parameter != nil:
+			prefix = "параметр " + e.Parameter.Name
+		case e.RequestBody != nil:
+			prefix = "тело запроса"
+		}
+		if e.Err == nil {
+			return []string{joinViolation(prefix, e.Reason)}
+		}
+		return openAPIViolations(prefix, e.Err)
+	case *openapi3filter.ResponseError:
+		if e.Err == nil {
+			return []string{joinViolation(prefix, e.Reason)}
+		}
+		return openAPIViolations(prefix, e.Err)
+	case *openapi3.SchemaError:
+		if path := e.JSONPointer(); len(path) > 0 {
+			prefix = joinViolation(prefix, "поле "+strings.Join(path, "."))
+		}
+		return []string{joinViolation(prefix, e.Reason)}
+	}
+	return []string{joinViolation(prefix, err.Error())}
+}
+
+// OpenAPIMiddleware проверяет запросы по спецификации API и отвечает 400 со списком
+// нарушений. Авторизацию проверяет AuthMiddleware, а маршруты вне спецификации
+// пропускаются без проверки.
+func (m *Middleware) OpenAPIMiddleware(spec []byte, opts OpenAPIOptions) (mux.MiddlewareFunc, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора спецификации OpenAPI: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("спецификация OpenAPI некорректна: %w", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка построения маршрутов OpenAPI: %w", err)
+	}
+	filterOptions := &openapi3filter.Options{
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		MultiError:          true,
+		SkipSettingDefaults: true,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			log := logger.FromContext(r.Context())
+
+			// Клиенты исторически шлют JSON без Content-Type, поэтому его отсутствие не считается ошибкой.
+			if route.Operation.RequestBody != nil && r.Header.Get("Content-Type") == "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    filterOptions,
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					problem.Write(w, r, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "Слишком большое тело запроса")
+					return
+				}
+				violations := openAPIViolations("", err)
+				log.WithField("violations", violations).Warn("Запрос не соответствует спецификации API")
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Запрос не соответствует спецификации API", violations...)
+				return
+			}
+
+			if !opts.ValidateResponses || !jsonResponses(route.Operation) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			bw := &bufferingWriter{header: w.Header().Clone()}
+			next.ServeHTTP(bw, r)
+			if bw.statusCode == 0 {
+				bw.statusCode = http.StatusOK
+			}
+			if bw.header.Get("Content-Type") == "" && bw.body.Len() > 0 {
+				bw.header.Set("Content-Type", http.DetectContentType(bw.body.Bytes()))
+			}
+
+			var violations []string
+			if bw.statusCode < http.StatusMultipleChoices && route.Operation.Responses.Status(bw.statusCode) == nil {
+				violations = []string{fmt.Sprintf("ответ: статус %d не описан в спецификации", bw.statusCode)}
+			} else if err := openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 bw.statusCode,
+				Header:                 bw.header,
+				Body:                   io.NopCloser(bytes.NewReader(bw.body.Bytes())),
+				Options:                filterOptions,
+			}); err != nil {
+				violations = openAPIViolations("ответ", err)
+			}
+			if len(violations) > 0 {
+				log.WithField("violations", violations).Error("Ответ не соответствует спецификации API")
+				problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "Ответ не соответствует спецификации API", violations...)
+				return
+			}
+
+			for key, values := range bw.header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(bw.statusCode)
+			w.Write(bw.body.Bytes())
+		})
+	}, nil
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/models"
+)
+
+const testSpec = `
+openapi: 3.0.0
+info:
+  title: test
+  version: 1.0.0
+paths:
+  /items:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+              required: [name]
+              additionalProperties: false
+      responses:
+        '201':
+          description: Создано
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id:
+                    type: string
+                    format: uuid
+                required: [id]
+`
+
+func TestOpenAPIMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		strict         bool
+		handlerStatus  int
+		handlerBody    string
+		expectedStatus int
+		expectedDetail string
+	}{
+		{
+			name:           "valid",
+			path:           "/items",
+			body:           `{"name":"a"}`,
+			strict:         true,
+			handlerStatus:  http.StatusCreated,
+			handlerBody:    `{"id":"7a0f8c4e-8d4c-4a51-9d65-0e3f6a1b2c3d"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing field",
+			path:           "/items",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "тело запроса: поле name",
+		},
+		{
+			name:           "unknown field",
+			path:           "/items",
+			body:           `{"name":"a","id":"x"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetail: "тело запроса",
+		},
+		{
+			name:           "response drift is ignored by default",
+			path:           "/items",
+			body:           `{"name":"a"}`,
+			handlerStatus:  http.StatusCreated,
+			handlerBody:    `{"id":"not-a-uuid"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "response drift in strict mode",
+			path:           "/items",
+			body:           `{"name":"a"}`,
+			strict:         true,
+			handlerStatus:  http.StatusCreated,
+			handlerBody:    `{"id":"not-a-uuid"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedDetail: "ответ: поле id",
+		},
+		{
+			name:           "undocumented success status in strict mode",
+			path:           "/items",
+			body:           `{"name":"a"}`,
+			strict:         true,
+			handlerStatus:  http.StatusOK,
+			handlerBody:    `{"id":"7a0f8c4e-8d4c-4a51-9d65-0e3f6a1b2c3d"}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedDetail: "статус 200 не описан",
+		},
+		{
+			name:           "route outside spec",
+			path:           "/graphql",
+			body:           `anything`,
+			strict:         true,
+			handlerStatus:  http.StatusOK,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := NewMiddleware(nil).OpenAPIMiddleware([]byte(testSpec), OpenAPIOptions{ValidateResponses: tt.strict})
+			require.NoError(t, err)
+			handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.handlerStatus)
+				w.Write([]byte(tt.handlerBody))
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.expectedDetail != "" {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+				require.NotEmpty(t, errResp.Details)
+				assert.Contains(t, errResp.Details[0], tt.expectedDetail)
+			} else {
+				assert.Equal(t, tt.handlerBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPIMiddlewareInvalidSpec(t *testing.T) {
+	_, err := NewMiddleware(nil).OpenAPIMiddleware([]byte("openapi: 3.0.0\npaths: 1"), OpenAPIOptions{})
+	assert.Error(t, err)
+}
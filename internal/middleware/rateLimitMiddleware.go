@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
+	"pvz/internal/problem"
+	"pvz/internal/ratelimit"
+)
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (m *Middleware) RateLimitMiddleware(limiter *ratelimit.Limiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := "ip:" + clientIP(r)
+			if userID, ok := r.Context().Value(contextkeys.ContextKeyUserID).(string); ok && userID != "" {
+				key = "user:" + userID
+			}
+			route := routeName(r)
+			allowed, retryAfter, rule := limiter.Allow(r.Method, route, key)
+			if rule == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if allowed {
+				metrics.RateLimitDecisions.WithLabelValues(route, ratelimit.DecisionAllowed).Inc()
+				next.ServeHTTP(w, r)
+				return
+			}
+			metrics.RateLimitDecisions.WithLabelValues(route, ratelimit.DecisionLimited).Inc()
+			logger.FromContext(r.Context()).WithField("rate_limit_rule", rule).Warn("Превышен лимит запросов")
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			problem.Write(w, r, http.StatusTooManyRequests, problem.CodeRateLimited, "Слишком много запросов")
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/metrics"
+	"pvz/internal/models"
+	"pvz/internal/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	mw := NewMiddleware(nil)
+	limiter := ratelimit.NewLimiter(map[string]ratelimit.Rule{
+		"POST /products": {Rate: 0.1, Burst: 1},
+		"POST /login":    {Rate: 0.1, Burst: 1},
+	})
+
+	router := mux.NewRouter()
+	router.Use(mw.RateLimitMiddleware(limiter))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/products", ok).Methods("POST")
+	router.HandleFunc("/login", ok).Methods("POST")
+	router.HandleFunc("/pvz", ok).Methods("GET")
+
+	asUser := func(req *http.Request, userID string) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), contextkeys.ContextKeyUserID, userID))
+	}
+
+	t.Run("Limits per user", func(t *testing.T) {
+		limitedBefore := testutil.ToFloat64(metrics.RateLimitDecisions.WithLabelValues("/products", ratelimit.DecisionLimited))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, asUser(httptest.NewRequest(http.MethodPost, "/products", nil), "u1"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, asUser(httptest.NewRequest(http.MethodPost, "/products", nil), "u1"))
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "10", rr.Header().Get("Retry-After"))
+		var errResp models.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+		assert.Equal(t, "Слишком много запросов", errResp.Message)
+
+		assert.Equal(t, limitedBefore+1, testutil.ToFloat64(metrics.RateLimitDecisions.WithLabelValues("/products", ratelimit.DecisionLimited)))
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, asUser(httptest.NewRequest(http.MethodPost, "/products", nil), "u2"))
+		assert.Equal(t, http.StatusOK, rr.Code, "Лимит другого пользователя не должен быть исчерпан")
+	})
+
+	t.Run("Limits anonymous requests per IP", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "10.1.1.1:5555"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "10.1.1.1:6666"
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "10.1.1.2:5555"
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Routes without rule are not limited", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, asUser(httptest.NewRequest(http.MethodGet, "/pvz", nil), "u1"))
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+	})
+}
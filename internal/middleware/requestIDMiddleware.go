@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+func (m *Middleware) RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !logger.ValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		fields := logrus.Fields{
+			"request_id": requestID,
+			"method":     r.Method,
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				fields["route"] = tpl
+			}
+		}
+		if pvzId, ok := mux.Vars(r)["pvzId"]; ok {
+			fields["pvz_id"] = pvzId
+		}
+
+		ctx := context.WithValue(r.Context(), contextkeys.ContextKeyRequestID, requestID)
+		ctx = logger.WithFields(ctx, fields)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	mw := NewMiddleware(nil)
+
+	var gotID string
+	var gotFields logrus.Fields
+	router := mux.NewRouter()
+	router.Use(mw.RequestIDMiddleware)
+	router.HandleFunc("/pvz/{pvzId}/close_last_reception", func(w http.ResponseWriter, r *http.Request) {
+		gotID, _ = r.Context().Value(contextkeys.ContextKeyRequestID).(string)
+		gotFields = logger.FromContext(r.Context()).Data
+		w.WriteHeader(http.StatusOK)
+	}).Methods("POST")
+
+	t.Run("Generates request id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pvz/123/close_last_reception", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEmpty(t, gotID)
+		assert.Equal(t, gotID, rr.Header().Get(RequestIDHeader))
+		assert.Equal(t, gotID, gotFields["request_id"])
+		assert.Equal(t, "/pvz/{pvzId}/close_last_reception", gotFields["route"])
+		assert.Equal(t, "123", gotFields["pvz_id"])
+		assert.Equal(t, http.MethodPost, gotFields["method"])
+	})
+
+	t.Run("Accepts client request id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pvz/123/close_last_reception", nil)
+		req.Header.Set(RequestIDHeader, "client-id-42")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, "client-id-42", gotID)
+		assert.Equal(t, "client-id-42", rr.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("Replaces invalid request id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pvz/123/close_last_reception", nil)
+		req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.NotEqual(t, "bad id\nwith newline", gotID)
+		assert.Equal(t, gotID, rr.Header().Get(RequestIDHeader))
+	})
+}
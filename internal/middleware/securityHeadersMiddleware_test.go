@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	mw := NewMiddleware(nil)
+	handler := mw.SecurityHeadersMiddleware(24 * time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/pvz", nil))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", rr.Header().Get("Referrer-Policy"))
+	assert.NotEmpty(t, rr.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, rr.Header().Get("Strict-Transport-Security"), "HSTS не отправляется по HTTP")
+
+	req := httptest.NewRequest(http.MethodGet, "/pvz", nil)
+	req.TLS = &tls.ConnectionState{}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "max-age=86400; includeSubDomains", rr.Header().Get("Strict-Transport-Security"))
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,6 +20,12 @@ type PVZ struct {
 	City             string    `json:"city" db:"city"`
 }
 
+// PVZVersion — версия данных ПВЗ: растет при каждом изменении его приёмок и товаров.
+type PVZVersion struct {
+	ID      uuid.UUID `db:"id"`
+	Version int64     `db:"version"`
+}
+
 type Reception struct {
 	ID       uuid.UUID `json:"id" db:"id"`
 	DateTime time.Time `json:"dateTime" db:"date_time"`
@@ -33,8 +40,17 @@ type Product struct {
 	ReceptionId uuid.UUID `json:"receptionId" db:"reception_id"`
 }
 
+// ErrorResponse — ответ об ошибке в формате RFC 7807 (application/problem+json).
+// Message повторяет Detail для клиентов, которые читают прежний формат.
 type ErrorResponse struct {
-	Message string `json:"message"`
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail"`
+	Instance string   `json:"instance,omitempty"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Details  []string `json:"details,omitempty"`
 }
 
 type PVZResponse struct {
@@ -46,3 +62,105 @@ type ReceptionInfo struct {
 	Reception *Reception `json:"reception"`
 	Products  []*Product `json:"products"`
 }
+
+// PVZFilter ограничивает потоковую выдачу ПВЗ; границы дат включительно.
+type PVZFilter struct {
+	City string
+	From *time.Time
+	To   *time.Time
+}
+
+type ExportFilter struct {
+	From  *time.Time
+	To    *time.Time
+	City  string
+	PVZId *uuid.UUID
+}
+
+// ExportRow — строка выгрузки: товар вместе с приёмкой и ПВЗ.
+type ExportRow struct {
+	PVZ       PVZ
+	Reception Reception
+	Product   Product
+}
+
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+	BucketWeek = "week"
+
+	GroupByCity        = "city"
+	GroupByPVZ         = "pvz"
+	GroupByProductType = "product_type"
+)
+
+type IntakeQuery struct {
+	From    time.Time
+	To      time.Time
+	Bucket  string
+	GroupBy []string
+	City    string
+	PVZId   *uuid.UUID
+}
+
+// IntakeRow — объем приёмки за интервал; поля группировки, не выбранные в запросе, пустые.
+type IntakeRow struct {
+	Bucket                      time.Time  `json:"bucket"`
+	City                        string     `json:"city,omitempty"`
+	PVZId                       *uuid.UUID `json:"pvzId,omitempty"`
+	ProductType                 string     `json:"productType,omitempty"`
+	Receptions                  int        `json:"receptions"`
+	Products                    int        `json:"products"`
+	AvgReceptionDurationSeconds *float64   `json:"avgReceptionDurationSeconds"`
+}
+
+const (
+	CloseReasonManual     = "manual"
+	CloseReasonAutoClosed = "auto_closed"
+	// CloseReasonMigrationDedup — приёмка закрыта миграцией как лишняя открытая приёмка ПВЗ.
+	CloseReasonMigrationDedup = "migration_dedup"
+)
+
+// AutoCloseLimits — сколько приёмка может оставаться открытой; ByCity переопределяет Default.
+type AutoCloseLimits struct {
+	Default time.Duration
+	ByCity  map[string]time.Duration
+}
+
+func (l AutoCloseLimits) For(city string) time.Duration {
+	if limit, ok := l.ByCity[city]; ok {
+		return limit
+	}
+	return l.Default
+}
+
+type AutoClosedReception struct {
+	ID       uuid.UUID
+	PVZId    uuid.UUID
+	City     string
+	DateTime time.Time
+	ClosedAt time.Time
+}
+
+const (
+	EventPVZCreated      = "pvz.created"
+	EventReceptionOpened = "reception.opened"
+	EventReceptionClosed = "reception.closed"
+	EventProductAdded    = "product.added"
+	EventProductRemoved  = "product.removed"
+)
+
+// OutboxEvent — доменное событие из таблицы outbox или уведомления NOTIFY. Payload
+// содержит JSON сущности, к которой относится событие; PVZId задает порядок доставки.
+type OutboxEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	PVZId     uuid.UUID       `json:"pvzId"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type ReceptionClosedPayload struct {
+	Reception
+	CloseReason string `json:"closeReason"`
+}
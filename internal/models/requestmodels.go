@@ -15,6 +15,10 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type CreatePVZRequest struct {
+	City string `json:"city"`
+}
+
 type CreateReceptionRequest struct {
 	PVZId string `json:"pvzId"`
 }
@@ -68,6 +68,125 @@ func (ReceptionStatus) EnumDescriptor() ([]byte, []int) {
 	return file_pvz_proto_rawDescGZIP(), []int{0}
 }
 
+// Стабильные коды ошибок. Ошибки сервиса содержат в деталях статуса
+// google.rpc.ErrorInfo с domain "pvz" и reason — именем кода без префикса
+// ERROR_CODE_, например RECEPTION_ALREADY_OPEN. Описание кодов — в схеме
+// ErrorCode в swagger.yaml; REST возвращает те же коды в поле code.
+type ErrorCode int32
+
+const (
+	ErrorCode_ERROR_CODE_UNSPECIFIED             ErrorCode = 0
+	ErrorCode_ERROR_CODE_INVALID_REQUEST         ErrorCode = 1
+	ErrorCode_ERROR_CODE_UNKNOWN_CITY            ErrorCode = 2
+	ErrorCode_ERROR_CODE_INVALID_ROLE            ErrorCode = 3
+	ErrorCode_ERROR_CODE_INVALID_CREDENTIALS     ErrorCode = 4
+	ErrorCode_ERROR_CODE_UNAUTHORIZED            ErrorCode = 5
+	ErrorCode_ERROR_CODE_FORBIDDEN               ErrorCode = 6
+	ErrorCode_ERROR_CODE_NOT_FOUND               ErrorCode = 7
+	ErrorCode_ERROR_CODE_PVZ_NOT_FOUND           ErrorCode = 8
+	ErrorCode_ERROR_CODE_USER_NOT_FOUND          ErrorCode = 9
+	ErrorCode_ERROR_CODE_FEED_DISABLED           ErrorCode = 10
+	ErrorCode_ERROR_CODE_METHOD_NOT_ALLOWED      ErrorCode = 11
+	ErrorCode_ERROR_CODE_NOT_ACCEPTABLE          ErrorCode = 12
+	ErrorCode_ERROR_CODE_CONFLICT                ErrorCode = 13
+	ErrorCode_ERROR_CODE_USER_ALREADY_EXISTS     ErrorCode = 14
+	ErrorCode_ERROR_CODE_RECEPTION_ALREADY_OPEN  ErrorCode = 15
+	ErrorCode_ERROR_CODE_NO_ACTIVE_RECEPTION     ErrorCode = 16
+	ErrorCode_ERROR_CODE_NO_PRODUCT_TO_DELETE    ErrorCode = 17
+	ErrorCode_ERROR_CODE_IDEMPOTENCY_KEY_INVALID ErrorCode = 18
+	ErrorCode_ERROR_CODE_IDEMPOTENCY_KEY_REUSED  ErrorCode = 19
+	ErrorCode_ERROR_CODE_IDEMPOTENCY_IN_PROGRESS ErrorCode = 20
+	ErrorCode_ERROR_CODE_PAYLOAD_TOO_LARGE       ErrorCode = 21
+	ErrorCode_ERROR_CODE_RATE_LIMITED            ErrorCode = 22
+	ErrorCode_ERROR_CODE_INTERNAL                ErrorCode = 23
+	ErrorCode_ERROR_CODE_UNAVAILABLE             ErrorCode = 24
+)
+
+// Enum value maps for ErrorCode.
+var (
+	ErrorCode_name = map[int32]string{
+		0:  "ERROR_CODE_UNSPECIFIED",
+		1:  "ERROR_CODE_INVALID_REQUEST",
+		2:  "ERROR_CODE_UNKNOWN_CITY",
+		3:  "ERROR_CODE_INVALID_ROLE",
+		4:  "ERROR_CODE_INVALID_CREDENTIALS",
+		5:  "ERROR_CODE_UNAUTHORIZED",
+		6:  "ERROR_CODE_FORBIDDEN",
+		7:  "ERROR_CODE_NOT_FOUND",
+		8:  "ERROR_CODE_PVZ_NOT_FOUND",
+		9:  "ERROR_CODE_USER_NOT_FOUND",
+		10: "ERROR_CODE_FEED_DISABLED",
+		11: "ERROR_CODE_METHOD_NOT_ALLOWED",
+		12: "ERROR_CODE_NOT_ACCEPTABLE",
+		13: "ERROR_CODE_CONFLICT",
+		14: "ERROR_CODE_USER_ALREADY_EXISTS",
+		15: "ERROR_CODE_RECEPTION_ALREADY_OPEN",
+		16: "ERROR_CODE_NO_ACTIVE_RECEPTION",
+		17: "ERROR_CODE_NO_PRODUCT_TO_DELETE",
+		18: "ERROR_CODE_IDEMPOTENCY_KEY_INVALID",
+		19: "ERROR_CODE_IDEMPOTENCY_KEY_REUSED",
+		20: "ERROR_CODE_IDEMPOTENCY_IN_PROGRESS",
+		21: "ERROR_CODE_PAYLOAD_TOO_LARGE",
+		22: "ERROR_CODE_RATE_LIMITED",
+		23: "ERROR_CODE_INTERNAL",
+		24: "ERROR_CODE_UNAVAILABLE",
+	}
+	ErrorCode_value = map[string]int32{
+		"ERROR_CODE_UNSPECIFIED":             0,
+		"ERROR_CODE_INVALID_REQUEST":         1,
+		"ERROR_CODE_UNKNOWN_CITY":            2,
+		"ERROR_CODE_INVALID_ROLE":            3,
+		"ERROR_CODE_INVALID_CREDENTIALS":     4,
+		"ERROR_CODE_UNAUTHORIZED":            5,
+		"ERROR_CODE_FORBIDDEN":               6,
+		"ERROR_CODE_NOT_FOUND":               7,
+		"ERROR_CODE_PVZ_NOT_FOUND":           8,
+		"ERROR_CODE_USER_NOT_FOUND":          9,
+		"ERROR_CODE_FEED_DISABLED":           10,
+		"ERROR_CODE_METHOD_NOT_ALLOWED":      11,
+		"ERROR_CODE_NOT_ACCEPTABLE":          12,
+		"ERROR_CODE_CONFLICT":                13,
+		"ERROR_CODE_USER_ALREADY_EXISTS":     14,
+		"ERROR_CODE_RECEPTION_ALREADY_OPEN":  15,
+		"ERROR_CODE_NO_ACTIVE_RECEPTION":     16,
+		"ERROR_CODE_NO_PRODUCT_TO_DELETE":    17,
+		"ERROR_CODE_IDEMPOTENCY_KEY_INVALID": 18,
+		"ERROR_CODE_IDEMPOTENCY_KEY_REUSED":  19,
+		"ERROR_CODE_IDEMPOTENCY_IN_PROGRESS": 20,
+		"ERROR_CODE_PAYLOAD_TOO_LARGE":       21,
+		"ERROR_CODE_RATE_LIMITED":            22,
+		"ERROR_CODE_INTERNAL":                23,
+		"ERROR_CODE_UNAVAILABLE":             24,
+	}
+)
+
+func (x ErrorCode) Enum() *ErrorCode {
+	p := new(ErrorCode)
+	*p = x
+	return p
+}
+
+func (x ErrorCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_pvz_proto_enumTypes[1].Descriptor()
+}
+
+func (ErrorCode) Type() protoreflect.EnumType {
+	return &file_pvz_proto_enumTypes[1]
+}
+
+func (x ErrorCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorCode.Descriptor instead.
+func (ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{1}
+}
+
 type PVZ struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
 	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -208,6 +327,290 @@ func (x *GetPVZListResponse) GetPvzs() []*PVZ {
 	return nil
 }
 
+type StreamPVZsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	City  string                 `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	// Границы даты регистрации включительно; не заданы — без ограничения.
+	RegisteredFrom *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=registered_from,json=registeredFrom,proto3" json:"registered_from,omitempty"`
+	RegisteredTo   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=registered_to,json=registeredTo,proto3" json:"registered_to,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StreamPVZsRequest) Reset() {
+	*x = StreamPVZsRequest{}
+	mi := &file_pvz_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamPVZsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamPVZsRequest) ProtoMessage() {}
+
+func (x *StreamPVZsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamPVZsRequest.ProtoReflect.Descriptor instead.
+func (*StreamPVZsRequest) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamPVZsRequest) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *StreamPVZsRequest) GetRegisteredFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RegisteredFrom
+	}
+	return nil
+}
+
+func (x *StreamPVZsRequest) GetRegisteredTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RegisteredTo
+	}
+	return nil
+}
+
+type GetIntakeAnalyticsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	From  *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To    *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	// hour, day (по умолчанию) или week.
+	Bucket string `protobuf:"bytes,3,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// city (по умолчанию), pvz, product_type.
+	GroupBy       []string `protobuf:"bytes,4,rep,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	City          string   `protobuf:"bytes,5,opt,name=city,proto3" json:"city,omitempty"`
+	PvzId         string   `protobuf:"bytes,6,opt,name=pvz_id,json=pvzId,proto3" json:"pvz_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetIntakeAnalyticsRequest) Reset() {
+	*x = GetIntakeAnalyticsRequest{}
+	mi := &file_pvz_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetIntakeAnalyticsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIntakeAnalyticsRequest) ProtoMessage() {}
+
+func (x *GetIntakeAnalyticsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIntakeAnalyticsRequest.ProtoReflect.Descriptor instead.
+func (*GetIntakeAnalyticsRequest) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetIntakeAnalyticsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *GetIntakeAnalyticsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *GetIntakeAnalyticsRequest) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *GetIntakeAnalyticsRequest) GetGroupBy() []string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return nil
+}
+
+func (x *GetIntakeAnalyticsRequest) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *GetIntakeAnalyticsRequest) GetPvzId() string {
+	if x != nil {
+		return x.PvzId
+	}
+	return ""
+}
+
+type IntakeRow struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Bucket      *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	City        string                 `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	PvzId       string                 `protobuf:"bytes,3,opt,name=pvz_id,json=pvzId,proto3" json:"pvz_id,omitempty"`
+	ProductType string                 `protobuf:"bytes,4,opt,name=product_type,json=productType,proto3" json:"product_type,omitempty"`
+	Receptions  int64                  `protobuf:"varint,5,opt,name=receptions,proto3" json:"receptions,omitempty"`
+	Products    int64                  `protobuf:"varint,6,opt,name=products,proto3" json:"products,omitempty"`
+	// Не задано, если в интервале нет закрытых приёмок.
+	AvgReceptionDurationSeconds *float64 `protobuf:"fixed64,7,opt,name=avg_reception_duration_seconds,json=avgReceptionDurationSeconds,proto3,oneof" json:"avg_reception_duration_seconds,omitempty"`
+	unknownFields               protoimpl.UnknownFields
+	sizeCache                   protoimpl.SizeCache
+}
+
+func (x *IntakeRow) Reset() {
+	*x = IntakeRow{}
+	mi := &file_pvz_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IntakeRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IntakeRow) ProtoMessage() {}
+
+func (x *IntakeRow) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IntakeRow.ProtoReflect.Descriptor instead.
+func (*IntakeRow) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IntakeRow) GetBucket() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Bucket
+	}
+	return nil
+}
+
+func (x *IntakeRow) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *IntakeRow) GetPvzId() string {
+	if x != nil {
+		return x.PvzId
+	}
+	return ""
+}
+
+func (x *IntakeRow) GetProductType() string {
+	if x != nil {
+		return x.ProductType
+	}
+	return ""
+}
+
+func (x *IntakeRow) GetReceptions() int64 {
+	if x != nil {
+		return x.Receptions
+	}
+	return 0
+}
+
+func (x *IntakeRow) GetProducts() int64 {
+	if x != nil {
+		return x.Products
+	}
+	return 0
+}
+
+func (x *IntakeRow) GetAvgReceptionDurationSeconds() float64 {
+	if x != nil && x.AvgReceptionDurationSeconds != nil {
+		return *x.AvgReceptionDurationSeconds
+	}
+	return 0
+}
+
+type GetIntakeAnalyticsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rows          []*IntakeRow           `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetIntakeAnalyticsResponse) Reset() {
+	*x = GetIntakeAnalyticsResponse{}
+	mi := &file_pvz_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetIntakeAnalyticsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIntakeAnalyticsResponse) ProtoMessage() {}
+
+func (x *GetIntakeAnalyticsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIntakeAnalyticsResponse.ProtoReflect.Descriptor instead.
+func (*GetIntakeAnalyticsResponse) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetIntakeAnalyticsResponse) GetRows() []*IntakeRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
 var File_pvz_proto protoreflect.FileDescriptor
 
 const file_pvz_proto_rawDesc = "" +
@@ -219,14 +622,68 @@ const file_pvz_proto_rawDesc = "" +
 	"\x04city\x18\x03 \x01(\tR\x04city\"\x13\n" +
 	"\x11GetPVZListRequest\"5\n" +
 	"\x12GetPVZListResponse\x12\x1f\n" +
-	"\x04pvzs\x18\x01 \x03(\v2\v.pvz.v1.PVZR\x04pvzs*P\n" +
+	"\x04pvzs\x18\x01 \x03(\v2\v.pvz.v1.PVZR\x04pvzs\"\xad\x01\n" +
+	"\x11StreamPVZsRequest\x12\x12\n" +
+	"\x04city\x18\x01 \x01(\tR\x04city\x12C\n" +
+	"\x0fregistered_from\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x0eregisteredFrom\x12?\n" +
+	"\rregistered_to\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\fregisteredTo\"\xd5\x01\n" +
+	"\x19GetIntakeAnalyticsRequest\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12\x16\n" +
+	"\x06bucket\x18\x03 \x01(\tR\x06bucket\x12\x19\n" +
+	"\bgroup_by\x18\x04 \x03(\tR\agroupBy\x12\x12\n" +
+	"\x04city\x18\x05 \x01(\tR\x04city\x12\x15\n" +
+	"\x06pvz_id\x18\x06 \x01(\tR\x05pvzId\"\xb6\x02\n" +
+	"\tIntakeRow\x122\n" +
+	"\x06bucket\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x06bucket\x12\x12\n" +
+	"\x04city\x18\x02 \x01(\tR\x04city\x12\x15\n" +
+	"\x06pvz_id\x18\x03 \x01(\tR\x05pvzId\x12!\n" +
+	"\fproduct_type\x18\x04 \x01(\tR\vproductType\x12\x1e\n" +
+	"\n" +
+	"receptions\x18\x05 \x01(\x03R\n" +
+	"receptions\x12\x1a\n" +
+	"\bproducts\x18\x06 \x01(\x03R\bproducts\x12H\n" +
+	"\x1eavg_reception_duration_seconds\x18\a \x01(\x01H\x00R\x1bavgReceptionDurationSeconds\x88\x01\x01B!\n" +
+	"\x1f_avg_reception_duration_seconds\"C\n" +
+	"\x1aGetIntakeAnalyticsResponse\x12%\n" +
+	"\x04rows\x18\x01 \x03(\v2\x11.pvz.v1.IntakeRowR\x04rows*P\n" +
 	"\x0fReceptionStatus\x12 \n" +
 	"\x1cRECEPTION_STATUS_IN_PROGRESS\x10\x00\x12\x1b\n" +
-	"\x17RECEPTION_STATUS_CLOSED\x10\x012Q\n" +
+	"\x17RECEPTION_STATUS_CLOSED\x10\x01*\xab\x06\n" +
+	"\tErrorCode\x12\x1a\n" +
+	"\x16ERROR_CODE_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aERROR_CODE_INVALID_REQUEST\x10\x01\x12\x1b\n" +
+	"\x17ERROR_CODE_UNKNOWN_CITY\x10\x02\x12\x1b\n" +
+	"\x17ERROR_CODE_INVALID_ROLE\x10\x03\x12\"\n" +
+	"\x1eERROR_CODE_INVALID_CREDENTIALS\x10\x04\x12\x1b\n" +
+	"\x17ERROR_CODE_UNAUTHORIZED\x10\x05\x12\x18\n" +
+	"\x14ERROR_CODE_FORBIDDEN\x10\x06\x12\x18\n" +
+	"\x14ERROR_CODE_NOT_FOUND\x10\a\x12\x1c\n" +
+	"\x18ERROR_CODE_PVZ_NOT_FOUND\x10\b\x12\x1d\n" +
+	"\x19ERROR_CODE_USER_NOT_FOUND\x10\t\x12\x1c\n" +
+	"\x18ERROR_CODE_FEED_DISABLED\x10\n" +
+	"\x12!\n" +
+	"\x1dERROR_CODE_METHOD_NOT_ALLOWED\x10\v\x12\x1d\n" +
+	"\x19ERROR_CODE_NOT_ACCEPTABLE\x10\f\x12\x17\n" +
+	"\x13ERROR_CODE_CONFLICT\x10\r\x12\"\n" +
+	"\x1eERROR_CODE_USER_ALREADY_EXISTS\x10\x0e\x12%\n" +
+	"!ERROR_CODE_RECEPTION_ALREADY_OPEN\x10\x0f\x12\"\n" +
+	"\x1eERROR_CODE_NO_ACTIVE_RECEPTION\x10\x10\x12#\n" +
+	"\x1fERROR_CODE_NO_PRODUCT_TO_DELETE\x10\x11\x12&\n" +
+	"\"ERROR_CODE_IDEMPOTENCY_KEY_INVALID\x10\x12\x12%\n" +
+	"!ERROR_CODE_IDEMPOTENCY_KEY_REUSED\x10\x13\x12&\n" +
+	"\"ERROR_CODE_IDEMPOTENCY_IN_PROGRESS\x10\x14\x12 \n" +
+	"\x1cERROR_CODE_PAYLOAD_TOO_LARGE\x10\x15\x12\x1b\n" +
+	"\x17ERROR_CODE_RATE_LIMITED\x10\x16\x12\x17\n" +
+	"\x13ERROR_CODE_INTERNAL\x10\x17\x12\x1a\n" +
+	"\x16ERROR_CODE_UNAVAILABLE\x10\x182\xe6\x01\n" +
 	"\n" +
 	"PVZService\x12C\n" +
 	"\n" +
-	"GetPVZList\x12\x19.pvz.v1.GetPVZListRequest\x1a\x1a.pvz.v1.GetPVZListResponseB\x1fZ\x1dpvz/internal/pb/pvz_v1;pvz_v1b\x06proto3"
+	"GetPVZList\x12\x19.pvz.v1.GetPVZListRequest\x1a\x1a.pvz.v1.GetPVZListResponse\x12[\n" +
+	"\x12GetIntakeAnalytics\x12!.pvz.v1.GetIntakeAnalyticsRequest\x1a\".pvz.v1.GetIntakeAnalyticsResponse\x126\n" +
+	"\n" +
+	"StreamPVZs\x12\x19.pvz.v1.StreamPVZsRequest\x1a\v.pvz.v1.PVZ0\x01B\x1fZ\x1dpvz/internal/pb/pvz_v1;pvz_v1b\x06proto3"
 
 var (
 	file_pvz_proto_rawDescOnce sync.Once
@@ -240,25 +697,40 @@ func file_pvz_proto_rawDescGZIP() []byte {
 	return file_pvz_proto_rawDescData
 }
 
-var file_pvz_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_pvz_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_pvz_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_pvz_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_pvz_proto_goTypes = []any{
-	(ReceptionStatus)(0),          // 0: pvz.v1.ReceptionStatus
-	(*PVZ)(nil),                   // 1: pvz.v1.PVZ
-	(*GetPVZListRequest)(nil),     // 2: pvz.v1.GetPVZListRequest
-	(*GetPVZListResponse)(nil),    // 3: pvz.v1.GetPVZListResponse
-	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+	(ReceptionStatus)(0),               // 0: pvz.v1.ReceptionStatus
+	(ErrorCode)(0),                     // 1: pvz.v1.ErrorCode
+	(*PVZ)(nil),                        // 2: pvz.v1.PVZ
+	(*GetPVZListRequest)(nil),          // 3: pvz.v1.GetPVZListRequest
+	(*GetPVZListResponse)(nil),         // 4: pvz.v1.GetPVZListResponse
+	(*StreamPVZsRequest)(nil),          // 5: pvz.v1.StreamPVZsRequest
+	(*GetIntakeAnalyticsRequest)(nil),  // 6: pvz.v1.GetIntakeAnalyticsRequest
+	(*IntakeRow)(nil),                  // 7: pvz.v1.IntakeRow
+	(*GetIntakeAnalyticsResponse)(nil), // 8: pvz.v1.GetIntakeAnalyticsResponse
+	(*timestamppb.Timestamp)(nil),      // 9: google.protobuf.Timestamp
 }
 var file_pvz_proto_depIdxs = []int32{
-	4, // 0: pvz.v1.PVZ.registration_date:type_name -> google.protobuf.Timestamp
-	1, // 1: pvz.v1.GetPVZListResponse.pvzs:type_name -> pvz.v1.PVZ
-	2, // 2: pvz.v1.PVZService.GetPVZList:input_type -> pvz.v1.GetPVZListRequest
-	3, // 3: pvz.v1.PVZService.GetPVZList:output_type -> pvz.v1.GetPVZListResponse
-	3, // [3:4] is the sub-list for method output_type
-	2, // [2:3] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	9,  // 0: pvz.v1.PVZ.registration_date:type_name -> google.protobuf.Timestamp
+	2,  // 1: pvz.v1.GetPVZListResponse.pvzs:type_name -> pvz.v1.PVZ
+	9,  // 2: pvz.v1.StreamPVZsRequest.registered_from:type_name -> google.protobuf.Timestamp
+	9,  // 3: pvz.v1.StreamPVZsRequest.registered_to:type_name -> google.protobuf.Timestamp
+	9,  // 4: pvz.v1.GetIntakeAnalyticsRequest.from:type_name -> google.protobuf.Timestamp
+	9,  // 5: pvz.v1.GetIntakeAnalyticsRequest.to:type_name -> google.protobuf.Timestamp
+	9,  // 6: pvz.v1.IntakeRow.bucket:type_name -> google.protobuf.Timestamp
+	7,  // 7: pvz.v1.GetIntakeAnalyticsResponse.rows:type_name -> pvz.v1.IntakeRow
+	3,  // 8: pvz.v1.PVZService.GetPVZList:input_type -> pvz.v1.GetPVZListRequest
+	6,  // 9: pvz.v1.PVZService.GetIntakeAnalytics:input_type -> pvz.v1.GetIntakeAnalyticsRequest
+	5,  // 10: pvz.v1.PVZService.StreamPVZs:input_type -> pvz.v1.StreamPVZsRequest
+	4,  // 11: pvz.v1.PVZService.GetPVZList:output_type -> pvz.v1.GetPVZListResponse
+	8,  // 12: pvz.v1.PVZService.GetIntakeAnalytics:output_type -> pvz.v1.GetIntakeAnalyticsResponse
+	2,  // 13: pvz.v1.PVZService.StreamPVZs:output_type -> pvz.v1.PVZ
+	11, // [11:14] is the sub-list for method output_type
+	8,  // [8:11] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_pvz_proto_init() }
@@ -266,13 +738,14 @@ func file_pvz_proto_init() {
 	if File_pvz_proto != nil {
 		return
 	}
+	file_pvz_proto_msgTypes[5].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pvz_proto_rawDesc), len(file_pvz_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   3,
+			NumEnums:      2,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
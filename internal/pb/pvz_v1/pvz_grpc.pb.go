@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PVZService_GetPVZList_FullMethodName = "/pvz.v1.PVZService/GetPVZList"
+	PVZService_GetPVZList_FullMethodName         = "/pvz.v1.PVZService/GetPVZList"
+	PVZService_GetIntakeAnalytics_FullMethodName = "/pvz.v1.PVZService/GetIntakeAnalytics"
+	PVZService_StreamPVZs_FullMethodName         = "/pvz.v1.PVZService/StreamPVZs"
 )
 
 // PVZServiceClient is the client API for PVZService service.
@@ -27,6 +29,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type PVZServiceClient interface {
 	GetPVZList(ctx context.Context, in *GetPVZListRequest, opts ...grpc.CallOption) (*GetPVZListResponse, error)
+	GetIntakeAnalytics(ctx context.Context, in *GetIntakeAnalyticsRequest, opts ...grpc.CallOption) (*GetIntakeAnalyticsResponse, error)
+	// Отдает ПВЗ по одному сообщению по мере чтения из БД, в порядке даты регистрации.
+	StreamPVZs(ctx context.Context, in *StreamPVZsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PVZ], error)
 }
 
 type pVZServiceClient struct {
@@ -47,11 +52,43 @@ func (c *pVZServiceClient) GetPVZList(ctx context.Context, in *GetPVZListRequest
 	return out, nil
 }
 
+func (c *pVZServiceClient) GetIntakeAnalytics(ctx context.Context, in *GetIntakeAnalyticsRequest, opts ...grpc.CallOption) (*GetIntakeAnalyticsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetIntakeAnalyticsResponse)
+	err := c.cc.Invoke(ctx, PVZService_GetIntakeAnalytics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pVZServiceClient) StreamPVZs(ctx context.Context, in *StreamPVZsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PVZ], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PVZService_ServiceDesc.Streams[0], PVZService_StreamPVZs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamPVZsRequest, PVZ]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PVZService_StreamPVZsClient = grpc.ServerStreamingClient[PVZ]
+
 // PVZServiceServer is the server API for PVZService service.
 // All implementations must embed UnimplementedPVZServiceServer
 // for forward compatibility.
 type PVZServiceServer interface {
 	GetPVZList(context.Context, *GetPVZListRequest) (*GetPVZListResponse, error)
+	GetIntakeAnalytics(context.Context, *GetIntakeAnalyticsRequest) (*GetIntakeAnalyticsResponse, error)
+	// Отдает ПВЗ по одному сообщению по мере чтения из БД, в порядке даты регистрации.
+	StreamPVZs(*StreamPVZsRequest, grpc.ServerStreamingServer[PVZ]) error
 	mustEmbedUnimplementedPVZServiceServer()
 }
 
@@ -65,6 +102,12 @@ type UnimplementedPVZServiceServer struct{}
 func (UnimplementedPVZServiceServer) GetPVZList(context.Context, *GetPVZListRequest) (*GetPVZListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPVZList not implemented")
 }
+func (UnimplementedPVZServiceServer) GetIntakeAnalytics(context.Context, *GetIntakeAnalyticsRequest) (*GetIntakeAnalyticsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIntakeAnalytics not implemented")
+}
+func (UnimplementedPVZServiceServer) StreamPVZs(*StreamPVZsRequest, grpc.ServerStreamingServer[PVZ]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPVZs not implemented")
+}
 func (UnimplementedPVZServiceServer) mustEmbedUnimplementedPVZServiceServer() {}
 func (UnimplementedPVZServiceServer) testEmbeddedByValue()                    {}
 
@@ -104,6 +147,35 @@ func _PVZService_GetPVZList_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PVZService_GetIntakeAnalytics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetIntakeAnalyticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PVZServiceServer).GetIntakeAnalytics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PVZService_GetIntakeAnalytics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PVZServiceServer).GetIntakeAnalytics(ctx, req.(*GetIntakeAnalyticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PVZService_StreamPVZs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPVZsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PVZServiceServer).StreamPVZs(m, &grpc.GenericServerStream[StreamPVZsRequest, PVZ]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PVZService_StreamPVZsServer = grpc.ServerStreamingServer[PVZ]
+
 // PVZService_ServiceDesc is the grpc.ServiceDesc for PVZService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,7 +187,17 @@ var PVZService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetPVZList",
 			Handler:    _PVZService_GetPVZList_Handler,
 		},
+		{
+			MethodName: "GetIntakeAnalytics",
+			Handler:    _PVZService_GetIntakeAnalytics_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPVZs",
+			Handler:       _PVZService_StreamPVZs_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pvz.proto",
 }
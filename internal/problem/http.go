@@ -0,0 +1,38 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pvz/internal/models"
+)
+
+const ContentType = "application/problem+json"
+
+// Write отправляет ответ об ошибке в формате RFC 7807. Тип проблемы не публикуется
+// (about:blank), поэтому title — текст HTTP-статуса, а сама ошибка задается кодом.
+// Язык detail выбирается по заголовку Accept-Language.
+func Write(w http.ResponseWriter, r *http.Request, status int, code Code, detail string, details ...string) {
+	lang := Negotiate(r.Header.Get("Accept-Language"))
+	detail = Localize(code, detail, lang)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Language", lang.String())
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.ErrorResponse{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     string(code),
+		Message:  detail,
+		Details:  details,
+	})
+}
+
+// WriteError отправляет ошибку сервисного слоя со статусом, который задает ее код.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	Write(w, r, e.Code.HTTPStatus(), e.Code, e.Message)
+}
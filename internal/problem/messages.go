@@ -0,0 +1,92 @@
+package problem
+
+import "golang.org/x/text/language"
+
+// Languages перечисляет языки сообщений об ошибках; первый используется по умолчанию.
+var Languages = []language.Tag{language.Russian, language.English}
+
+var matcher = language.NewMatcher(Languages)
+
+// messages — каталог сообщений по кодам ошибок. Тест пакета проверяет, что у каждого
+// кода есть перевод на каждый язык из Languages.
+var messages = map[language.Tag]map[Code]string{
+	language.Russian: {
+		CodeInvalidRequest:        "неверный запрос",
+		CodeUnknownCity:           "неизвестный город",
+		CodeInvalidRole:           "неверная роль",
+		CodeInvalidCredentials:    "неверные учетные данные",
+		CodeUnauthorized:          "требуется авторизация",
+		CodeForbidden:             "доступ запрещен",
+		CodeNotFound:              "не найдено",
+		CodePVZNotFound:           "ПВЗ не найден",
+		CodeUserNotFound:          "пользователь не найден",
+		CodeFeedDisabled:          "лента событий выключена",
+		CodeMethodNotAllowed:      "метод не поддерживается",
+		CodeNotAcceptable:         "формат ответа не поддерживается",
+		CodeConflict:              "конфликт с текущим состоянием",
+		CodeUserAlreadyExists:     "пользователь с таким email уже существует",
+		CodeReceptionAlreadyOpen:  "у ПВЗ уже есть открытая приёмка",
+		CodeNoActiveReception:     "у ПВЗ нет открытой приёмки",
+		CodeNoProductToDelete:     "в открытой приёмке нет товаров",
+		CodeIdempotencyKeyInvalid: "неверный ключ идемпотентности",
+		CodeIdempotencyKeyReused:  "ключ идемпотентности уже использован с другим запросом",
+		CodeIdempotencyInProgress: "запрос с этим ключом идемпотентности ещё выполняется",
+		CodePayloadTooLarge:       "тело запроса слишком большое",
+		CodeRateLimited:           "слишком много запросов",
+		CodeUnavailable:           "база данных временно недоступна",
+		CodeInternal:              "внутренняя ошибка сервера",
+	},
+	language.English: {
+		CodeInvalidRequest:        "invalid request",
+		CodeUnknownCity:           "unknown city",
+		CodeInvalidRole:           "invalid role",
+		CodeInvalidCredentials:    "invalid credentials",
+		CodeUnauthorized:          "authorization required",
+		CodeForbidden:             "access denied",
+		CodeNotFound:              "not found",
+		CodePVZNotFound:           "pickup point not found",
+		CodeUserNotFound:          "user not found",
+		CodeFeedDisabled:          "event feed is disabled",
+		CodeMethodNotAllowed:      "method not allowed",
+		CodeNotAcceptable:         "response format is not supported",
+		CodeConflict:              "conflict with the current state",
+		CodeUserAlreadyExists:     "a user with this email already exists",
+		CodeReceptionAlreadyOpen:  "the pickup point already has an open reception",
+		CodeNoActiveReception:     "the pickup point has no open reception",
+		CodeNoProductToDelete:     "the open reception has no products",
+		CodeIdempotencyKeyInvalid: "invalid idempotency key",
+		CodeIdempotencyKeyReused:  "the idempotency key was already used with a different request",
+		CodeIdempotencyInProgress: "a request with this idempotency key is still in progress",
+		CodePayloadTooLarge:       "request body is too large",
+		CodeRateLimited:           "too many requests",
+		CodeUnavailable:           "the database is temporarily unavailable",
+		CodeInternal:              "internal server error",
+	},
+}
+
+// Negotiate выбирает язык сообщений по значению Accept-Language. Без подходящего
+// языка возвращается русский.
+func Negotiate(acceptLanguage string) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Languages[0]
+	}
+	_, i, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Languages[0]
+	}
+	return Languages[i]
+}
+
+// Localize возвращает текст ошибки с кодом code на языке lang. Русский текст message
+// уточняет ошибку (например, называет неверный параметр) и отдается как есть, для
+// остальных языков берется сообщение каталога.
+func Localize(code Code, message string, lang language.Tag) string {
+	if lang == Languages[0] && message != "" {
+		return message
+	}
+	if m, ok := messages[lang][code]; ok {
+		return m
+	}
+	return messages[lang][CodeInternal]
+}
@@ -0,0 +1,148 @@
+// Package problem задает стабильные коды ошибок API. Коды передаются в REST в теле
+// application/problem+json (RFC 7807), а в gRPC — в google.rpc.ErrorInfo, поэтому
+// клиенты различают ошибки по коду, а не по тексту сообщения.
+package problem
+
+import (
+	"errors"
+	"net/http"
+)
+
+type Code string
+
+const (
+	CodeInvalidRequest        Code = "INVALID_REQUEST"
+	CodeUnknownCity           Code = "UNKNOWN_CITY"
+	CodeInvalidRole           Code = "INVALID_ROLE"
+	CodeInvalidCredentials    Code = "INVALID_CREDENTIALS"
+	CodeUnauthorized          Code = "UNAUTHORIZED"
+	CodeForbidden             Code = "FORBIDDEN"
+	CodeNotFound              Code = "NOT_FOUND"
+	CodePVZNotFound           Code = "PVZ_NOT_FOUND"
+	CodeUserNotFound          Code = "USER_NOT_FOUND"
+	CodeFeedDisabled          Code = "FEED_DISABLED"
+	CodeMethodNotAllowed      Code = "METHOD_NOT_ALLOWED"
+	CodeNotAcceptable         Code = "NOT_ACCEPTABLE"
+	CodeConflict              Code = "CONFLICT"
+	CodeUserAlreadyExists     Code = "USER_ALREADY_EXISTS"
+	CodeReceptionAlreadyOpen  Code = "RECEPTION_ALREADY_OPEN"
+	CodeNoActiveReception     Code = "NO_ACTIVE_RECEPTION"
+	CodeNoProductToDelete     Code = "NO_PRODUCT_TO_DELETE"
+	CodeIdempotencyKeyInvalid Code = "IDEMPOTENCY_KEY_INVALID"
+	CodeIdempotencyKeyReused  Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInProgress Code = "IDEMPOTENCY_IN_PROGRESS"
+	CodePayloadTooLarge       Code = "PAYLOAD_TOO_LARGE"
+	CodeRateLimited           Code = "RATE_LIMITED"
+	CodeUnavailable           Code = "UNAVAILABLE"
+	CodeInternal              Code = "INTERNAL"
+)
+
+// Codes перечисляет все коды; по нему тесты сверяют документацию в swagger.yaml и pvz.proto.
+var Codes = []Code{
+	CodeInvalidRequest,
+	CodeUnknownCity,
+	CodeInvalidRole,
+	CodeInvalidCredentials,
+	CodeUnauthorized,
+	CodeForbidden,
+	CodeNotFound,
+	CodePVZNotFound,
+	CodeUserNotFound,
+	CodeFeedDisabled,
+	CodeMethodNotAllowed,
+	CodeNotAcceptable,
+	CodeConflict,
+	CodeUserAlreadyExists,
+	CodeReceptionAlreadyOpen,
+	CodeNoActiveReception,
+	CodeNoProductToDelete,
+	CodeIdempotencyKeyInvalid,
+	CodeIdempotencyKeyReused,
+	CodeIdempotencyInProgress,
+	CodePayloadTooLarge,
+	CodeRateLimited,
+	CodeUnavailable,
+	CodeInternal,
+}
+
+// Domain — значение google.rpc.ErrorInfo.domain для ошибок сервиса.
+const Domain = "pvz"
+
+var statuses = map[Code]int{
+	CodeInvalidRequest:        http.StatusBadRequest,
+	CodeUnknownCity:           http.StatusBadRequest,
+	CodeInvalidRole:           http.StatusBadRequest,
+	CodeInvalidCredentials:    http.StatusUnauthorized,
+	CodeUnauthorized:          http.StatusUnauthorized,
+	CodeForbidden:             http.StatusForbidden,
+	CodeNotFound:              http.StatusNotFound,
+	CodePVZNotFound:           http.StatusNotFound,
+	CodeUserNotFound:          http.StatusNotFound,
+	CodeFeedDisabled:          http.StatusNotFound,
+	CodeMethodNotAllowed:      http.StatusMethodNotAllowed,
+	CodeNotAcceptable:         http.StatusNotAcceptable,
+	CodeConflict:              http.StatusConflict,
+	CodeUserAlreadyExists:     http.StatusConflict,
+	CodeReceptionAlreadyOpen:  http.StatusConflict,
+	CodeNoActiveReception:     http.StatusConflict,
+	CodeNoProductToDelete:     http.StatusConflict,
+	CodeIdempotencyKeyInvalid: http.StatusBadRequest,
+	CodeIdempotencyKeyReused:  http.StatusConflict,
+	CodeIdempotencyInProgress: http.StatusConflict,
+	CodePayloadTooLarge:       http.StatusRequestEntityTooLarge,
+	CodeRateLimited:           http.StatusTooManyRequests,
+	CodeUnavailable:           http.StatusServiceUnavailable,
+	CodeInternal:              http.StatusInternalServerError,
+}
+
+// HTTPStatus возвращает HTTP-статус, с которым REST отдает ошибку с этим кодом.
+func (c Code) HTTPStatus() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error — ошибка с кодом API. Клиенту показывается только Message; причина Err
+// остается для журнала и errors.Is.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap возвращает копию ошибки с причиной err.
+func (e *Error) Wrap(err error) *Error {
+	return &Error{Code: e.Code, Message: e.Message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is сравнивает код и сообщение, поэтому errors.Is узнает сигнальную ошибку и после Wrap.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == e.Message
+}
+
+// From возвращает ошибку с кодом из цепочки err. Ошибки без кода считаются
+// внутренними: их текст может содержать детали БД и клиенту не показывается.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Code: CodeInternal, Message: "внутренняя ошибка сервера", Err: err}
+}
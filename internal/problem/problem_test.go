@@ -0,0 +1,124 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+
+	"pvz/docs"
+	"pvz/internal/models"
+	pb "pvz/internal/pb/pvz_v1"
+)
+
+func TestCodesDocumented(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(docs.Swagger)
+	require.NoError(t, err)
+	schema := doc.Components.Schemas["ErrorCode"]
+	require.NotNil(t, schema, "в swagger.yaml нет схемы ErrorCode")
+
+	documented := make(map[string]bool)
+	for _, value := range schema.Value.Enum {
+		documented[value.(string)] = true
+	}
+	assert.Len(t, documented, len(Codes), "перечисление ErrorCode в swagger.yaml расходится с Codes")
+	assert.Len(t, pb.ErrorCode_value, len(Codes)+1, "перечисление ErrorCode в pvz.proto расходится с Codes")
+	for _, code := range Codes {
+		assert.True(t, documented[string(code)], "код %s не описан в swagger.yaml", code)
+		_, ok := pb.ErrorCode_value["ERROR_CODE_"+string(code)]
+		assert.True(t, ok, "код %s не описан в pvz.proto", code)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	cause := errors.New("no rows in result set")
+	notFound := New(CodePVZNotFound, "ПВЗ не найден")
+	wrapped := fmt.Errorf("получение ПВЗ: %w", notFound.Wrap(cause))
+
+	e := From(wrapped)
+	assert.Equal(t, CodePVZNotFound, e.Code)
+	assert.Equal(t, "ПВЗ не найден", e.Message)
+	assert.ErrorIs(t, wrapped, notFound)
+	assert.ErrorIs(t, wrapped, cause)
+
+	e = From(fmt.Errorf("ошибка выборки ПВЗ: %w", cause))
+	assert.Equal(t, CodeInternal, e.Code)
+	assert.Equal(t, "внутренняя ошибка сервера", e.Message, "текст ошибки без кода не должен попадать к клиенту")
+	assert.ErrorIs(t, e, cause)
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for _, code := range Codes {
+		_, ok := statuses[code]
+		assert.True(t, ok, "для кода %s не задан HTTP-статус", code)
+	}
+	assert.Equal(t, http.StatusNotFound, CodePVZNotFound.HTTPStatus())
+	assert.Equal(t, http.StatusConflict, CodeReceptionAlreadyOpen.HTTPStatus())
+	assert.Equal(t, http.StatusServiceUnavailable, CodeUnavailable.HTTPStatus())
+	assert.Equal(t, http.StatusInternalServerError, Code("UNKNOWN").HTTPStatus())
+}
+
+func TestWrite(t *testing.T) {
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/receptions", nil)
+	Write(rr, r, http.StatusConflict, CodeReceptionAlreadyOpen, "Активная приёмка уже существует")
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, ContentType, rr.Header().Get("Content-Type"))
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, models.ErrorResponse{
+		Type:     "about:blank",
+		Title:    "Conflict",
+		Status:   http.StatusConflict,
+		Detail:   "Активная приёмка уже существует",
+		Instance: "/receptions",
+		Code:     "RECEPTION_ALREADY_OPEN",
+		Message:  "Активная приёмка уже существует",
+	}, resp)
+}
+
+func TestMessagesTranslated(t *testing.T) {
+	for _, lang := range Languages {
+		for _, code := range Codes {
+			assert.NotEmpty(t, messages[lang][code], "нет перевода кода %s на %s", code, lang)
+		}
+		assert.Len(t, messages[lang], len(Codes), "в каталоге %s есть коды вне Codes", lang)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for header, want := range map[string]language.Tag{
+		"":                      language.Russian,
+		"en":                    language.English,
+		"en-US,en;q=0.9":        language.English,
+		"de-DE, en;q=0.5":       language.English,
+		"ru-RU,ru;q=0.9,en;q=0": language.Russian,
+		"fr":                    language.Russian,
+		"не заголовок;q=abc":    language.Russian,
+	} {
+		assert.Equal(t, want, Negotiate(header), "Accept-Language: %q", header)
+	}
+}
+
+func TestWriteLocalized(t *testing.T) {
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/receptions", nil)
+	r.Header.Set("Accept-Language", "en-GB,en;q=0.9,ru;q=0.5")
+	Write(rr, r, http.StatusConflict, CodeReceptionAlreadyOpen, "у ПВЗ уже есть открытая приёмка")
+
+	assert.Equal(t, "en", rr.Header().Get("Content-Language"))
+	assert.Contains(t, rr.Header().Values("Vary"), "Accept-Language")
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "RECEPTION_ALREADY_OPEN", resp.Code)
+	assert.Equal(t, "the pickup point already has an open reception", resp.Detail)
+	assert.Equal(t, resp.Detail, resp.Message)
+}
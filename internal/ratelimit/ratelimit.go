@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRoute    = "*"
+	DecisionAllowed = "allowed"
+	DecisionLimited = "limited"
+	cleanupInterval = time.Minute
+)
+
+type Rule struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+type Limiter struct {
+	mu          sync.Mutex
+	rules       map[string]Rule
+	buckets     map[string]*bucket
+	now         func() time.Time
+	lastCleanup time.Time
+}
+
+func NewLimiter(rules map[string]Rule) *Limiter {
+	return &Limiter{
+		rules:   rules,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (l *Limiter) Enabled() bool {
+	return l != nil && len(l.rules) > 0
+}
+
+func (l *Limiter) ruleFor(method, route string) (string, Rule, bool) {
+	if rule, ok := l.rules[method+" "+route]; ok {
+		return method + " " + route, rule, true
+	}
+	if rule, ok := l.rules[route]; ok {
+		return route, rule, true
+	}
+	if rule, ok := l.rules[DefaultRoute]; ok {
+		return DefaultRoute, rule, true
+	}
+	return "", Rule{}, false
+}
+
+// Allow списывает токен из корзины ключа key для маршрута и возвращает,
+// разрешен ли запрос, время до появления следующего токена и правило,
+// по которому было принято решение (пустое, если ограничение не задано).
+func (l *Limiter) Allow(method, route, key string) (allowed bool, retryAfter time.Duration, ruleName string) {
+	ruleName, rule, ok := l.ruleFor(method, route)
+	if !ok {
+		return true, 0, ""
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	bucketKey := ruleName + "|" + key
+	b, ok := l.buckets[bucketKey]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now, rule: rule}
+		l.buckets[bucketKey] = b
+	}
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, ruleName
+	}
+	if rule.Rate <= 0 {
+		return false, time.Hour, ruleName
+	}
+	wait := time.Duration((1 - b.tokens) / rule.Rate * float64(time.Second))
+	return false, wait, ruleName
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.rule.Burst), b.tokens+elapsed*b.rule.Rate)
+		b.last = now
+	}
+}
+
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < cleanupInterval {
+		return
+	}
+	l.lastCleanup = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ParseRules разбирает правила вида "POST /products=5:10,/login=1:5,*=20:40",
+// где значение — это скорость пополнения (запросов в секунду) и размер корзины.
+func ParseRules(s string) (map[string]Rule, error) {
+	rules := make(map[string]Rule)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("неверное правило ограничения %q: ожидается маршрут=скорость:корзина", part)
+		}
+		rule, err := ParseRule(limit)
+		if err != nil {
+			return nil, fmt.Errorf("неверное правило ограничения %q: %w", part, err)
+		}
+		rules[strings.TrimSpace(route)] = rule
+	}
+	return rules, nil
+}
+
+func ParseRule(s string) (Rule, error) {
+	rateStr, burstStr, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return Rule{}, fmt.Errorf("ожидается скорость:корзина")
+	}
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate < 0 {
+		return Rule{}, fmt.Errorf("неверная скорость %q", rateStr)
+	}
+	burst, err := strconv.Atoi(burstStr)
+	if err != nil || burst < 1 {
+		return Rule{}, fmt.Errorf("неверный размер корзины %q", burstStr)
+	}
+	return Rule{Rate: rate, Burst: burst}, nil
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(map[string]Rule{
+		"POST /products": {Rate: 1, Burst: 2},
+		"/login":         {Rate: 0.5, Burst: 1},
+	})
+	limiter.now = func() time.Time { return now }
+
+	t.Run("Burst then limited", func(t *testing.T) {
+		allowed, _, rule := limiter.Allow("POST", "/products", "user:1")
+		assert.True(t, allowed)
+		assert.Equal(t, "POST /products", rule)
+
+		allowed, _, _ = limiter.Allow("POST", "/products", "user:1")
+		assert.True(t, allowed)
+
+		allowed, retryAfter, _ := limiter.Allow("POST", "/products", "user:1")
+		assert.False(t, allowed)
+		assert.Equal(t, time.Second, retryAfter)
+	})
+
+	t.Run("Keys are independent", func(t *testing.T) {
+		allowed, _, _ := limiter.Allow("POST", "/products", "user:2")
+		assert.True(t, allowed)
+	})
+
+	t.Run("Tokens refill over time", func(t *testing.T) {
+		now = now.Add(1500 * time.Millisecond)
+		allowed, _, _ := limiter.Allow("POST", "/products", "user:1")
+		assert.True(t, allowed)
+
+		allowed, retryAfter, _ := limiter.Allow("POST", "/products", "user:1")
+		assert.False(t, allowed)
+		assert.Equal(t, 500*time.Millisecond, retryAfter)
+	})
+
+	t.Run("Route rule without method", func(t *testing.T) {
+		allowed, _, rule := limiter.Allow("POST", "/login", "ip:10.0.0.1")
+		assert.True(t, allowed)
+		assert.Equal(t, "/login", rule)
+
+		allowed, retryAfter, _ := limiter.Allow("POST", "/login", "ip:10.0.0.1")
+		assert.False(t, allowed)
+		assert.Equal(t, 2*time.Second, retryAfter)
+	})
+
+	t.Run("No rule", func(t *testing.T) {
+		allowed, retryAfter, rule := limiter.Allow("GET", "/pvz", "user:1")
+		assert.True(t, allowed)
+		assert.Zero(t, retryAfter)
+		assert.Empty(t, rule)
+	})
+}
+
+func TestLimiterDefaultRuleAndCleanup(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(map[string]Rule{DefaultRoute: {Rate: 10, Burst: 1}})
+	limiter.now = func() time.Time { return now }
+
+	allowed, _, rule := limiter.Allow("GET", "/pvz", "user:1")
+	assert.True(t, allowed)
+	assert.Equal(t, DefaultRoute, rule)
+	assert.Len(t, limiter.buckets, 1)
+
+	now = now.Add(2 * cleanupInterval)
+	limiter.Allow("GET", "/pvz", "user:2")
+	assert.Len(t, limiter.buckets, 1, "Заполненная корзина простаивающего ключа должна быть удалена")
+}
+
+func TestLimiterEnabled(t *testing.T) {
+	var nilLimiter *Limiter
+	assert.False(t, nilLimiter.Enabled())
+	assert.False(t, NewLimiter(nil).Enabled())
+	assert.True(t, NewLimiter(map[string]Rule{"*": {Rate: 1, Burst: 1}}).Enabled())
+}
+
+func TestParseRules(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		rules, err := ParseRules("POST /products=5:10, /login=0.5:3,*=20:40")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Rule{
+			"POST /products": {Rate: 5, Burst: 10},
+			"/login":         {Rate: 0.5, Burst: 3},
+			"*":              {Rate: 20, Burst: 40},
+		}, rules)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		rules, err := ParseRules("")
+		assert.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	for _, input := range []string{"POST /products", "/login=abc:1", "/login=1:0", "/login=1"} {
+		t.Run("Invalid "+input, func(t *testing.T) {
+			_, err := ParseRules(input)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/logger"
+	"pvz/internal/models"
+)
+
+const (
+	defaultIntakePeriod = 30 * 24 * time.Hour
+	maxIntakeBuckets    = 2000
+)
+
+var intakeBuckets = map[string]time.Duration{
+	models.BucketHour: time.Hour,
+	models.BucketDay:  24 * time.Hour,
+	models.BucketWeek: 7 * 24 * time.Hour,
+}
+
+// IntakeAnalytics возвращает объем приёмки по интервалам; доступна только модераторам.
+// По умолчанию берутся последние 30 дней по дням с разбивкой по городам.
+func (s *Service) IntakeAnalytics(ctx context.Context, role, fromStr, toStr, bucket string, groupBy []string, city, pvzIdStr string) (rows []models.IntakeRow, err error) {
+	if role != "moderator" {
+		return nil, ErrForbidden
+	}
+	query := models.IntakeQuery{Bucket: bucket, City: city, To: time.Now()}
+	if toStr != "" {
+		if query.To, err = time.Parse(time.RFC3339, toStr); err != nil {
+			return nil, invalidRequest("неверный формат to, ожидается RFC3339")
+		}
+	}
+	query.From = query.To.Add(-defaultIntakePeriod)
+	if fromStr != "" {
+		if query.From, err = time.Parse(time.RFC3339, fromStr); err != nil {
+			return nil, invalidRequest("неверный формат from, ожидается RFC3339")
+		}
+	}
+	if !query.From.Before(query.To) {
+		return nil, invalidRequest("from должен быть раньше to")
+	}
+	if query.Bucket == "" {
+		query.Bucket = models.BucketDay
+	}
+	bucketSize, ok := intakeBuckets[query.Bucket]
+	if !ok {
+		return nil, invalidRequest("bucket должен быть hour, day или week")
+	}
+	if query.To.Sub(query.From)/bucketSize > maxIntakeBuckets {
+		return nil, invalidRequest(fmt.Sprintf("слишком большой период для разбиения по %s", query.Bucket))
+	}
+
+	if len(groupBy) == 0 {
+		groupBy = []string{models.GroupByCity}
+	}
+	requested := make(map[string]bool)
+	for _, g := range groupBy {
+		g = strings.TrimSpace(g)
+		if g != models.GroupByCity && g != models.GroupByPVZ && g != models.GroupByProductType {
+			return nil, invalidRequest(fmt.Sprintf("неизвестная группировка %q", g))
+		}
+		requested[g] = true
+	}
+	for _, g := range []string{models.GroupByCity, models.GroupByPVZ, models.GroupByProductType} {
+		if requested[g] {
+			query.GroupBy = append(query.GroupBy, g)
+		}
+	}
+
+	if city != "" && city != "Москва" && city != "Санкт-Петербург" && city != "Казань" {
+		return nil, ErrUnknownCity
+	}
+	if pvzIdStr != "" {
+		pvzId, err := uuid.Parse(pvzIdStr)
+		if err != nil {
+			return nil, invalidRequest("неверный pvzId")
+		}
+		query.PVZId = &pvzId
+	}
+
+	rows, err = s.database.IntakeAnalytics(ctx, query)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка расчета аналитики приёмки в БД")
+		return nil, dbError("ошибка расчета аналитики", err)
+	}
+	if rows == nil {
+		rows = []models.IntakeRow{}
+	}
+	return rows, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"pvz/internal/models"
+	"pvz/internal/problem"
+)
+
+func TestIntakeAnalytics(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	pvzId := uuid.New()
+
+	t.Run("success", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		svc := NewService(mdb, []byte("secret"))
+		expected := []models.IntakeRow{{Bucket: from, City: "Казань", ProductType: "обувь", Receptions: 1, Products: 3}}
+		mdb.On("IntakeAnalytics", ctx, models.IntakeQuery{
+			From:    from,
+			To:      to,
+			Bucket:  models.BucketHour,
+			GroupBy: []string{models.GroupByCity, models.GroupByPVZ, models.GroupByProductType},
+			City:    "Казань",
+			PVZId:   &pvzId,
+		}).Return(expected, nil)
+
+		rows, err := svc.IntakeAnalytics(ctx, "moderator", from.Format(time.RFC3339), to.Format(time.RFC3339), models.BucketHour,
+			[]string{"product_type", " pvz", "city", "city"}, "Казань", pvzId.String())
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rows)
+		mdb.AssertExpectations(t)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		svc := NewService(mdb, []byte("secret"))
+		mdb.On("IntakeAnalytics", ctx, mock.MatchedBy(func(q models.IntakeQuery) bool {
+			return q.Bucket == models.BucketDay &&
+				assert.ObjectsAreEqual([]string{models.GroupByCity}, q.GroupBy) &&
+				q.To.Sub(q.From) == 30*24*time.Hour &&
+				time.Since(q.To) < time.Minute
+		})).Return(nil, nil)
+
+		rows, err := svc.IntakeAnalytics(ctx, "moderator", "", "", "", nil, "", "")
+		assert.NoError(t, err)
+		assert.NotNil(t, rows, "пустой результат сериализуется как []")
+		assert.Empty(t, rows)
+		mdb.AssertExpectations(t)
+	})
+
+	t.Run("forbidden for employee", func(t *testing.T) {
+		svc := NewService(new(MockDatabase), []byte("secret"))
+		_, err := svc.IntakeAnalytics(ctx, "employee", "", "", "", nil, "", "")
+		assert.Equal(t, http.StatusForbidden, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "доступ запрещен")
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		svc := NewService(new(MockDatabase), []byte("secret"))
+		for _, tt := range []struct {
+			from, to, bucket string
+			groupBy          []string
+			city, pvzId      string
+			expectedErr      string
+		}{
+			{from: "2025-04-01", expectedErr: "неверный формат from, ожидается RFC3339"},
+			{to: "now", expectedErr: "неверный формат to, ожидается RFC3339"},
+			{from: to.Format(time.RFC3339), to: from.Format(time.RFC3339), expectedErr: "from должен быть раньше to"},
+			{bucket: "month", expectedErr: "bucket должен быть hour, day или week"},
+			{from: "2020-01-01T00:00:00Z", to: "2025-01-01T00:00:00Z", bucket: "hour", expectedErr: "слишком большой период для разбиения по hour"},
+			{groupBy: []string{"region"}, expectedErr: `неизвестная группировка "region"`},
+			{city: "Тверь", expectedErr: "неизвестный город"},
+			{pvzId: "1", expectedErr: "неверный pvzId"},
+		} {
+			_, err := svc.IntakeAnalytics(ctx, "moderator", tt.from, tt.to, tt.bucket, tt.groupBy, tt.city, tt.pvzId)
+			assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
+			assert.EqualError(t, err, tt.expectedErr)
+		}
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		svc := NewService(mdb, []byte("secret"))
+		mdb.On("IntakeAnalytics", ctx, mock.Anything).Return(nil, errors.New("db error"))
+
+		_, err := svc.IntakeAnalytics(ctx, "moderator", "", "", "", nil, "", "")
+		assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "ошибка расчета аналитики: db error")
+	})
+}
@@ -4,70 +4,121 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 
 	"pvz/internal/database"
+	"pvz/internal/events"
+	"pvz/internal/logger"
 	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
 )
 
 type ServiceInterface interface {
-	DummyLogin(req *models.DummyLoginRequest) (token string, status int, err error)
-	Register(ctx context.Context, req *models.RegisterRequest) (ans *models.User, status int, err error)
-	Login(ctx context.Context, req *models.LoginRequest) (token string, status int, err error)
-	CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) (int, error)
-	ListPVZ(ctx context.Context, startDateStr string, endDateStr string, pageStr string, limitStr string) (results []*models.PVZResponse, status int, err error)
-	CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, status int, err error)
-	DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) (status int, err error)
-	CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, status int, err error)
-	AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (product *models.Product, status int, err error)
+	DummyLogin(req *models.DummyLoginRequest) (token string, err error)
+	Register(ctx context.Context, req *models.RegisterRequest) (ans *models.User, err error)
+	Login(ctx context.Context, req *models.LoginRequest) (token string, err error)
+	CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) error
+	ListPVZ(ctx context.Context, startDateStr string, endDateStr string, pageStr string, limitStr string) (results []*models.PVZResponse, err error)
+	ListPVZVersion(ctx context.Context, startDateStr string, endDateStr string, pageStr string, limitStr string) (version string, err error)
+	CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, err error)
+	DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) (err error)
+	CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, err error)
+	AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (product *models.Product, err error)
 	GetPVZ(ctx context.Context) (pvzs []*pb.PVZ, err error)
+	StreamPVZs(ctx context.Context, city, fromStr, toStr string, fn func(pvz *models.PVZ) error) (err error)
+	ExportProducts(ctx context.Context, fromStr, toStr, city, pvzIdStr string, fn func(row *models.ExportRow) error) (err error)
+	IntakeAnalytics(ctx context.Context, role, fromStr, toStr, bucket string, groupBy []string, city, pvzIdStr string) (rows []models.IntakeRow, err error)
+	GetUser(ctx context.Context, userID uuid.UUID) (user *models.User, err error)
+	ListPVZPage(ctx context.Context, page, limit int) (pvzs []models.PVZ, err error)
+	GetPVZByID(ctx context.Context, pvzId uuid.UUID) (pvz *models.PVZ, err error)
+	ListReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (recs map[uuid.UUID][]models.Reception, err error)
+	ListProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (products map[uuid.UUID][]*models.Product, err error)
+	SubscribePVZEvents(ctx context.Context, role string, pvzId uuid.UUID, lastEventID string) (sub *events.Subscription, err error)
 }
 
 type Service struct {
-	database  database.Database
-	jwtSecret []byte
+	database      database.Database
+	jwtSecret     []byte
+	tokenTTL      time.Duration
+	dummyTokenTTL time.Duration
+	cities        sync.Map
+	// openCities хранит города, для которых метрика открытых приёмок уже выставлялась.
+	openCities sync.Map
+	feed       *events.Feed
 }
 
-func NewService(db database.Database, jwtSecret []byte) *Service {
-	return &Service{database: db, jwtSecret: jwtSecret}
+type Option func(s *Service)
+
+func WithFeed(feed *events.Feed) Option {
+	return func(s *Service) {
+		s.feed = feed
+	}
+}
+
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.tokenTTL = ttl
+	}
+}
+
+func WithDummyTokenTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.dummyTokenTTL = ttl
+	}
+}
+
+func NewService(db database.Database, jwtSecret []byte, opts ...Option) *Service {
+	s := &Service{database: db, jwtSecret: jwtSecret, tokenTTL: 72 * time.Hour, dummyTokenTTL: 72 * time.Hour}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *Service) generateToken(userID uuid.UUID, role string) (string, error) {
+func (s *Service) generateToken(userID uuid.UUID, role string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"id":   userID.String(),
 		"role": role,
-		"exp":  time.Now().Add(72 * time.Hour).Unix(),
+		"exp":  time.Now().Add(ttl).Unix(),
 		"iat":  time.Now().Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
 	return token.SignedString(s.jwtSecret)
 }
 
-func (s *Service) DummyLogin(req *models.DummyLoginRequest) (token string, status int, err error) {
+// IssueToken выпускает токен для существующего пользователя в обход пароля; используется pvzctl.
+func (s *Service) IssueToken(userID uuid.UUID, role string) (string, error) {
+	if role != "employee" && role != "moderator" {
+		return "", ErrInvalidRole
+	}
+	return s.generateToken(userID, role, s.tokenTTL)
+}
+
+func (s *Service) DummyLogin(req *models.DummyLoginRequest) (token string, err error) {
 	if req.Role != "employee" && req.Role != "moderator" {
-		return "", http.StatusBadRequest, errors.New("неверная роль")
+		return "", ErrInvalidRole
 	}
 	userID := uuid.New()
-	token, err = s.generateToken(userID, req.Role)
+	token, err = s.generateToken(userID, req.Role, s.dummyTokenTTL)
 	if err != nil {
-		return "", http.StatusInternalServerError, errors.New("ошибка генерации токена")
+		return "", fmt.Errorf("ошибка генерации токена: %w", err)
 	}
-	return token, http.StatusOK, nil
+	return token, nil
 }
 
-func (s *Service) Register(ctx context.Context, req *models.RegisterRequest) (ans *models.User, status int, err error) {
+func (s *Service) Register(ctx context.Context, req *models.RegisterRequest) (ans *models.User, err error) {
 	if req.Role != "employee" && req.Role != "moderator" {
-		return ans, http.StatusBadRequest, errors.New("неверная роль")
+		return ans, ErrInvalidRole
 	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return ans, http.StatusInternalServerError, errors.New("ошибка хэширования пароля")
+		return ans, fmt.Errorf("ошибка хэширования пароля: %w", err)
 	}
 	user := models.User{
 		Email:    req.Email,
@@ -75,27 +126,55 @@ func (s *Service) Register(ctx context.Context, req *models.RegisterRequest) (an
 		Role:     req.Role,
 	}
 	if err := s.database.CreateUser(ctx, &user); err != nil {
-		return ans, http.StatusBadRequest, errors.New(fmt.Sprintf("ошибка регистрации: %v", err))
+		logger.FromContext(ctx).WithError(err).Error("Ошибка создания пользователя в БД")
+		if errors.Is(err, database.ErrAlreadyExists) {
+			return ans, ErrUserAlreadyExists.Wrap(err)
+		}
+		return ans, dbError("ошибка регистрации", err)
 	}
 	ans = &models.User{
 		ID:    user.ID,
 		Email: user.Email,
 		Role:  user.Role,
 	}
-	return ans, http.StatusOK, nil
+	logger.FromContext(ctx).WithFields(logrus.Fields{
+		"user_id": ans.ID,
+		"role":    ans.Role,
+	}).Info("Пользователь зарегистрирован")
+	return ans, nil
 }
 
-func (s *Service) Login(ctx context.Context, req *models.LoginRequest) (token string, status int, err error) {
+func (s *Service) Login(ctx context.Context, req *models.LoginRequest) (token string, err error) {
 	user, err := s.database.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		return "", http.StatusUnauthorized, errors.New("неверные учетные данные")
+		if !errors.Is(err, database.ErrNotFound) {
+			logger.FromContext(ctx).WithError(err).Error("Ошибка получения пользователя из БД")
+			return "", dbError("ошибка входа", err)
+		}
+		logger.FromContext(ctx).WithError(err).Warn("Пользователь для входа не найден")
+		return "", ErrInvalidCredentials
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return "", http.StatusUnauthorized, errors.New("неверные учетные данные")
+		logger.FromContext(ctx).WithField("user_id", user.ID).Warn("Неверный пароль при входе")
+		return "", ErrInvalidCredentials
 	}
-	token, err = s.generateToken(user.ID, user.Role)
+	token, err = s.generateToken(user.ID, user.Role, s.tokenTTL)
+	if err != nil {
+		return "", fmt.Errorf("ошибка генерации токена: %w", err)
+	}
+	return token, nil
+}
+
+// GetUser возвращает пользователя без пароля. У пользователей из /dummyLogin записи в БД нет.
+func (s *Service) GetUser(ctx context.Context, userID uuid.UUID) (user *models.User, err error) {
+	user, err = s.database.GetUserByID(ctx, userID)
 	if err != nil {
-		return "", http.StatusInternalServerError, errors.New("ошибка генерации токена")
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrUserNotFound.Wrap(err)
+		}
+		logger.FromContext(ctx).WithError(err).Error("Ошибка получения пользователя из БД")
+		return nil, dbError("ошибка получения пользователя", err)
 	}
-	return token, http.StatusOK, nil
+	user.Password = ""
+	return user, nil
 }
@@ -8,13 +8,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
 
+	"pvz/internal/database"
 	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
+	"pvz/internal/problem"
 )
 
 type MockDatabase struct {
@@ -38,6 +41,12 @@ func (m *MockDatabase) GetUserByEmail(ctx context.Context, email string) (*model
 	return u, args.Error(1)
 }
 
+func (m *MockDatabase) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	u, _ := args.Get(0).(*models.User)
+	return u, args.Error(1)
+}
+
 func (m *MockDatabase) CreatePVZ(ctx context.Context, pvz *models.PVZ) error {
 	args := m.Called(ctx, pvz)
 	if args.Error(0) == nil {
@@ -52,6 +61,13 @@ func (m *MockDatabase) GetPVZs(ctx context.Context, limit, offset int) ([]models
 	}
 	return nil, args.Error(1)
 }
+func (m *MockDatabase) GetPVZVersions(ctx context.Context, pvzIds []uuid.UUID) ([]models.PVZVersion, error) {
+	args := m.Called(ctx, pvzIds)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.PVZVersion), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
 func (m *MockDatabase) GetReceptionsByPVZ(ctx context.Context, pvzId uuid.UUID, startDate, endDate *time.Time) ([]models.Reception, error) {
 	args := m.Called(ctx, pvzId, startDate, endDate)
 	if args.Get(0) != nil {
@@ -66,6 +82,16 @@ func (m *MockDatabase) GetProductsByReception(ctx context.Context, receptionID u
 	}
 	return nil, args.Error(1)
 }
+func (m *MockDatabase) GetReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) ([]models.Reception, error) {
+	args := m.Called(ctx, pvzIds, startDate, endDate)
+	recs, _ := args.Get(0).([]models.Reception)
+	return recs, args.Error(1)
+}
+func (m *MockDatabase) GetProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) ([]*models.Product, error) {
+	args := m.Called(ctx, receptionIds)
+	products, _ := args.Get(0).([]*models.Product)
+	return products, args.Error(1)
+}
 func (m *MockDatabase) CloseLastReception(ctx context.Context, pvzId uuid.UUID) (*models.Reception, error) {
 	args := m.Called(ctx, pvzId)
 	if rec, ok := args.Get(0).(*models.Reception); ok {
@@ -100,25 +126,113 @@ func (m *MockDatabase) GetPVZ(ctx context.Context) ([]*pb.PVZ, error) {
 	return nil, args.Error(1)
 }
 
+func (m *MockDatabase) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (*models.PVZ, error) {
+	args := m.Called(ctx, pvzId)
+	if pvz, ok := args.Get(0).(*models.PVZ); ok {
+		return pvz, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockDatabase) CountProductsByReception(ctx context.Context, receptionID uuid.UUID) (int, error) {
+	args := m.Called(ctx, receptionID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDatabase) CountOpenReceptionsByCity(ctx context.Context) (map[string]int, error) {
+	args := m.Called(ctx)
+	if counts, ok := args.Get(0).(map[string]int); ok {
+		return counts, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// ExportProducts передает в fn строки, заданные первым аргументом Return.
+func (m *MockDatabase) StreamPVZs(ctx context.Context, filter models.PVZFilter, fn func(pvz *models.PVZ) error) error {
+	args := m.Called(ctx, filter)
+	if pvzs, ok := args.Get(0).([]models.PVZ); ok {
+		for i := range pvzs {
+			if err := fn(&pvzs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockDatabase) ExportProducts(ctx context.Context, filter models.ExportFilter, fn func(row *models.ExportRow) error) error {
+	args := m.Called(ctx, filter)
+	if rows, ok := args.Get(0).([]models.ExportRow); ok {
+		for i := range rows {
+			if err := fn(&rows[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockDatabase) IntakeAnalytics(ctx context.Context, query models.IntakeQuery) ([]models.IntakeRow, error) {
+	args := m.Called(ctx, query)
+	rows, _ := args.Get(0).([]models.IntakeRow)
+	return rows, args.Error(1)
+}
+
+func (m *MockDatabase) AutoCloseReceptions(ctx context.Context, now time.Time, limits models.AutoCloseLimits) ([]models.AutoClosedReception, bool, error) {
+	args := m.Called(ctx, now, limits)
+	closed, _ := args.Get(0).([]models.AutoClosedReception)
+	return closed, args.Bool(1), args.Error(2)
+}
+
 func TestDummyLogin(t *testing.T) {
 	jwtSecret := []byte("testsecret")
 
 	svc := NewService(nil, jwtSecret)
 
 	t.Run("invalid role", func(t *testing.T) {
-		token, status, err := svc.DummyLogin(&models.DummyLoginRequest{Role: "admin"})
+		token, err := svc.DummyLogin(&models.DummyLoginRequest{Role: "admin"})
 		assert.Empty(t, token)
-		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "неверная роль")
 	})
 
 	t.Run("valid role", func(t *testing.T) {
-		token, status, err := svc.DummyLogin(&models.DummyLoginRequest{Role: "employee"})
+		token, err := svc.DummyLogin(&models.DummyLoginRequest{Role: "employee"})
 		assert.NotEmpty(t, token)
 		assert.GreaterOrEqual(t, strings.Count(token, "."), 2, "token should be a JWT")
-		assert.Equal(t, http.StatusOK, status)
 		assert.NoError(t, err)
 	})
+
+	t.Run("custom ttl", func(t *testing.T) {
+		svc := NewService(nil, jwtSecret, WithDummyTokenTTL(time.Hour))
+		token, err := svc.DummyLogin(&models.DummyLoginRequest{Role: "moderator"})
+		assert.NoError(t, err)
+		claims := jwt.MapClaims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+			return jwtSecret, nil
+		})
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(time.Hour).Unix(), claims["exp"], 5)
+	})
+}
+
+func TestIssueToken(t *testing.T) {
+	jwtSecret := []byte("testsecret")
+	svc := NewService(nil, jwtSecret, WithTokenTTL(time.Hour))
+	userID := uuid.New()
+
+	token, err := svc.IssueToken(userID, "moderator")
+	assert.NoError(t, err)
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, userID.String(), claims["id"])
+	assert.Equal(t, "moderator", claims["role"])
+
+	_, err = svc.IssueToken(userID, "admin")
+	assert.EqualError(t, err, "неверная роль")
 }
 
 func TestRegister(t *testing.T) {
@@ -128,13 +242,13 @@ func TestRegister(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("invalid role", func(t *testing.T) {
-		user, status, err := svc.Register(ctx, &models.RegisterRequest{
+		user, err := svc.Register(ctx, &models.RegisterRequest{
 			Email:    "test@example.com",
 			Password: "password123",
 			Role:     "admin",
 		})
 		assert.Nil(t, user)
-		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "неверная роль")
 	})
 
@@ -146,13 +260,28 @@ func TestRegister(t *testing.T) {
 		}
 		mockDB.On("CreateUser", ctx, mock.AnythingOfType("*models.User")).Return(errors.New("db error")).Once()
 
-		user, status, err := svc.Register(ctx, req)
+		user, err := svc.Register(ctx, req)
 		assert.Nil(t, user)
-		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "ошибка регистрации: db error")
 		mockDB.AssertExpectations(t)
 	})
 
+	t.Run("email already taken", func(t *testing.T) {
+		req := &models.RegisterRequest{
+			Email:    "taken@example.com",
+			Password: "password123",
+			Role:     "employee",
+		}
+		mockDB.On("CreateUser", ctx, mock.AnythingOfType("*models.User")).Return(database.ErrAlreadyExists).Once()
+
+		user, err := svc.Register(ctx, req)
+		assert.Nil(t, user)
+		assert.ErrorIs(t, err, ErrUserAlreadyExists)
+		assert.Equal(t, http.StatusConflict, problem.From(err).Code.HTTPStatus())
+		mockDB.AssertExpectations(t)
+	})
+
 	t.Run("success", func(t *testing.T) {
 		req := &models.RegisterRequest{
 			Email:    "success@example.com",
@@ -163,9 +292,8 @@ func TestRegister(t *testing.T) {
 			return user.Email == req.Email && user.Role == req.Role && user.Password != req.Password
 		})).Return(nil).Once()
 
-		user, status, err := svc.Register(ctx, req)
+		user, err := svc.Register(ctx, req)
 		assert.NotNil(t, user)
-		assert.Equal(t, http.StatusOK, status)
 		assert.NoError(t, err)
 		assert.Equal(t, req.Email, user.Email)
 		assert.Equal(t, req.Role, user.Role)
@@ -190,14 +318,14 @@ func TestLogin(t *testing.T) {
 	}
 
 	t.Run("user not found", func(t *testing.T) {
-		mockDB.On("GetUserByEmail", ctx, "notfound@example.com").Return((*models.User)(nil), errors.New("not found")).Once()
+		mockDB.On("GetUserByEmail", ctx, "notfound@example.com").Return((*models.User)(nil), database.ErrNotFound).Once()
 
-		token, status, err := svc.Login(ctx, &models.LoginRequest{
+		token, err := svc.Login(ctx, &models.LoginRequest{
 			Email:    "notfound@example.com",
 			Password: plainPassword,
 		})
 		assert.Empty(t, token)
-		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, http.StatusUnauthorized, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "неверные учетные данные")
 		mockDB.AssertExpectations(t)
 	})
@@ -205,12 +333,12 @@ func TestLogin(t *testing.T) {
 	t.Run("invalid password", func(t *testing.T) {
 		mockDB.On("GetUserByEmail", ctx, "login@example.com").Return(testUser, nil).Once()
 
-		token, status, err := svc.Login(ctx, &models.LoginRequest{
+		token, err := svc.Login(ctx, &models.LoginRequest{
 			Email:    "login@example.com",
 			Password: "wrongpassword",
 		})
 		assert.Empty(t, token)
-		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, http.StatusUnauthorized, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "неверные учетные данные")
 		mockDB.AssertExpectations(t)
 	})
@@ -218,14 +346,31 @@ func TestLogin(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockDB.On("GetUserByEmail", ctx, "login@example.com").Return(testUser, nil).Once()
 
-		token, status, err := svc.Login(ctx, &models.LoginRequest{
+		token, err := svc.Login(ctx, &models.LoginRequest{
 			Email:    "login@example.com",
 			Password: plainPassword,
 		})
 		assert.NotEmpty(t, token)
-		assert.Equal(t, http.StatusOK, status)
 		assert.NoError(t, err)
 		assert.GreaterOrEqual(t, strings.Count(token, "."), 2, "token should be a JWT")
 		mockDB.AssertExpectations(t)
 	})
 }
+
+func TestGetUser(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	mdb := new(MockDatabase)
+	mdb.On("GetUserByID", ctx, id).Return(&models.User{ID: id, Email: "mod@example.com", Password: "hash", Role: "moderator"}, nil).Once()
+	svc := NewService(mdb, []byte("secret"))
+	user, err := svc.GetUser(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "mod@example.com", user.Email)
+	assert.Empty(t, user.Password, "пароль не возвращается")
+
+	mdb.On("GetUserByID", ctx, id).Return(nil, database.ErrNotFound).Once()
+	_, err = svc.GetUser(ctx, id)
+	assert.Equal(t, http.StatusNotFound, problem.From(err).Code.HTTPStatus())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"pvz/internal/database"
+	"pvz/internal/problem"
+)
+
+// Ошибки сервиса. Транспорт выбирает по их коду статус ответа: HTTP-статус в REST
+// и код статуса в gRPC.
+var (
+	ErrForbidden            = problem.New(problem.CodeForbidden, "доступ запрещен")
+	ErrInvalidRole          = problem.New(problem.CodeInvalidRole, "неверная роль")
+	ErrInvalidCredentials   = problem.New(problem.CodeInvalidCredentials, "неверные учетные данные")
+	ErrUnknownCity          = problem.New(problem.CodeUnknownCity, "неизвестный город")
+	ErrPVZNotFound          = problem.New(problem.CodePVZNotFound, "ПВЗ не найден")
+	ErrUserNotFound         = problem.New(problem.CodeUserNotFound, "пользователь не найден")
+	ErrUserAlreadyExists    = problem.New(problem.CodeUserAlreadyExists, "пользователь с таким email уже существует")
+	ErrReceptionAlreadyOpen = problem.New(problem.CodeReceptionAlreadyOpen, "у ПВЗ уже есть открытая приёмка")
+	ErrNoActiveReception    = problem.New(problem.CodeNoActiveReception, "у ПВЗ нет открытой приёмки")
+	ErrNoProductToDelete    = problem.New(problem.CodeNoProductToDelete, "в открытой приёмке нет товаров")
+	ErrFeedDisabled         = problem.New(problem.CodeFeedDisabled, "лента событий выключена")
+	ErrUnavailable          = problem.New(problem.CodeUnavailable, "база данных временно недоступна")
+	ErrNotFound             = problem.New(problem.CodeNotFound, "запись не найдена")
+	ErrConflict             = problem.New(problem.CodeConflict, "запись уже существует")
+)
+
+func invalidRequest(message string) error {
+	return problem.New(problem.CodeInvalidRequest, message)
+}
+
+// dbError переводит ошибку БД в ошибку сервиса, op описывает операцию для журнала.
+// ErrNotFound и ErrAlreadyExists становятся общими NOT_FOUND и CONFLICT; если операции
+// нужен более точный код, например PVZ_NOT_FOUND, она проверяет ошибку до вызова dbError.
+// Прочие ошибки остаются внутренними, и их текст клиенту не показывается.
+func dbError(op string, err error) error {
+	err = fmt.Errorf("%s: %w", op, err)
+	switch {
+	case errors.Is(err, database.ErrUnavailable):
+		return ErrUnavailable.Wrap(err)
+	case errors.Is(err, database.ErrNotFound):
+		return ErrNotFound.Wrap(err)
+	case errors.Is(err, database.ErrAlreadyExists):
+		return ErrConflict.Wrap(err)
+	case errors.Is(err, database.ErrReceptionAlreadyOpen):
+		return ErrReceptionAlreadyOpen.Wrap(err)
+	case errors.Is(err, database.ErrNoActiveReception):
+		return ErrNoActiveReception.Wrap(err)
+	case errors.Is(err, database.ErrNoProductToDelete):
+		return ErrNoProductToDelete.Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/database"
+	"pvz/internal/problem"
+)
+
+func TestDBError(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   problem.Code
+		status int
+	}{
+		{database.ErrNotFound, problem.CodeNotFound, http.StatusNotFound},
+		{database.ErrAlreadyExists, problem.CodeConflict, http.StatusConflict},
+		{database.ErrUnavailable, problem.CodeUnavailable, http.StatusServiceUnavailable},
+		{database.ErrReceptionAlreadyOpen, problem.CodeReceptionAlreadyOpen, http.StatusConflict},
+		{database.ErrNoActiveReception, problem.CodeNoActiveReception, http.StatusConflict},
+		{database.ErrNoProductToDelete, problem.CodeNoProductToDelete, http.StatusConflict},
+		{errors.New("syntax error"), problem.CodeInternal, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			err := dbError("операция", fmt.Errorf("%w: no rows in result set", tt.err))
+			assert.Equal(t, tt.code, problem.From(err).Code)
+			assert.Equal(t, tt.status, problem.From(err).Code.HTTPStatus())
+			assert.ErrorIs(t, err, tt.err, "причина остается доступной для журнала")
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/logger"
+	"pvz/internal/models"
+)
+
+// ExportProducts проверяет параметры выгрузки и передает подходящие товары в fn по мере чтения из БД.
+// Ошибка fn прерывает выгрузку и возвращается как есть.
+func (s *Service) ExportProducts(ctx context.Context, fromStr, toStr, city, pvzIdStr string, fn func(row *models.ExportRow) error) (err error) {
+	var filter models.ExportFilter
+	if fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return invalidRequest("неверный формат from, ожидается RFC3339")
+		}
+		filter.From = &from
+	}
+	if toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return invalidRequest("неверный формат to, ожидается RFC3339")
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return invalidRequest("from не может быть позже to")
+	}
+	if city != "" && city != "Москва" && city != "Санкт-Петербург" && city != "Казань" {
+		return ErrUnknownCity
+	}
+	filter.City = city
+	if pvzIdStr != "" {
+		pvzId, err := uuid.Parse(pvzIdStr)
+		if err != nil {
+			return invalidRequest("неверный pvzId")
+		}
+		filter.PVZId = &pvzId
+	}
+
+	var fnErr error
+	err = s.database.ExportProducts(ctx, filter, func(row *models.ExportRow) error {
+		fnErr = fn(row)
+		return fnErr
+	})
+	if err != nil {
+		if fnErr != nil {
+			return fnErr
+		}
+		logger.FromContext(ctx).WithError(err).Error("Ошибка выгрузки товаров из БД")
+		return dbError("ошибка выгрузки товаров", err)
+	}
+	return nil
+}
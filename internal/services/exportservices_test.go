@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"pvz/internal/models"
+	"pvz/internal/problem"
+)
+
+func TestExportProducts(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	rows := []models.ExportRow{
+		{PVZ: models.PVZ{ID: pvzId, City: "Москва"}, Product: models.Product{Type: "обувь"}},
+		{PVZ: models.PVZ{ID: pvzId, City: "Москва"}, Product: models.Product{Type: "одежда"}},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		svc := NewService(mdb, []byte("secret"))
+		mdb.On("ExportProducts", ctx, models.ExportFilter{From: &from, To: &to, City: "Москва", PVZId: &pvzId}).Return(rows, nil)
+
+		var got []string
+		err := svc.ExportProducts(ctx, from.Format(time.RFC3339), to.Format(time.RFC3339), "Москва", pvzId.String(), func(row *models.ExportRow) error {
+			got = append(got, row.Product.Type)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"обувь", "одежда"}, got)
+		mdb.AssertExpectations(t)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		svc := NewService(new(MockDatabase), []byte("secret"))
+		for _, tt := range []struct {
+			from, to, city, pvzId string
+			expectedErr           string
+		}{
+			{from: "2025-04-01", expectedErr: "неверный формат from, ожидается RFC3339"},
+			{to: "tomorrow", expectedErr: "неверный формат to, ожидается RFC3339"},
+			{from: to.Format(time.RFC3339), to: from.Format(time.RFC3339), expectedErr: "from не может быть позже to"},
+			{city: "Новосибирск", expectedErr: "неизвестный город"},
+			{pvzId: "42", expectedErr: "неверный pvzId"},
+		} {
+			err := svc.ExportProducts(ctx, tt.from, tt.to, tt.city, tt.pvzId, func(*models.ExportRow) error { return nil })
+			assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
+			assert.EqualError(t, err, tt.expectedErr)
+		}
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		svc := NewService(mdb, []byte("secret"))
+		mdb.On("ExportProducts", ctx, mock.Anything).Return(nil, errors.New("db error"))
+
+		err := svc.ExportProducts(ctx, "", "", "", "", func(*models.ExportRow) error { return nil })
+		assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "ошибка выгрузки товаров: db error")
+	})
+
+	t.Run("writer error is returned as is", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		svc := NewService(mdb, []byte("secret"))
+		mdb.On("ExportProducts", ctx, mock.Anything).Return(rows, nil)
+		writeErr := errors.New("broken pipe")
+
+		err := svc.ExportProducts(ctx, "", "", "", "", func(*models.ExportRow) error { return writeErr })
+		assert.ErrorIs(t, err, writeErr)
+	})
+}
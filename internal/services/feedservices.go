@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/database"
+	"pvz/internal/events"
+	"pvz/internal/logger"
+)
+
+// SubscribePVZEvents подписывает на события приёмки ПВЗ. lastEventID — значение
+// заголовка Last-Event-ID, пустое для новой подписки. Подписку нужно закрыть.
+func (s *Service) SubscribePVZEvents(ctx context.Context, role string, pvzId uuid.UUID, lastEventID string) (sub *events.Subscription, err error) {
+	if role != "employee" && role != "moderator" {
+		return nil, ErrForbidden
+	}
+	if s.feed == nil {
+		return nil, ErrFeedDisabled
+	}
+	var lastID *int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			return nil, invalidRequest("неверный Last-Event-ID")
+		}
+		lastID = &id
+	}
+	if _, err := s.database.GetPVZByID(ctx, pvzId); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrPVZNotFound.Wrap(err)
+		}
+		logger.FromContext(ctx).WithError(err).Error("Ошибка получения ПВЗ из БД")
+		return nil, dbError("ошибка получения ПВЗ", err)
+	}
+	return s.feed.Subscribe(pvzId, lastID), nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/events"
+	"pvz/internal/models"
+	"pvz/internal/problem"
+)
+
+func TestSubscribePVZEvents(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	t.Run("forbidden role", func(t *testing.T) {
+		svc := NewService(nil, []byte("unused"), WithFeed(events.NewFeed(8)))
+		_, err := svc.SubscribePVZEvents(ctx, "guest", pvzId, "")
+		assert.Equal(t, http.StatusForbidden, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "доступ запрещен")
+	})
+
+	t.Run("invalid Last-Event-ID", func(t *testing.T) {
+		svc := NewService(nil, []byte("unused"), WithFeed(events.NewFeed(8)))
+		_, err := svc.SubscribePVZEvents(ctx, "employee", pvzId, "abc")
+		assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "неверный Last-Event-ID")
+	})
+
+	t.Run("pvz not found", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("GetPVZByID", ctx, pvzId).Return(nil, database.ErrNotFound).Once()
+		svc := NewService(mockDB, []byte("unused"), WithFeed(events.NewFeed(8)))
+		_, err := svc.SubscribePVZEvents(ctx, "moderator", pvzId, "")
+		assert.Equal(t, http.StatusNotFound, problem.From(err).Code.HTTPStatus())
+		assert.ErrorIs(t, err, ErrPVZNotFound)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("GetPVZByID", ctx, pvzId).Return(nil, errors.New("db down")).Once()
+		svc := NewService(mockDB, []byte("unused"), WithFeed(events.NewFeed(8)))
+		_, err := svc.SubscribePVZEvents(ctx, "moderator", pvzId, "")
+		assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		feed := events.NewFeed(8)
+		feed.Publish(models.OutboxEvent{ID: 7, PVZId: pvzId})
+		feed.Publish(models.OutboxEvent{ID: 8, PVZId: pvzId})
+		mockDB := new(MockDatabase)
+		mockDB.On("GetPVZByID", ctx, pvzId).Return(&models.PVZ{ID: pvzId}, nil).Once()
+		svc := NewService(mockDB, []byte("unused"), WithFeed(feed))
+
+		sub, err := svc.SubscribePVZEvents(ctx, "employee", pvzId, "7")
+		require.NoError(t, err)
+		defer sub.Close()
+		require.Len(t, sub.Backlog, 1)
+		assert.Equal(t, int64(8), sub.Backlog[0].ID)
+		mockDB.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
+)
+
+const unknownCity = "unknown"
+
+func (s *Service) cityOf(ctx context.Context, pvzId uuid.UUID) string {
+	if city, ok := s.cities.Load(pvzId); ok {
+		return city.(string)
+	}
+	pvz, err := s.database.GetPVZByID(ctx, pvzId)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Warn("Не удалось определить город ПВЗ для метрик")
+		return unknownCity
+	}
+	s.cities.Store(pvzId, pvz.City)
+	return pvz.City
+}
+
+// RefreshOpenReceptions выставляет метрику открытых приёмок по данным БД. Это
+// единственный источник значений метрики: города, в которых открытых приёмок
+// не осталось, получают 0.
+func (s *Service) RefreshOpenReceptions(ctx context.Context) error {
+	counts, err := s.database.CountOpenReceptionsByCity(ctx)
+	if err != nil {
+		return err
+	}
+	s.openCities.Range(func(city, _ interface{}) bool {
+		if _, ok := counts[city.(string)]; !ok {
+			metrics.OpenReceptions.WithLabelValues(city.(string)).Set(0)
+		}
+		return true
+	})
+	for city, count := range counts {
+		s.openCities.Store(city, struct{}{})
+		metrics.OpenReceptions.WithLabelValues(city).Set(float64(count))
+	}
+	return nil
+}
@@ -3,31 +3,67 @@ package services
 import (
 	"context"
 	"errors"
-	"net/http"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 
+	"pvz/internal/database"
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
 	"pvz/internal/models"
 )
 
-func (s *Service) DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) (status int, err error) {
+func (s *Service) DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) (err error) {
 	if role != "employee" {
-		return http.StatusForbidden, errors.New("доступ запрещен")
+		return ErrForbidden
 	}
 	err = s.database.DeleteLastProduct(ctx, pvzId)
 	if err != nil {
-		return http.StatusBadRequest, errors.New("ошибка удаления товара: " + err.Error())
+		logger.FromContext(ctx).WithError(err).Error("Ошибка удаления товара в БД")
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrPVZNotFound.Wrap(err)
+		}
+		return dbError("ошибка удаления товара", err)
 	}
-	return http.StatusOK, nil
+	logger.FromContext(ctx).Info("Последний товар удалён")
+	metrics.DeletedProductsTotal.WithLabelValues(s.cityOf(ctx, pvzId)).Inc()
+	return nil
 }
 
-func (s *Service) AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (product *models.Product, status int, err error) {
+func (s *Service) AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (product *models.Product, err error) {
 	if role != "employee" {
-		return product, http.StatusForbidden, errors.New("доступ запрещен")
+		return product, ErrForbidden
+	}
+	if producttype != "электроника" && producttype != "одежда" && producttype != "обувь" {
+		return product, invalidRequest("неизвестный тип товара")
 	}
 	product, err = s.database.AddProduct(ctx, pvzId, producttype)
 	if err != nil {
-		return product, http.StatusBadRequest, errors.New("ошибка добавления товара: " + err.Error())
+		logger.FromContext(ctx).WithError(err).Error("Ошибка добавления товара в БД")
+		if errors.Is(err, database.ErrNotFound) {
+			return product, ErrPVZNotFound.Wrap(err)
+		}
+		return product, dbError("ошибка добавления товара", err)
+	}
+	logger.FromContext(ctx).WithFields(logrus.Fields{
+		"product_id":   product.ID,
+		"reception_id": product.ReceptionId,
+		"product_type": product.Type,
+	}).Info("Товар добавлен")
+	metrics.AddedProductsTotal.WithLabelValues(s.cityOf(ctx, pvzId), product.Type).Inc()
+	return product, nil
+}
+
+// ListProductsByReceptions возвращает товары нескольких приёмок одним запросом, сгруппированные по приёмке.
+func (s *Service) ListProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (products map[uuid.UUID][]*models.Product, err error) {
+	rows, err := s.database.GetProductsByReceptions(ctx, receptionIds)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка выборки товаров из БД")
+		return nil, dbError("ошибка выборки товаров", err)
+	}
+	products = make(map[uuid.UUID][]*models.Product, len(receptionIds))
+	for _, product := range rows {
+		products[product.ReceptionId] = append(products[product.ReceptionId], product)
 	}
-	return product, http.StatusOK, nil
+	return products, nil
 }
@@ -8,9 +8,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 
+	"pvz/internal/database"
+	"pvz/internal/metrics"
 	"pvz/internal/models"
+	"pvz/internal/problem"
 )
 
 func TestDeleteLastProduct(t *testing.T) {
@@ -19,8 +23,8 @@ func TestDeleteLastProduct(t *testing.T) {
 
 	t.Run("not employee", func(t *testing.T) {
 		svc := NewService(nil, []byte("unused"))
-		status, err := svc.DeleteLastProduct(ctx, "moderator", pvzId)
-		assert.Equal(t, http.StatusForbidden, status)
+		err := svc.DeleteLastProduct(ctx, "moderator", pvzId)
+		assert.Equal(t, http.StatusForbidden, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "доступ запрещен")
 	})
 
@@ -28,19 +32,41 @@ func TestDeleteLastProduct(t *testing.T) {
 		mockDB := new(MockDatabase)
 		mockDB.On("DeleteLastProduct", ctx, pvzId).Return(errors.New("db error")).Once()
 		svc := NewService(mockDB, []byte("unused"))
-		status, err := svc.DeleteLastProduct(ctx, "employee", pvzId)
-		assert.Equal(t, http.StatusBadRequest, status)
+		err := svc.DeleteLastProduct(ctx, "employee", pvzId)
+		assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "ошибка удаления товара: db error")
 		mockDB.AssertExpectations(t)
 	})
 
+	t.Run("unknown pvz", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("DeleteLastProduct", ctx, pvzId).Return(database.ErrNotFound).Once()
+		svc := NewService(mockDB, []byte("unused"))
+		err := svc.DeleteLastProduct(ctx, "employee", pvzId)
+		assert.ErrorIs(t, err, ErrPVZNotFound)
+		assert.Equal(t, http.StatusNotFound, problem.From(err).Code.HTTPStatus())
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("no product to delete", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("DeleteLastProduct", ctx, pvzId).Return(database.ErrNoProductToDelete).Once()
+		svc := NewService(mockDB, []byte("unused"))
+		err := svc.DeleteLastProduct(ctx, "employee", pvzId)
+		assert.ErrorIs(t, err, ErrNoProductToDelete)
+		assert.Equal(t, http.StatusConflict, problem.From(err).Code.HTTPStatus())
+		mockDB.AssertExpectations(t)
+	})
+
 	t.Run("success", func(t *testing.T) {
 		mockDB := new(MockDatabase)
 		mockDB.On("DeleteLastProduct", ctx, pvzId).Return(nil).Once()
+		mockDB.On("GetPVZByID", ctx, pvzId).Return(&models.PVZ{ID: pvzId, City: "Казань"}, nil).Once()
 		svc := NewService(mockDB, []byte("unused"))
-		status, err := svc.DeleteLastProduct(ctx, "employee", pvzId)
-		assert.Equal(t, http.StatusOK, status)
+		before := testutil.ToFloat64(metrics.DeletedProductsTotal.WithLabelValues("Казань"))
+		err := svc.DeleteLastProduct(ctx, "employee", pvzId)
 		assert.NoError(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.DeletedProductsTotal.WithLabelValues("Казань")))
 		mockDB.AssertExpectations(t)
 	})
 }
@@ -48,23 +74,41 @@ func TestDeleteLastProduct(t *testing.T) {
 func TestAddProduct(t *testing.T) {
 	ctx := context.Background()
 	pvzId := uuid.New()
-	productType := "testType"
+	productType := "обувь"
 
 	t.Run("not employee", func(t *testing.T) {
 		svc := NewService(nil, []byte("unused"))
-		prod, status, err := svc.AddProduct(ctx, "moderator", pvzId, productType)
+		prod, err := svc.AddProduct(ctx, "moderator", pvzId, productType)
 		assert.Nil(t, prod)
-		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, http.StatusForbidden, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "доступ запрещен")
 	})
 
+	t.Run("unknown type", func(t *testing.T) {
+		svc := NewService(new(MockDatabase), []byte("unused"))
+		prod, err := svc.AddProduct(ctx, "employee", pvzId, "мебель")
+		assert.Nil(t, prod)
+		assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "неизвестный тип товара")
+	})
+
+	t.Run("unknown pvz", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("AddProduct", ctx, pvzId, productType).Return(nil, database.ErrNotFound).Once()
+		svc := NewService(mockDB, []byte("unused"))
+		_, err := svc.AddProduct(ctx, "employee", pvzId, productType)
+		assert.ErrorIs(t, err, ErrPVZNotFound)
+		assert.Equal(t, http.StatusNotFound, problem.From(err).Code.HTTPStatus())
+		mockDB.AssertExpectations(t)
+	})
+
 	t.Run("db error", func(t *testing.T) {
 		mockDB := new(MockDatabase)
 		mockDB.On("AddProduct", ctx, pvzId, productType).Return(nil, errors.New("db add error")).Once()
 		svc := NewService(mockDB, []byte("unused"))
-		prod, status, err := svc.AddProduct(ctx, "employee", pvzId, productType)
+		prod, err := svc.AddProduct(ctx, "employee", pvzId, productType)
 		assert.Nil(t, prod)
-		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "ошибка добавления товара: db add error")
 		mockDB.AssertExpectations(t)
 	})
@@ -77,14 +121,40 @@ func TestAddProduct(t *testing.T) {
 			ReceptionId: uuid.New(),
 		}
 		mockDB := new(MockDatabase)
-		mockDB.On("AddProduct", ctx, pvzId, productType).Return(expectedProduct, nil).Once()
+		mockDB.On("AddProduct", ctx, pvzId, productType).Return(expectedProduct, nil).Twice()
+		mockDB.On("GetPVZByID", ctx, pvzId).Return(&models.PVZ{ID: pvzId, City: "Москва"}, nil).Once()
 		svc := NewService(mockDB, []byte("unused"))
-		prod, status, err := svc.AddProduct(ctx, "employee", pvzId, productType)
+		before := testutil.ToFloat64(metrics.AddedProductsTotal.WithLabelValues("Москва", productType))
+		_, err := svc.AddProduct(ctx, "employee", pvzId, productType)
+		assert.NoError(t, err)
+		prod, err := svc.AddProduct(ctx, "employee", pvzId, productType)
+		assert.Equal(t, before+2, testutil.ToFloat64(metrics.AddedProductsTotal.WithLabelValues("Москва", productType)))
 		assert.NotNil(t, prod)
-		assert.Equal(t, http.StatusOK, status)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedProduct.ID, prod.ID)
 		assert.Equal(t, expectedProduct.Type, prod.Type)
 		mockDB.AssertExpectations(t)
 	})
 }
+
+func TestListProductsByReceptions(t *testing.T) {
+	ctx := context.Background()
+	first, second := uuid.New(), uuid.New()
+
+	mdb := new(MockDatabase)
+	mdb.On("GetProductsByReceptions", ctx, []uuid.UUID{first, second}).Return([]*models.Product{
+		{ID: uuid.New(), ReceptionId: first, Type: "обувь"},
+		{ID: uuid.New(), ReceptionId: first, Type: "одежда"},
+	}, nil).Once()
+	svc := NewService(mdb, []byte("secret"))
+
+	products, err := svc.ListProductsByReceptions(ctx, []uuid.UUID{first, second})
+	assert.NoError(t, err)
+	assert.Len(t, products[first], 2)
+	assert.Empty(t, products[second])
+
+	mdb.On("GetProductsByReceptions", ctx, []uuid.UUID{first}).Return(nil, errors.New("db error")).Once()
+	_, err = svc.ListProductsByReceptions(ctx, []uuid.UUID{first})
+	assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
+	assert.EqualError(t, err, "ошибка выборки товаров: db error")
+}
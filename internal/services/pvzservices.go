@@ -2,69 +2,102 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/database"
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
 	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
 )
 
-func (s *Service) CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) (status int, err error) {
+func (s *Service) CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) (err error) {
 	if role != "moderator" {
-		return http.StatusForbidden, errors.New("доступ запрещен")
+		return ErrForbidden
 	}
 	if pvz.City != "Москва" && pvz.City != "Санкт-Петербург" && pvz.City != "Казань" {
-		return http.StatusBadRequest, errors.New(fmt.Sprintf("ошибка ПВЗ можно создать только в Москве, Санкт-Петербурге или Казани %v", err))
+		return ErrUnknownCity
 	}
 	pvz.RegistrationDate = time.Now()
 	if err := s.database.CreatePVZ(ctx, pvz); err != nil {
-		return http.StatusBadRequest, errors.New(fmt.Sprintf("ошибка создания ПВЗ: %v", err))
+		logger.FromContext(ctx).WithError(err).Error("Ошибка создания ПВЗ в БД")
+		return dbError("ошибка создания ПВЗ", err)
 	}
-	return http.StatusOK, nil
+	logger.FromContext(ctx).WithFields(logrus.Fields{
+		"pvz_id": pvz.ID,
+		"city":   pvz.City,
+	}).Info("ПВЗ создан")
+	s.cities.Store(pvz.ID, pvz.City)
+	metrics.CreatedPVZTotal.WithLabelValues(pvz.City).Inc()
+	return nil
+}
+
+// listPVZParams — параметры ListPVZ после разбора. Неверные значения, как и раньше,
+// заменяются значениями по умолчанию.
+type listPVZParams struct {
+	page, limit        int
+	startDate, endDate *time.Time
 }
 
-func (s *Service) ListPVZ(ctx context.Context, startDateStr string, endDateStr string, pageStr string, limitStr string) (results []*models.PVZResponse, status int, err error) {
-	page := 1
-	limit := 10
+func parseListPVZParams(startDateStr, endDateStr, pageStr, limitStr string) listPVZParams {
+	params := listPVZParams{page: 1, limit: 10}
 	if pageStr != "" {
 		if p, err := strconv.Atoi(pageStr); err == nil && p >= 1 {
-			page = p
+			params.page = p
 		}
 	}
 	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l >= 1 && l <= 30 {
-			limit = l
+			params.limit = l
 		}
 	}
-	offset := (page - 1) * limit
-	pvzs, err := s.database.GetPVZs(ctx, limit, offset)
-	if err != nil {
-		return results, http.StatusBadRequest, errors.New("ошибка выборки ПВЗ")
-	}
-	var startDate, endDate *time.Time
 	if startDateStr != "" {
 		if t, err := time.Parse(time.RFC3339, startDateStr); err == nil {
-			startDate = &t
+			params.startDate = &t
 		}
 	}
 	if endDateStr != "" {
 		if t, err := time.Parse(time.RFC3339, endDateStr); err == nil {
-			endDate = &t
+			params.endDate = &t
 		}
 	}
+	return params
+}
+
+func (s *Service) ListPVZ(ctx context.Context, startDateStr string, endDateStr string, pageStr string, limitStr string) (results []*models.PVZResponse, err error) {
+	params := parseListPVZParams(startDateStr, endDateStr, pageStr, limitStr)
+	startDate, endDate := params.startDate, params.endDate
+	pvzs, err := s.database.GetPVZs(ctx, params.limit, (params.page-1)*params.limit)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка выборки ПВЗ из БД")
+		return results, dbError("ошибка выборки ПВЗ", err)
+	}
+	// Пустые списки отдаются как [], а не null: так требует спецификация.
+	results = make([]*models.PVZResponse, 0, len(pvzs))
 	for _, pvz := range pvzs {
 		recs, err := s.database.GetReceptionsByPVZ(ctx, pvz.ID, startDate, endDate)
 		if err != nil {
-			return results, http.StatusInternalServerError, errors.New("ошибка выборки приёмок")
+			logger.FromContext(ctx).WithError(err).WithField("pvz_id", pvz.ID).Error("Ошибка выборки приёмок из БД")
+			return results, dbError("ошибка выборки приёмок", err)
 		}
-		var recInfos []*models.ReceptionInfo
+		recInfos := make([]*models.ReceptionInfo, 0, len(recs))
 		for _, rec := range recs {
 			products, err := s.database.GetProductsByReception(ctx, rec.ID)
 			if err != nil {
-				return results, http.StatusInternalServerError, errors.New("ошибка выборки товаров")
+				logger.FromContext(ctx).WithError(err).WithField("reception_id", rec.ID).Error("Ошибка выборки товаров из БД")
+				return results, dbError("ошибка выборки товаров", err)
+			}
+			if products == nil {
+				products = []*models.Product{}
 			}
 			recInfos = append(recInfos, &models.ReceptionInfo{
 				Reception: &rec,
@@ -76,13 +109,144 @@ func (s *Service) ListPVZ(ctx context.Context, startDateStr string, endDateStr s
 			Receptions: recInfos,
 		})
 	}
-	return results, http.StatusOK, nil
+	return results, nil
+}
+
+// ListPVZVersion возвращает версию ответа ListPVZ с теми же параметрами: хеш параметров
+// и версий ПВЗ страницы. Страница читается тем же GetPVZs, что и в ListPVZ, поэтому при
+// кеше ПВЗ версия описывает тот же набор ПВЗ, что и ответ. Версия меняется при добавлении
+// ПВЗ и любом изменении приёмок и товаров ПВЗ страницы. Ее нужно получать до ListPVZ:
+// тогда при гонке с записью версия окажется старее данных и клиент просто перезапросит
+// их, но не наоборот.
+func (s *Service) ListPVZVersion(ctx context.Context, startDateStr string, endDateStr string, pageStr string, limitStr string) (version string, err error) {
+	params := parseListPVZParams(startDateStr, endDateStr, pageStr, limitStr)
+	pvzs, err := s.database.GetPVZs(ctx, params.limit, (params.page-1)*params.limit)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка выборки ПВЗ из БД")
+		return "", dbError("ошибка выборки ПВЗ", err)
+	}
+	versions := make(map[uuid.UUID]int64, len(pvzs))
+	if len(pvzs) > 0 {
+		ids := make([]uuid.UUID, 0, len(pvzs))
+		for _, pvz := range pvzs {
+			ids = append(ids, pvz.ID)
+		}
+		rows, err := s.database.GetPVZVersions(ctx, ids)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Error("Ошибка выборки версий ПВЗ из БД")
+			return "", dbError("ошибка выборки версий ПВЗ", err)
+		}
+		for _, v := range rows {
+			versions[v.ID] = v.Version
+		}
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "pvz-list\n%d\n%d\n%s\n%s\n", params.page, params.limit, formatOptionalTime(params.startDate), formatOptionalTime(params.endDate))
+	for _, pvz := range pvzs {
+		fmt.Fprintf(h, "%s:%d\n", pvz.ID, versions[pvz.ID])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+// PVZListVersion возвращает версию ответа GetPVZ. В ответе только сами ПВЗ, поэтому версия
+// считается по нему же: она всегда соответствует отданным данным и не требует запроса к БД.
+func PVZListVersion(pvzs []*pb.PVZ) string {
+	// GetPVZ не сортирует ПВЗ, поэтому версия не должна зависеть от порядка.
+	sorted := append([]*pb.PVZ(nil), pvzs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetId() < sorted[j].GetId() })
+	h := sha256.New()
+	fmt.Fprint(h, "pvz-all\n")
+	for _, pvz := range sorted {
+		fmt.Fprintf(h, "%s:%s:%s\n", pvz.GetId(), pvz.GetCity(), pvz.GetRegistrationDate().AsTime().UTC().Format(time.RFC3339Nano))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ListPVZPage возвращает страницу ПВЗ без приёмок; приёмки догружаются отдельно
+// через ListReceptionsByPVZs для всей страницы сразу.
+func (s *Service) ListPVZPage(ctx context.Context, page, limit int) (pvzs []models.PVZ, err error) {
+	if page < 1 {
+		return nil, invalidRequest("page должен быть не меньше 1")
+	}
+	if limit < 1 || limit > 30 {
+		return nil, invalidRequest("limit должен быть от 1 до 30")
+	}
+	pvzs, err = s.database.GetPVZs(ctx, limit, (page-1)*limit)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка выборки ПВЗ из БД")
+		return nil, dbError("ошибка выборки ПВЗ", err)
+	}
+	return pvzs, nil
+}
+
+func (s *Service) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (pvz *models.PVZ, err error) {
+	pvz, err = s.database.GetPVZByID(ctx, pvzId)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrPVZNotFound.Wrap(err)
+		}
+		logger.FromContext(ctx).WithError(err).Error("Ошибка получения ПВЗ из БД")
+		return nil, dbError("ошибка получения ПВЗ", err)
+	}
+	return pvz, nil
 }
 
 func (s *Service) GetPVZ(ctx context.Context) (pvzs []*pb.PVZ, err error) {
 	pvzs, err = s.database.GetPVZ(ctx)
 	if err != nil {
-		return pvzs, errors.New("ошибка получения ПВЗ: " + err.Error())
+		logger.FromContext(ctx).WithError(err).Error("Ошибка получения ПВЗ из БД")
+		return pvzs, dbError("ошибка получения ПВЗ", err)
 	}
 	return pvzs, nil
 }
+
+// StreamPVZs проверяет фильтры и передает ПВЗ в fn по мере чтения из БД.
+// Ошибка fn и отмена ctx прерывают чтение и возвращаются как есть.
+func (s *Service) StreamPVZs(ctx context.Context, city, fromStr, toStr string, fn func(pvz *models.PVZ) error) (err error) {
+	var filter models.PVZFilter
+	if city != "" && city != "Москва" && city != "Санкт-Петербург" && city != "Казань" {
+		return ErrUnknownCity
+	}
+	filter.City = city
+	if fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return invalidRequest("неверный формат registered_from, ожидается RFC3339")
+		}
+		filter.From = &from
+	}
+	if toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return invalidRequest("неверный формат registered_to, ожидается RFC3339")
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return invalidRequest("registered_from не может быть позже registered_to")
+	}
+
+	var fnErr error
+	err = s.database.StreamPVZs(ctx, filter, func(pvz *models.PVZ) error {
+		fnErr = fn(pvz)
+		return fnErr
+	})
+	if err != nil {
+		if fnErr != nil {
+			return fnErr
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		logger.FromContext(ctx).WithError(err).Error("Ошибка получения ПВЗ из БД")
+		return dbError("ошибка получения ПВЗ", err)
+	}
+	return nil
+}
@@ -10,22 +10,25 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"pvz/internal/database"
 	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
+	"pvz/internal/problem"
 )
 
 func TestCreatePVZ(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name           string
-		role           string
-		inputPVZ       models.PVZ
-		mockSetup      func(mdb *MockDatabase, pvz *models.PVZ)
-		expectedStatus int
-		expectedErr    string
+		name         string
+		role         string
+		inputPVZ     models.PVZ
+		mockSetup    func(mdb *MockDatabase, pvz *models.PVZ)
+		expectedCode problem.Code
+		expectedErr  string
 	}{
 		{
 			name:     "role not moderator",
@@ -33,8 +36,8 @@ func TestCreatePVZ(t *testing.T) {
 			inputPVZ: models.PVZ{City: "Москва"},
 			mockSetup: func(mdb *MockDatabase, pvz *models.PVZ) {
 			},
-			expectedStatus: http.StatusForbidden,
-			expectedErr:    "доступ запрещен",
+			expectedCode: problem.CodeForbidden,
+			expectedErr:  "доступ запрещен",
 		},
 		{
 			name:     "invalid city",
@@ -42,9 +45,8 @@ func TestCreatePVZ(t *testing.T) {
 			inputPVZ: models.PVZ{City: "Ростов"},
 			mockSetup: func(mdb *MockDatabase, pvz *models.PVZ) {
 			},
-			expectedStatus: http.StatusBadRequest,
-
-			expectedErr: "ошибка ПВЗ можно создать только",
+			expectedCode: problem.CodeUnknownCity,
+			expectedErr:  "неизвестный город",
 		},
 		{
 			name:     "db create error",
@@ -53,8 +55,18 @@ func TestCreatePVZ(t *testing.T) {
 			mockSetup: func(mdb *MockDatabase, pvz *models.PVZ) {
 				mdb.On("CreatePVZ", ctx, pvz).Return(errors.New("db create error")).Once()
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedErr:    "ошибка создания ПВЗ: db create error",
+			expectedCode: problem.CodeInternal,
+			expectedErr:  "ошибка создания ПВЗ: db create error",
+		},
+		{
+			name:     "db unavailable",
+			role:     "moderator",
+			inputPVZ: models.PVZ{City: "Москва"},
+			mockSetup: func(mdb *MockDatabase, pvz *models.PVZ) {
+				mdb.On("CreatePVZ", ctx, pvz).Return(database.ErrUnavailable).Once()
+			},
+			expectedCode: problem.CodeUnavailable,
+			expectedErr:  "база данных временно недоступна",
 		},
 		{
 			name:     "success",
@@ -67,8 +79,6 @@ func TestCreatePVZ(t *testing.T) {
 					p.RegistrationDate = time.Now()
 				})
 			},
-			expectedStatus: http.StatusOK,
-			expectedErr:    "",
 		},
 	}
 
@@ -79,9 +89,9 @@ func TestCreatePVZ(t *testing.T) {
 			tt.mockSetup(mockDB, &input)
 
 			svc := NewService(mockDB, []byte("unused"))
-			status, err := svc.CreatePVZ(ctx, &input, tt.role)
-			assert.Equal(t, tt.expectedStatus, status)
+			err := svc.CreatePVZ(ctx, &input, tt.role)
 			if tt.expectedErr != "" {
+				assert.Equal(t, tt.expectedCode, problem.From(err).Code)
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedErr)
 			} else {
@@ -104,7 +114,7 @@ func TestListPVZ(t *testing.T) {
 		pageStr           string
 		limitStr          string
 		mockSetup         func(mdb *MockDatabase)
-		expectedStatus    int
+		expectedCode      problem.Code
 		expectedErrSubstr string
 		expectedResults   int
 	}{
@@ -117,7 +127,7 @@ func TestListPVZ(t *testing.T) {
 			mockSetup: func(mdb *MockDatabase) {
 				mdb.On("GetPVZs", ctx, 10, 0).Return(nil, errors.New("db get error")).Once()
 			},
-			expectedStatus:    http.StatusBadRequest,
+			expectedCode:      problem.CodeInternal,
 			expectedErrSubstr: "ошибка выборки ПВЗ",
 		},
 		{
@@ -133,7 +143,7 @@ func TestListPVZ(t *testing.T) {
 
 				mdb.On("GetReceptionsByPVZ", ctx, pvz.ID, (*time.Time)(nil), (*time.Time)(nil)).Return(nil, errors.New("db receptions error")).Once()
 			},
-			expectedStatus:    http.StatusInternalServerError,
+			expectedCode:      problem.CodeInternal,
 			expectedErrSubstr: "ошибка выборки приёмок",
 		},
 		{
@@ -152,7 +162,7 @@ func TestListPVZ(t *testing.T) {
 
 				mdb.On("GetProductsByReception", ctx, reception.ID).Return(nil, errors.New("db products error")).Once()
 			},
-			expectedStatus:    http.StatusInternalServerError,
+			expectedCode:      problem.CodeInternal,
 			expectedErrSubstr: "ошибка выборки товаров",
 		},
 		{
@@ -169,7 +179,6 @@ func TestListPVZ(t *testing.T) {
 				endDate, _ := time.Parse(time.RFC3339, "2023-01-02T00:00:00Z")
 				mdb.On("GetReceptionsByPVZ", ctx, pvz.ID, &startDate, &endDate).Return([]models.Reception{}, nil).Once()
 			},
-			expectedStatus:  http.StatusOK,
 			expectedResults: 1,
 		},
 	}
@@ -179,9 +188,9 @@ func TestListPVZ(t *testing.T) {
 			mockDB := new(MockDatabase)
 			tt.mockSetup(mockDB)
 			svc := NewService(mockDB, []byte("unused"))
-			results, status, err := svc.ListPVZ(ctx, tt.startDateStr, tt.endDateStr, tt.pageStr, tt.limitStr)
-			assert.Equal(t, tt.expectedStatus, status)
+			results, err := svc.ListPVZ(ctx, tt.startDateStr, tt.endDateStr, tt.pageStr, tt.limitStr)
 			if tt.expectedErrSubstr != "" {
+				assert.Equal(t, tt.expectedCode, problem.From(err).Code)
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedErrSubstr)
 			} else {
@@ -240,3 +249,205 @@ func TestGetPVZ(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamPVZs(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	pvzs := []models.PVZ{
+		{ID: uuid.New(), City: "Казань", RegistrationDate: from},
+		{ID: uuid.New(), City: "Казань", RegistrationDate: to},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		mdb.On("StreamPVZs", ctx, models.PVZFilter{City: "Казань", From: &from, To: &to}).Return(pvzs, nil)
+
+		var got []uuid.UUID
+		err := NewService(mdb, []byte("secret")).StreamPVZs(ctx, "Казань", from.Format(time.RFC3339), to.Format(time.RFC3339), func(pvz *models.PVZ) error {
+			got = append(got, pvz.ID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{pvzs[0].ID, pvzs[1].ID}, got)
+		mdb.AssertExpectations(t)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		svc := NewService(new(MockDatabase), []byte("secret"))
+		for _, tt := range []struct {
+			city, from, to string
+			expectedErr    string
+		}{
+			{city: "Новосибирск", expectedErr: "неизвестный город"},
+			{from: "2025-04-01", expectedErr: "неверный формат registered_from, ожидается RFC3339"},
+			{to: "tomorrow", expectedErr: "неверный формат registered_to, ожидается RFC3339"},
+			{from: to.Format(time.RFC3339), to: from.Format(time.RFC3339), expectedErr: "registered_from не может быть позже registered_to"},
+		} {
+			err := svc.StreamPVZs(ctx, tt.city, tt.from, tt.to, func(*models.PVZ) error { return nil })
+			assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
+			assert.EqualError(t, err, tt.expectedErr)
+		}
+	})
+
+	t.Run("send error is returned as is", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		mdb.On("StreamPVZs", ctx, models.PVZFilter{}).Return(pvzs, nil)
+		sendErr := errors.New("send error")
+
+		err := NewService(mdb, []byte("secret")).StreamPVZs(ctx, "", "", "", func(*models.PVZ) error { return sendErr })
+		assert.Equal(t, sendErr, err)
+	})
+
+	t.Run("canceled context is returned as is", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		mdb := new(MockDatabase)
+		mdb.On("StreamPVZs", canceled, models.PVZFilter{}).Return(nil, errors.New("conn closed"))
+
+		err := NewService(mdb, []byte("secret")).StreamPVZs(canceled, "", "", "", func(*models.PVZ) error { return nil })
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		mdb.On("StreamPVZs", ctx, models.PVZFilter{}).Return(nil, errors.New("db error"))
+
+		err := NewService(mdb, []byte("secret")).StreamPVZs(ctx, "", "", "", func(*models.PVZ) error { return nil })
+		assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "ошибка получения ПВЗ: db error")
+	})
+}
+
+func TestListPVZPage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mdb := new(MockDatabase)
+		pvzs := []models.PVZ{{ID: uuid.New(), City: "Москва"}}
+		mdb.On("GetPVZs", ctx, 5, 10).Return(pvzs, nil)
+
+		got, err := NewService(mdb, []byte("secret")).ListPVZPage(ctx, 3, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, pvzs, got)
+		mdb.AssertExpectations(t)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		svc := NewService(new(MockDatabase), []byte("secret"))
+		_, err := svc.ListPVZPage(ctx, 0, 10)
+		assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "page должен быть не меньше 1")
+		_, err = svc.ListPVZPage(ctx, 1, 31)
+		assert.Equal(t, http.StatusBadRequest, problem.From(err).Code.HTTPStatus())
+		assert.EqualError(t, err, "limit должен быть от 1 до 30")
+	})
+}
+
+func TestListPVZVersion(t *testing.T) {
+	ctx := context.Background()
+	id1, id2 := uuid.New(), uuid.New()
+	page := []models.PVZ{{ID: id1, City: "Москва"}, {ID: id2, City: "Казань"}}
+	versions := []models.PVZVersion{{ID: id2, Version: 4}, {ID: id1, Version: 1}}
+
+	mdb := new(MockDatabase)
+	mdb.On("GetPVZs", ctx, 5, 5).Return(page, nil)
+	mdb.On("GetPVZs", ctx, 10, 0).Return(page, nil)
+	mdb.On("GetPVZVersions", ctx, []uuid.UUID{id1, id2}).Return(versions, nil)
+	svc := NewService(mdb, []byte("secret"))
+
+	v1, err := svc.ListPVZVersion(ctx, "", "", "2", "5")
+	require.NoError(t, err)
+	assert.NotEmpty(t, v1)
+	again, err := svc.ListPVZVersion(ctx, "", "", "2", "5")
+	require.NoError(t, err)
+	assert.Equal(t, v1, again, "версия детерминирована")
+
+	defaults, err := svc.ListPVZVersion(ctx, "", "", "abc", "100")
+	require.NoError(t, err)
+	filtered, err := svc.ListPVZVersion(ctx, "2025-01-01T00:00:00Z", "", "", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, v1, defaults, "версия зависит от страницы")
+	assert.NotEqual(t, defaults, filtered, "версия зависит от периода приёмок")
+
+	versions[0].Version++
+	changed, err := svc.ListPVZVersion(ctx, "", "", "2", "5")
+	require.NoError(t, err)
+	assert.NotEqual(t, v1, changed, "изменение ПВЗ меняет версию")
+
+	mdb.On("GetPVZs", ctx, 10, 20).Return([]models.PVZ{}, nil)
+	empty, err := svc.ListPVZVersion(ctx, "", "", "3", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, empty, "для пустой страницы версии не запрашиваются")
+
+	mdb.On("GetPVZs", ctx, 10, 10).Return(nil, database.ErrUnavailable)
+	_, err = svc.ListPVZVersion(ctx, "", "", "2", "")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	mdb.AssertExpectations(t)
+}
+
+// Страница ПВЗ берется из кеша, поэтому версия не должна опережать ответ, пока кеш не сброшен.
+func TestListPVZVersionFollowsCachedPage(t *testing.T) {
+	ctx := context.Background()
+	backend := database.NewMemoryDatabase()
+	svc := NewService(database.NewCachedDatabase(backend, time.Hour, 10), []byte("secret"))
+	cached := svc.database.(*database.CachedDatabase)
+	list := func() (string, []*models.PVZResponse) {
+		t.Helper()
+		version, err := svc.ListPVZVersion(ctx, "", "", "", "")
+		require.NoError(t, err)
+		results, err := svc.ListPVZ(ctx, "", "", "", "")
+		require.NoError(t, err)
+		return version, results
+	}
+
+	require.NoError(t, svc.CreatePVZ(ctx, &models.PVZ{City: "Москва"}, "moderator"))
+	before, results := list()
+	require.Len(t, results, 1)
+
+	// ПВЗ добавлен в обход кеша, например другой репликой, а уведомление еще не пришло.
+	require.NoError(t, backend.CreatePVZ(ctx, &models.PVZ{City: "Казань", RegistrationDate: time.Now()}))
+	stale, results := list()
+	assert.Len(t, results, 1)
+	assert.Equal(t, before, stale, "версия не должна опережать закешированный ответ")
+
+	cached.Invalidate()
+	fresh, results := list()
+	assert.Len(t, results, 2)
+	assert.NotEqual(t, before, fresh)
+
+	// Приёмки читаются мимо кеша, и их изменение меняет версию сразу.
+	_, err := backend.CreateReception(ctx, results[0].PVZ.ID)
+	require.NoError(t, err)
+	withReception, results := list()
+	assert.NotEqual(t, fresh, withReception)
+	assert.Len(t, results[0].Receptions, 1)
+}
+
+func TestPVZListVersion(t *testing.T) {
+	now := timestamppb.Now()
+	first := &pb.PVZ{Id: uuid.NewString(), City: "Москва", RegistrationDate: now}
+	second := &pb.PVZ{Id: uuid.NewString(), City: "Казань", RegistrationDate: now}
+
+	version := PVZListVersion([]*pb.PVZ{first, second})
+	assert.Equal(t, version, PVZListVersion([]*pb.PVZ{second, first}), "версия не зависит от порядка ПВЗ")
+	assert.NotEqual(t, version, PVZListVersion([]*pb.PVZ{first}), "версия зависит от набора ПВЗ")
+	assert.NotEqual(t, version, PVZListVersion(nil))
+}
+
+func TestGetPVZByID(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	mdb := new(MockDatabase)
+	mdb.On("GetPVZByID", ctx, pvzId).Return(&models.PVZ{ID: pvzId, City: "Казань"}, nil).Once()
+	svc := NewService(mdb, []byte("secret"))
+	pvz, err := svc.GetPVZByID(ctx, pvzId)
+	assert.NoError(t, err)
+	assert.Equal(t, "Казань", pvz.City)
+
+	mdb.On("GetPVZByID", ctx, pvzId).Return(nil, database.ErrNotFound).Once()
+	_, err = svc.GetPVZByID(ctx, pvzId)
+	assert.Equal(t, http.StatusNotFound, problem.From(err).Code.HTTPStatus())
+	assert.ErrorIs(t, err, ErrPVZNotFound)
+}
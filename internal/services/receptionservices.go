@@ -3,31 +3,94 @@ package services
 import (
 	"context"
 	"errors"
-	"net/http"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 
+	"pvz/internal/database"
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
 	"pvz/internal/models"
 )
 
-func (s *Service) CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, status int, err error) {
+func (s *Service) CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, err error) {
 	if role != "employee" {
-		return rec, http.StatusForbidden, errors.New("доступ запрещен")
+		return rec, ErrForbidden
 	}
 	rec, err = s.database.CloseLastReception(ctx, pvzId)
 	if err != nil {
-		return rec, http.StatusBadRequest, errors.New("ошибка закрытия приёмки: " + err.Error())
+		logger.FromContext(ctx).WithError(err).Error("Ошибка закрытия приёмки в БД")
+		if errors.Is(err, database.ErrNotFound) {
+			return rec, ErrPVZNotFound.Wrap(err)
+		}
+		return rec, dbError("ошибка закрытия приёмки", err)
 	}
-	return rec, http.StatusOK, nil
+	logger.FromContext(ctx).WithField("reception_id", rec.ID).Info("Приёмка закрыта")
+	city := s.cityOf(ctx, pvzId)
+	metrics.ReceptionDuration.WithLabelValues(city).Observe(time.Since(rec.DateTime).Seconds())
+	if count, err := s.database.CountProductsByReception(ctx, rec.ID); err != nil {
+		logger.FromContext(ctx).WithError(err).Warn("Не удалось подсчитать товары приёмки для метрик")
+	} else {
+		metrics.ProductsPerReception.WithLabelValues(city).Observe(float64(count))
+	}
+	return rec, nil
 }
 
-func (s *Service) CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, status int, err error) {
+func (s *Service) CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (rec *models.Reception, err error) {
 	if role != "employee" {
-		return rec, http.StatusForbidden, errors.New("доступ запрещен")
+		return rec, ErrForbidden
 	}
 	rec, err = s.database.CreateReception(ctx, pvzId)
 	if err != nil {
-		return rec, http.StatusBadRequest, errors.New("ошибка создания приёмки: " + err.Error())
+		logger.FromContext(ctx).WithError(err).Error("Ошибка создания приёмки в БД")
+		if errors.Is(err, database.ErrNotFound) {
+			return rec, ErrPVZNotFound.Wrap(err)
+		}
+		return rec, dbError("ошибка создания приёмки", err)
+	}
+	logger.FromContext(ctx).WithField("reception_id", rec.ID).Info("Приёмка создана")
+	city := s.cityOf(ctx, pvzId)
+	metrics.CreatedReceptionTotal.WithLabelValues(city).Inc()
+	return rec, nil
+}
+
+// ListReceptionsByPVZs возвращает приёмки нескольких ПВЗ одним запросом, сгруппированные по ПВЗ.
+func (s *Service) ListReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (recs map[uuid.UUID][]models.Reception, err error) {
+	rows, err := s.database.GetReceptionsByPVZs(ctx, pvzIds, startDate, endDate)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка выборки приёмок из БД")
+		return nil, dbError("ошибка выборки приёмок", err)
+	}
+	recs = make(map[uuid.UUID][]models.Reception, len(pvzIds))
+	for _, rec := range rows {
+		recs[rec.PVZId] = append(recs[rec.PVZId], rec)
+	}
+	return recs, nil
+}
+
+// AutoCloseReceptions закрывает приёмки, которые остаются открытыми дольше лимита города,
+// с причиной auto_closed. Если проход уже выполняет другая реплика, ничего не делает.
+func (s *Service) AutoCloseReceptions(ctx context.Context, limits models.AutoCloseLimits) error {
+	closed, acquired, err := s.database.AutoCloseReceptions(ctx, time.Now(), limits)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		logger.FromContext(ctx).Debug("Автозакрытие приёмок выполняет другая реплика")
+		return nil
+	}
+	for _, rec := range closed {
+		s.cities.Store(rec.PVZId, rec.City)
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"reception_id": rec.ID,
+			"pvz_id":       rec.PVZId,
+			"city":         rec.City,
+			"opened_at":    rec.DateTime,
+			"open_for":     rec.ClosedAt.Sub(rec.DateTime).Round(time.Second).String(),
+			"reason":       models.CloseReasonAutoClosed,
+		}).Warn("Приёмка закрыта автоматически")
+		metrics.AutoClosedReceptionsTotal.WithLabelValues(rec.City).Inc()
 	}
-	return rec, http.StatusOK, nil
+	return nil
 }
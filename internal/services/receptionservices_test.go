@@ -3,14 +3,22 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
+	"pvz/internal/database"
+	"pvz/internal/metrics"
 	"pvz/internal/models"
+	"pvz/internal/problem"
 )
 
 func TestCloseLastReception(t *testing.T) {
@@ -19,9 +27,9 @@ func TestCloseLastReception(t *testing.T) {
 
 	t.Run("role not employee", func(t *testing.T) {
 		svc := NewService(nil, []byte("unused"))
-		rec, status, err := svc.CloseLastReception(ctx, "moderator", pvzId)
+		rec, err := svc.CloseLastReception(ctx, "moderator", pvzId)
 		assert.Nil(t, rec)
-		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, http.StatusForbidden, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "доступ запрещен")
 	})
 
@@ -30,10 +38,32 @@ func TestCloseLastReception(t *testing.T) {
 		mockDB.On("CloseLastReception", ctx, pvzId).Return(nil, errors.New("db error")).Once()
 
 		svc := NewService(mockDB, []byte("unused"))
-		rec, status, err := svc.CloseLastReception(ctx, "employee", pvzId)
+		rec, err := svc.CloseLastReception(ctx, "employee", pvzId)
 		assert.Nil(t, rec)
-		assert.Equal(t, http.StatusBadRequest, status)
 		assert.EqualError(t, err, "ошибка закрытия приёмки: db error")
+		assert.Equal(t, problem.CodeInternal, problem.From(err).Code)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("unknown pvz", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("CloseLastReception", ctx, pvzId).Return(nil, database.ErrNotFound).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		_, err := svc.CloseLastReception(ctx, "employee", pvzId)
+		assert.ErrorIs(t, err, ErrPVZNotFound)
+		assert.Equal(t, http.StatusNotFound, problem.From(err).Code.HTTPStatus())
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("no active reception", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("CloseLastReception", ctx, pvzId).Return(nil, database.ErrNoActiveReception).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		_, err := svc.CloseLastReception(ctx, "employee", pvzId)
+		assert.ErrorIs(t, err, ErrNoActiveReception)
+		assert.Equal(t, http.StatusConflict, problem.From(err).Code.HTTPStatus())
 		mockDB.AssertExpectations(t)
 	})
 
@@ -46,10 +76,11 @@ func TestCloseLastReception(t *testing.T) {
 		}
 		mockDB := new(MockDatabase)
 		mockDB.On("CloseLastReception", ctx, pvzId).Return(expectedRec, nil).Once()
+		mockDB.On("GetPVZByID", ctx, pvzId).Return(&models.PVZ{ID: pvzId, City: "Санкт-Петербург"}, nil).Once()
+		mockDB.On("CountProductsByReception", ctx, expectedRec.ID).Return(12, nil).Once()
 
 		svc := NewService(mockDB, []byte("unused"))
-		rec, status, err := svc.CloseLastReception(ctx, "employee", pvzId)
-		assert.Equal(t, http.StatusOK, status)
+		rec, err := svc.CloseLastReception(ctx, "employee", pvzId)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedRec, rec)
 		mockDB.AssertExpectations(t)
@@ -62,21 +93,44 @@ func TestCreateReception(t *testing.T) {
 
 	t.Run("role not employee", func(t *testing.T) {
 		svc := NewService(nil, []byte("unused"))
-		rec, status, err := svc.CreateReception(ctx, "moderator", pvzId)
+		rec, err := svc.CreateReception(ctx, "moderator", pvzId)
 		assert.Nil(t, rec)
-		assert.Equal(t, http.StatusForbidden, status)
+		assert.Equal(t, http.StatusForbidden, problem.From(err).Code.HTTPStatus())
 		assert.EqualError(t, err, "доступ запрещен")
 	})
 
-	t.Run("db error", func(t *testing.T) {
+	t.Run("reception already open", func(t *testing.T) {
 		mockDB := new(MockDatabase)
-		mockDB.On("CreateReception", ctx, pvzId).Return(nil, errors.New("Активная приёмка уже существует")).Once()
+		mockDB.On("CreateReception", ctx, pvzId).Return(nil, database.ErrReceptionAlreadyOpen).Once()
 
 		svc := NewService(mockDB, []byte("unused"))
-		rec, status, err := svc.CreateReception(ctx, "employee", pvzId)
+		rec, err := svc.CreateReception(ctx, "employee", pvzId)
 		assert.Nil(t, rec)
-		assert.Equal(t, http.StatusBadRequest, status)
-		assert.EqualError(t, err, "ошибка создания приёмки: Активная приёмка уже существует")
+		assert.ErrorIs(t, err, ErrReceptionAlreadyOpen)
+		assert.Equal(t, http.StatusConflict, problem.From(err).Code.HTTPStatus())
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("pvz not found", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("CreateReception", ctx, pvzId).Return(nil, database.ErrNotFound).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		_, err := svc.CreateReception(ctx, "employee", pvzId)
+		assert.ErrorIs(t, err, ErrPVZNotFound)
+		assert.Equal(t, http.StatusNotFound, problem.From(err).Code.HTTPStatus())
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("db unavailable", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("CreateReception", ctx, pvzId).Return(nil, fmt.Errorf("%w: connection refused", database.ErrUnavailable)).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		_, err := svc.CreateReception(ctx, "employee", pvzId)
+		assert.ErrorIs(t, err, ErrUnavailable)
+		assert.Equal(t, http.StatusServiceUnavailable, problem.From(err).Code.HTTPStatus())
+		assert.NotContains(t, problem.From(err).Message, "connection refused", "текст ошибки БД не должен попадать к клиенту")
 		mockDB.AssertExpectations(t)
 	})
 
@@ -89,13 +143,145 @@ func TestCreateReception(t *testing.T) {
 		}
 		mockDB := new(MockDatabase)
 		mockDB.On("CreateReception", ctx, pvzId).Return(expectedRec, nil).Once()
+		mockDB.On("GetPVZByID", ctx, pvzId).Return(nil, errors.New("db error")).Once()
 
 		svc := NewService(mockDB, []byte("unused"))
-		rec, status, err := svc.CreateReception(ctx, "employee", pvzId)
+		before := testutil.ToFloat64(metrics.CreatedReceptionTotal.WithLabelValues(unknownCity))
+		rec, err := svc.CreateReception(ctx, "employee", pvzId)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.CreatedReceptionTotal.WithLabelValues(unknownCity)))
 		assert.NotNil(t, rec)
-		assert.Equal(t, http.StatusOK, status)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedRec, rec)
 		mockDB.AssertExpectations(t)
 	})
 }
+
+func sampleCount(t *testing.T, o prometheus.Observer) uint64 {
+	var m dto.Metric
+	assert.NoError(t, o.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestReceptionLifecycleMetrics(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+	city := "Москва"
+	rec := &models.Reception{
+		ID:       uuid.New(),
+		DateTime: time.Now().Add(-30 * time.Minute),
+		PVZId:    pvzId,
+		Status:   "in_progress",
+	}
+
+	mockDB := new(MockDatabase)
+	mockDB.On("CreateReception", ctx, pvzId).Return(rec, nil).Once()
+	mockDB.On("GetPVZByID", ctx, pvzId).Return(&models.PVZ{ID: pvzId, City: city}, nil).Once()
+	mockDB.On("CloseLastReception", ctx, pvzId).Return(rec, nil).Once()
+	mockDB.On("CountProductsByReception", ctx, rec.ID).Return(3, nil).Once()
+
+	svc := NewService(mockDB, []byte("unused"))
+	durationsBefore := sampleCount(t, metrics.ReceptionDuration.WithLabelValues(city))
+	productsBefore := sampleCount(t, metrics.ProductsPerReception.WithLabelValues(city))
+
+	_, err := svc.CreateReception(ctx, "employee", pvzId)
+	assert.NoError(t, err)
+
+	_, err = svc.CloseLastReception(ctx, "employee", pvzId)
+	assert.NoError(t, err)
+	assert.Equal(t, durationsBefore+1, sampleCount(t, metrics.ReceptionDuration.WithLabelValues(city)))
+	assert.Equal(t, productsBefore+1, sampleCount(t, metrics.ProductsPerReception.WithLabelValues(city)))
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestRefreshOpenReceptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("db error", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("CountOpenReceptionsByCity", ctx).Return(nil, errors.New("db error")).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		assert.EqualError(t, svc.RefreshOpenReceptions(ctx), "db error")
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("CountOpenReceptionsByCity", ctx).Return(map[string]int{"Казань": 4}, nil).Once()
+		mockDB.On("CountOpenReceptionsByCity", ctx).Return(map[string]int{"Москва": 1}, nil).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		assert.NoError(t, svc.RefreshOpenReceptions(ctx))
+		assert.Equal(t, 4.0, testutil.ToFloat64(metrics.OpenReceptions.WithLabelValues("Казань")))
+
+		assert.NoError(t, svc.RefreshOpenReceptions(ctx))
+		assert.Equal(t, 0.0, testutil.ToFloat64(metrics.OpenReceptions.WithLabelValues("Казань")), "город без открытых приёмок обнуляется")
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.OpenReceptions.WithLabelValues("Москва")))
+		mockDB.AssertExpectations(t)
+	})
+}
+
+func TestAutoCloseReceptions(t *testing.T) {
+	ctx := context.Background()
+	limits := models.AutoCloseLimits{Default: 12 * time.Hour}
+
+	t.Run("db error", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("AutoCloseReceptions", ctx, mock.AnythingOfType("time.Time"), limits).Return(nil, false, errors.New("db error")).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		assert.EqualError(t, svc.AutoCloseReceptions(ctx, limits), "db error")
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("lock held by another replica", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		mockDB.On("AutoCloseReceptions", ctx, mock.AnythingOfType("time.Time"), limits).Return(nil, false, nil).Once()
+
+		svc := NewService(mockDB, []byte("unused"))
+		assert.NoError(t, svc.AutoCloseReceptions(ctx, limits))
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		mockDB := new(MockDatabase)
+		now := time.Now()
+		closed := []models.AutoClosedReception{
+			{ID: uuid.New(), PVZId: uuid.New(), City: "Санкт-Петербург", DateTime: now.Add(-13 * time.Hour), ClosedAt: now},
+			{ID: uuid.New(), PVZId: uuid.New(), City: "Санкт-Петербург", DateTime: now.Add(-20 * time.Hour), ClosedAt: now},
+		}
+		mockDB.On("AutoCloseReceptions", ctx, mock.AnythingOfType("time.Time"), limits).Return(closed, true, nil).Once()
+
+		before := testutil.ToFloat64(metrics.AutoClosedReceptionsTotal.WithLabelValues("Санкт-Петербург"))
+		svc := NewService(mockDB, []byte("unused"))
+		assert.NoError(t, svc.AutoCloseReceptions(ctx, limits))
+		assert.Equal(t, before+2, testutil.ToFloat64(metrics.AutoClosedReceptionsTotal.WithLabelValues("Санкт-Петербург")))
+		assert.Equal(t, "Санкт-Петербург", svc.cityOf(ctx, closed[0].PVZId), "город ПВЗ запоминается для метрик")
+		mockDB.AssertExpectations(t)
+	})
+}
+
+func TestListReceptionsByPVZs(t *testing.T) {
+	ctx := context.Background()
+	moscow, kazan := uuid.New(), uuid.New()
+	start := time.Now().Add(-time.Hour)
+
+	mdb := new(MockDatabase)
+	mdb.On("GetReceptionsByPVZs", ctx, []uuid.UUID{moscow, kazan}, &start, (*time.Time)(nil)).Return([]models.Reception{
+		{ID: uuid.New(), PVZId: kazan},
+		{ID: uuid.New(), PVZId: moscow},
+		{ID: uuid.New(), PVZId: kazan},
+	}, nil).Once()
+	svc := NewService(mdb, []byte("secret"))
+
+	recs, err := svc.ListReceptionsByPVZs(ctx, []uuid.UUID{moscow, kazan}, &start, nil)
+	assert.NoError(t, err)
+	assert.Len(t, recs[moscow], 1)
+	assert.Len(t, recs[kazan], 2)
+
+	mdb.On("GetReceptionsByPVZs", ctx, []uuid.UUID{moscow}, (*time.Time)(nil), (*time.Time)(nil)).Return(nil, errors.New("db error")).Once()
+	_, err = svc.ListReceptionsByPVZs(ctx, []uuid.UUID{moscow}, nil, nil)
+	assert.Equal(t, http.StatusInternalServerError, problem.From(err).Code.HTTPStatus())
+	assert.EqualError(t, err, "ошибка выборки приёмок: db error")
+}
@@ -0,0 +1,128 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultCheckInterval = 5 * time.Second
+
+// CertReloader отдает сертификат сервера и перечитывает его с диска,
+// когда меняется время модификации файла сертификата или ключа.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu            sync.Mutex
+	cert          *tls.Certificate
+	certModTime   time.Time
+	keyModTime    time.Time
+	lastCheck     time.Time
+	checkInterval time.Duration
+	now           func() time.Time
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		checkInterval: defaultCheckInterval,
+		now:           time.Now,
+	}
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(certModTime, keyModTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("ошибка чтения сертификата: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("ошибка чтения ключа: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+func (r *CertReloader) load(certModTime, keyModTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки сертификата: %w", err)
+	}
+	r.cert = &cert
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+	return nil
+}
+
+func (r *CertReloader) maybeReload() {
+	now := r.now()
+	if now.Sub(r.lastCheck) < r.checkInterval {
+		return
+	}
+	r.lastCheck = now
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		logrus.WithError(err).Warn("Не удалось проверить сертификат, используется загруженный ранее")
+		return
+	}
+	if certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return
+	}
+	if err := r.load(certModTime, keyModTime); err != nil {
+		logrus.WithError(err).Warn("Не удалось перечитать сертификат, используется загруженный ранее")
+		return
+	}
+	logrus.WithField("cert_file", r.certFile).Info("Сертификат перечитан с диска")
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maybeReload()
+	return r.cert, nil
+}
+
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("в файле CA нет сертификатов")
+	}
+	return pool, nil
+}
+
+// ServerConfig собирает TLS-конфигурацию сервера. Если clientCAFile задан,
+// клиенты обязаны предъявить сертификат, подписанный этим CA.
+func ServerConfig(reloader *CertReloader, clientCAFile string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if clientCAFile != "" {
+		pool, err := LoadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
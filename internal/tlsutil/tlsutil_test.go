@@ -0,0 +1,99 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/tlsutil/tlstest"
+)
+
+func leafCN(t *testing.T, cert *tls.Certificate) string {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t)
+	certFile, keyFile := ca.Issue(t, "first").WriteFiles(t, dir)
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", leafCN(t, cert))
+
+	ca.Issue(t, "second").WriteFiles(t, dir)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	cert, _ = reloader.GetCertificate(nil)
+	assert.Equal(t, "first", leafCN(t, cert), "Файлы проверяются не чаще checkInterval")
+
+	now = now.Add(defaultCheckInterval)
+	cert, _ = reloader.GetCertificate(nil)
+	assert.Equal(t, "second", leafCN(t, cert))
+
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	even := later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, even, even))
+	now = now.Add(defaultCheckInterval)
+	cert, _ = reloader.GetCertificate(nil)
+	assert.Equal(t, "second", leafCN(t, cert), "Битый файл не должен заменять рабочий сертификат")
+}
+
+func TestNewCertReloaderErrors(t *testing.T) {
+	_, err := NewCertReloader("missing.crt", "missing.key")
+	assert.Error(t, err)
+}
+
+func TestServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t)
+	certFile, keyFile := ca.Issue(t, "localhost").WriteFiles(t, dir)
+	reloader, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	cfg, err := ServerConfig(reloader, ca.WriteFile(t, dir))
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}), ErrorLog: log.New(io.Discard, "", 0)}
+	go server.Serve(ln)
+	defer server.Close()
+	url := "https://" + ln.Addr().String()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      ca.Pool(),
+		Certificates: []tls.Certificate{ca.Issue(t, "terminal-1").TLSCertificate(t)},
+	}}}
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "terminal-1", string(body))
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.Pool()}}}
+	_, err = anonymous.Get(url)
+	assert.Error(t, err, "Без клиентского сертификата соединение должно отклоняться")
+
+	_, err = ServerConfig(reloader, certFile+".missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package graphqlh
+
+import (
+	"net/http"
+
+	"pvz/internal/problem"
+)
+
+// Error — ошибка резолвера с HTTP-статусом, который задает код ошибки сервиса;
+// статус и код попадают в extensions ответа, поэтому клиент различает ошибки так же, как в REST.
+type Error struct {
+	status  int
+	message string
+}
+
+func newError(err error) *Error {
+	e := problem.From(err)
+	return &Error{status: e.Code.HTTPStatus(), message: e.Message}
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":   errorCode(e.status),
+		"status": e.status,
+	}
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "BAD_REQUEST"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "FORBIDDEN"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "CONFLICT"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	default:
+		return "INTERNAL"
+	}
+}
+
+var (
+	errUnauthenticated = &Error{status: http.StatusUnauthorized, message: "требуется авторизация"}
+	errInvalidID       = &Error{status: http.StatusBadRequest, message: "неверный идентификатор"}
+)
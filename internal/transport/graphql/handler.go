@@ -0,0 +1,65 @@
+package graphqlh
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"pvz/internal/logger"
+	"pvz/internal/services"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxDepth ограничивает вложенность запроса: pvzs → receptions → products укладываются с запасом.
+const maxDepth = 8
+
+type Handler struct {
+	schema *graphql.Schema
+	svc    services.ServiceInterface
+}
+
+type Option func(r *resolver)
+
+// WithDummyLogin включает мутацию dummyLogin, как FEATURE_DUMMY_LOGIN для REST.
+func WithDummyLogin(enabled bool) Option {
+	return func(r *resolver) {
+		r.dummyLogin = enabled
+	}
+}
+
+func NewHandler(svc services.ServiceInterface, opts ...Option) *Handler {
+	r := &resolver{services: svc}
+	for _, opt := range opts {
+		opt(r)
+	}
+	schema := graphql.MustParseSchema(schemaSDL, r, graphql.MaxDepth(maxDepth))
+	return &Handler{schema: schema, svc: svc}
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"message": "неверный запрос GraphQL"}},
+		})
+		return
+	}
+	ctx := withLoaders(r.Context(), newLoaders(h.svc))
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	if len(resp.Errors) > 0 {
+		logger.FromContext(ctx).WithField("errors", len(resp.Errors)).Info("Запрос GraphQL выполнен с ошибками")
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,198 @@
+package graphqlh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/database"
+	"pvz/internal/middleware"
+	"pvz/internal/models"
+	"pvz/internal/services"
+)
+
+// countingService считает пакетные чтения, чтобы проверить отсутствие N+1.
+type countingService struct {
+	services.ServiceInterface
+	receptionCalls atomic.Int32
+	productCalls   atomic.Int32
+}
+
+func (s *countingService) ListReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (map[uuid.UUID][]models.Reception, error) {
+	s.receptionCalls.Add(1)
+	return s.ServiceInterface.ListReceptionsByPVZs(ctx, pvzIds, startDate, endDate)
+}
+
+func (s *countingService) ListProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (map[uuid.UUID][]*models.Product, error) {
+	s.productCalls.Add(1)
+	return s.ServiceInterface.ListProductsByReceptions(ctx, receptionIds)
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*httptest.Server, *countingService) {
+	secret := []byte("secret")
+	svc := &countingService{ServiceInterface: services.NewService(database.NewMemoryDatabase(), secret)}
+	ts := httptest.NewServer(middleware.NewMiddleware(secret).OptionalAuthMiddleware(NewHandler(svc, opts...)))
+	t.Cleanup(ts.Close)
+	return ts, svc
+}
+
+func exec(t *testing.T, ts *httptest.Server, token, query string, variables map[string]interface{}) response {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, ts.URL, bytes.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return out
+}
+
+func login(t *testing.T, ts *httptest.Server, role string) string {
+	t.Helper()
+	resp := exec(t, ts, "", `mutation($role: String!) { dummyLogin(role: $role) }`, map[string]interface{}{"role": role})
+	require.Empty(t, resp.Errors)
+	var token string
+	require.NoError(t, json.Unmarshal(resp.Data["dummyLogin"], &token))
+	return token
+}
+
+func TestGraphQLBatchesNestedReads(t *testing.T) {
+	ts, svc := newTestServer(t, WithDummyLogin(true))
+	moderator := login(t, ts, "moderator")
+	employee := login(t, ts, "employee")
+
+	for i, city := range []string{"Москва", "Казань", "Санкт-Петербург"} {
+		resp := exec(t, ts, moderator, `mutation($city: String!) { createPVZ(city: $city) { id } }`, map[string]interface{}{"city": city})
+		require.Empty(t, resp.Errors)
+		var pvz struct{ ID string }
+		require.NoError(t, json.Unmarshal(resp.Data["createPVZ"], &pvz))
+
+		resp = exec(t, ts, employee, `mutation($id: ID!) { createReception(pvzId: $id) { status } }`, map[string]interface{}{"id": pvz.ID})
+		require.Empty(t, resp.Errors)
+		for j := 0; j <= i; j++ {
+			resp = exec(t, ts, employee, `mutation($id: ID!) { addProduct(pvzId: $id, type: "обувь") { type } }`, map[string]interface{}{"id": pvz.ID})
+			require.Empty(t, resp.Errors)
+		}
+	}
+
+	resp := exec(t, ts, moderator, `{ pvzs(limit: 30) { city receptions { status productCount products { type } } } }`, nil)
+	require.Empty(t, resp.Errors)
+	var pvzs []struct {
+		City       string
+		Receptions []struct {
+			Status       string
+			ProductCount int
+			Products     []struct{ Type string }
+		}
+	}
+	require.NoError(t, json.Unmarshal(resp.Data["pvzs"], &pvzs))
+	require.Len(t, pvzs, 3)
+	counts := map[string]int{}
+	for _, pvz := range pvzs {
+		require.Len(t, pvz.Receptions, 1)
+		assert.Equal(t, "in_progress", pvz.Receptions[0].Status)
+		assert.Len(t, pvz.Receptions[0].Products, pvz.Receptions[0].ProductCount)
+		counts[pvz.City] = pvz.Receptions[0].ProductCount
+	}
+	assert.Equal(t, map[string]int{"Москва": 1, "Казань": 2, "Санкт-Петербург": 3}, counts)
+	assert.Equal(t, int32(1), svc.receptionCalls.Load(), "приёмки всех ПВЗ читаются одним запросом")
+	assert.Equal(t, int32(1), svc.productCalls.Load(), "товары всех приёмок читаются одним запросом")
+}
+
+func TestGraphQLAuth(t *testing.T) {
+	ts, _ := newTestServer(t, WithDummyLogin(true))
+	employee := login(t, ts, "employee")
+
+	errorCode := func(resp response) interface{} {
+		require.Len(t, resp.Errors, 1)
+		return resp.Errors[0].Extensions["code"]
+	}
+
+	assert.Equal(t, "UNAUTHENTICATED", errorCode(exec(t, ts, "", `{ pvzs { id } }`, nil)))
+	assert.Equal(t, "FORBIDDEN", errorCode(exec(t, ts, employee, `mutation { createPVZ(city: "Москва") { id } }`, nil)))
+	assert.Equal(t, "BAD_REQUEST", errorCode(exec(t, ts, employee, `{ pvz(id: "42") { id } }`, nil)))
+
+	resp := exec(t, ts, employee, fmt.Sprintf(`{ pvz(id: %q) { id } }`, uuid.NewString()), nil)
+	assert.Empty(t, resp.Errors)
+	assert.JSONEq(t, "null", string(resp.Data["pvz"]))
+
+	resp = exec(t, ts, employee, `{ me { role email } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"role":"employee","email":null}`, string(resp.Data["me"]))
+
+	resp = exec(t, ts, "", `mutation { register(email: "mod@example.com", password: "secret", role: "moderator") { id } }`, nil)
+	require.Empty(t, resp.Errors)
+	resp = exec(t, ts, "", `mutation { login(email: "mod@example.com", password: "secret") }`, nil)
+	require.Empty(t, resp.Errors)
+	var token string
+	require.NoError(t, json.Unmarshal(resp.Data["login"], &token))
+	resp = exec(t, ts, token, `{ me { role email } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"role":"moderator","email":"mod@example.com"}`, string(resp.Data["me"]))
+
+	disabled, _ := newTestServer(t)
+	assert.Equal(t, "NOT_FOUND", errorCode(exec(t, disabled, "", `mutation { dummyLogin(role: "employee") }`, nil)))
+}
+
+func TestGraphQLDomainErrors(t *testing.T) {
+	ts, _ := newTestServer(t, WithDummyLogin(true))
+	moderator := login(t, ts, "moderator")
+	employee := login(t, ts, "employee")
+
+	resp := exec(t, ts, moderator, `mutation { createPVZ(city: "Москва") { id } }`, nil)
+	require.Empty(t, resp.Errors)
+	var pvz struct{ ID string }
+	require.NoError(t, json.Unmarshal(resp.Data["createPVZ"], &pvz))
+	resp = exec(t, ts, employee, `mutation($id: ID!) { createReception(pvzId: $id) { status } }`, map[string]interface{}{"id": pvz.ID})
+	require.Empty(t, resp.Errors)
+
+	resp = exec(t, ts, employee, `mutation($id: ID!) { addProduct(pvzId: $id, type: "мебель") { type } }`, map[string]interface{}{"id": pvz.ID})
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "BAD_REQUEST", resp.Errors[0].Extensions["code"])
+	assert.Equal(t, "неизвестный тип товара", resp.Errors[0].Message)
+
+	unknown := map[string]interface{}{"id": uuid.NewString()}
+	for _, mutation := range []string{
+		`mutation($id: ID!) { closeLastReception(pvzId: $id) { status } }`,
+		`mutation($id: ID!) { addProduct(pvzId: $id, type: "обувь") { type } }`,
+		`mutation($id: ID!) { deleteLastProduct(pvzId: $id) }`,
+	} {
+		resp = exec(t, ts, employee, mutation, unknown)
+		require.Len(t, resp.Errors, 1, mutation)
+		assert.Equal(t, "NOT_FOUND", resp.Errors[0].Extensions["code"], mutation)
+		assert.Equal(t, "ПВЗ не найден", resp.Errors[0].Message, mutation)
+	}
+}
+
+func TestGraphQLBadRequest(t *testing.T) {
+	ts, _ := newTestServer(t)
+	resp, err := http.Post(ts.URL, "application/json", bytes.NewBufferString(`{"query":`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,149 @@
+package graphqlh
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"pvz/internal/models"
+	"pvz/internal/services"
+)
+
+// batchLoader собирает ключи соседних объектов и загружает их одним запросом. Ключи
+// регистрируются через prime, когда родительский резолвер получил список; первый load
+// забирает все зарегистрированные ключи, остальные ждут тот же результат.
+type batchLoader[K comparable, V any] struct {
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error)
+	mu      sync.Mutex
+	pending []K
+	batches map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{fetch: fetch, batches: make(map[K]*batch[K, V])}
+}
+
+func (l *batchLoader[K, V]) prime(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if _, ok := l.batches[key]; !ok {
+			l.batches[key] = nil
+			l.pending = append(l.pending, key)
+		}
+	}
+}
+
+func (l *batchLoader[K, V]) load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if ok && b != nil {
+		l.mu.Unlock()
+		<-b.done
+		return b.values[key], b.err
+	}
+	if !ok {
+		l.pending = append(l.pending, key)
+	}
+	b = &batch[K, V]{done: make(chan struct{})}
+	keys := l.pending
+	l.pending = nil
+	for _, k := range keys {
+		l.batches[k] = b
+	}
+	l.mu.Unlock()
+
+	b.values, b.err = l.fetch(ctx, keys)
+	close(b.done)
+	return b.values[key], b.err
+}
+
+// loaders живут один запрос: кешированные результаты не переживают его.
+type loaders struct {
+	services services.ServiceInterface
+
+	mu         sync.Mutex
+	pvzIds     []uuid.UUID
+	receptions map[receptionsRange]*batchLoader[uuid.UUID, []models.Reception]
+	products   *batchLoader[uuid.UUID, []*models.Product]
+}
+
+// receptionsRange — аргументы PVZ.receptions; для каждого периода свой загрузчик.
+type receptionsRange struct {
+	start, end time.Time
+	hasStart   bool
+	hasEnd     bool
+}
+
+type loadersKey struct{}
+
+func newLoaders(svc services.ServiceInterface) *loaders {
+	l := &loaders{
+		services:   svc,
+		receptions: make(map[receptionsRange]*batchLoader[uuid.UUID, []models.Reception]),
+	}
+	l.products = newBatchLoader(func(ctx context.Context, receptionIds []uuid.UUID) (map[uuid.UUID][]*models.Product, error) {
+		products, err := svc.ListProductsByReceptions(ctx, receptionIds)
+		if err != nil {
+			return nil, newError(err)
+		}
+		return products, nil
+	})
+	return l
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// addPVZs регистрирует ПВЗ из списка, чтобы их приёмки загрузились одним запросом.
+func (l *loaders) addPVZs(pvzIds ...uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pvzIds = append(l.pvzIds, pvzIds...)
+	for _, loader := range l.receptions {
+		loader.prime(pvzIds...)
+	}
+}
+
+func (l *loaders) receptionsFor(startDate, endDate *time.Time) *batchLoader[uuid.UUID, []models.Reception] {
+	var key receptionsRange
+	if startDate != nil {
+		key.start, key.hasStart = startDate.UTC(), true
+	}
+	if endDate != nil {
+		key.end, key.hasEnd = endDate.UTC(), true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if loader, ok := l.receptions[key]; ok {
+		return loader
+	}
+	loader := newBatchLoader(func(ctx context.Context, pvzIds []uuid.UUID) (map[uuid.UUID][]models.Reception, error) {
+		recs, err := l.services.ListReceptionsByPVZs(ctx, pvzIds, startDate, endDate)
+		if err != nil {
+			return nil, newError(err)
+		}
+		for _, pvzRecs := range recs {
+			for _, rec := range pvzRecs {
+				l.products.prime(rec.ID)
+			}
+		}
+		return recs, nil
+	})
+	loader.prime(l.pvzIds...)
+	l.receptions[key] = loader
+	return loader
+}
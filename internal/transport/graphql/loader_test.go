@@ -0,0 +1,60 @@
+package graphqlh
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchLoader(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("primed keys are fetched once", func(t *testing.T) {
+		var calls atomic.Int32
+		var fetched []int
+		loader := newBatchLoader(func(ctx context.Context, keys []int) (map[int]string, error) {
+			calls.Add(1)
+			fetched = keys
+			values := make(map[int]string, len(keys))
+			for _, key := range keys {
+				values[key] = string(rune('a' + key))
+			}
+			return values, nil
+		})
+		loader.prime(0, 1, 2, 1)
+
+		var wg sync.WaitGroup
+		got := make([]string, 3)
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				got[i], _ = loader.load(ctx, i)
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, []string{"a", "b", "c"}, got)
+		assert.Equal(t, int32(1), calls.Load())
+		assert.ElementsMatch(t, []int{0, 1, 2}, fetched)
+
+		value, _ := loader.load(ctx, 4)
+		assert.Equal(t, "e", value, "ключ без prime загружается отдельно")
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("error is shared by the batch", func(t *testing.T) {
+		fetchErr := errors.New("db error")
+		loader := newBatchLoader(func(ctx context.Context, keys []int) (map[int]string, error) {
+			return nil, fetchErr
+		})
+		loader.prime(1, 2)
+		_, err := loader.load(ctx, 1)
+		assert.ErrorIs(t, err, fetchErr)
+		_, err = loader.load(ctx, 2)
+		assert.ErrorIs(t, err, fetchErr)
+	})
+}
@@ -0,0 +1,307 @@
+package graphqlh
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/models"
+	"pvz/internal/services"
+)
+
+type resolver struct {
+	services   services.ServiceInterface
+	dummyLogin bool
+}
+
+// role возвращает роль из токена. Запросы без токена допускаются только для входа и регистрации.
+func role(ctx context.Context) (string, error) {
+	role, _ := ctx.Value(contextkeys.ContextKeyRole).(string)
+	if role == "" {
+		return "", errUnauthenticated
+	}
+	return role, nil
+}
+
+func parseID(id graphql.ID) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(string(id))
+	if err != nil {
+		return uuid.Nil, errInvalidID
+	}
+	return parsed, nil
+}
+
+func timePtr(t *graphql.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	userRole, err := role(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := ctx.Value(contextkeys.ContextKeyUserID).(string)
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errInvalidID
+	}
+	user, err := r.services.GetUser(ctx, id)
+	if errors.Is(err, services.ErrUserNotFound) {
+		return &userResolver{user: models.User{ID: id, Role: userRole}}, nil
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &userResolver{user: *user}, nil
+}
+
+func (r *resolver) Pvzs(ctx context.Context, args struct{ Page, Limit int32 }) ([]*pvzResolver, error) {
+	if _, err := role(ctx); err != nil {
+		return nil, err
+	}
+	pvzs, err := r.services.ListPVZPage(ctx, int(args.Page), int(args.Limit))
+	if err != nil {
+		return nil, newError(err)
+	}
+	ids := make([]uuid.UUID, 0, len(pvzs))
+	resolvers := make([]*pvzResolver, 0, len(pvzs))
+	for _, pvz := range pvzs {
+		ids = append(ids, pvz.ID)
+		resolvers = append(resolvers, &pvzResolver{pvz: pvz})
+	}
+	loadersFrom(ctx).addPVZs(ids...)
+	return resolvers, nil
+}
+
+func (r *resolver) Pvz(ctx context.Context, args struct{ ID graphql.ID }) (*pvzResolver, error) {
+	if _, err := role(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	pvz, err := r.services.GetPVZByID(ctx, id)
+	if errors.Is(err, services.ErrPVZNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &pvzResolver{pvz: *pvz}, nil
+}
+
+func (r *resolver) DummyLogin(args struct{ Role string }) (string, error) {
+	if !r.dummyLogin {
+		return "", &Error{status: http.StatusNotFound, message: "dummyLogin выключен"}
+	}
+	token, err := r.services.DummyLogin(&models.DummyLoginRequest{Role: args.Role})
+	if err != nil {
+		return "", newError(err)
+	}
+	return token, nil
+}
+
+func (r *resolver) Register(ctx context.Context, args models.RegisterRequest) (*userResolver, error) {
+	user, err := r.services.Register(ctx, &args)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &userResolver{user: *user}, nil
+}
+
+func (r *resolver) Login(ctx context.Context, args models.LoginRequest) (string, error) {
+	token, err := r.services.Login(ctx, &args)
+	if err != nil {
+		return "", newError(err)
+	}
+	return token, nil
+}
+
+func (r *resolver) CreatePVZ(ctx context.Context, args struct{ City string }) (*pvzResolver, error) {
+	userRole, err := role(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pvz := models.PVZ{City: args.City}
+	if err := r.services.CreatePVZ(ctx, &pvz, userRole); err != nil {
+		return nil, newError(err)
+	}
+	return &pvzResolver{pvz: pvz}, nil
+}
+
+type pvzArgs struct {
+	PvzID graphql.ID
+}
+
+// pvzMutation проверяет авторизацию и идентификатор ПВЗ для мутаций над приёмкой.
+func pvzMutation(ctx context.Context, pvzID graphql.ID) (string, uuid.UUID, error) {
+	userRole, err := role(ctx)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	id, err := parseID(pvzID)
+	return userRole, id, err
+}
+
+func (r *resolver) CreateReception(ctx context.Context, args pvzArgs) (*receptionResolver, error) {
+	userRole, pvzId, err := pvzMutation(ctx, args.PvzID)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := r.services.CreateReception(ctx, userRole, pvzId)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &receptionResolver{rec: *rec}, nil
+}
+
+func (r *resolver) CloseLastReception(ctx context.Context, args pvzArgs) (*receptionResolver, error) {
+	userRole, pvzId, err := pvzMutation(ctx, args.PvzID)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := r.services.CloseLastReception(ctx, userRole, pvzId)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &receptionResolver{rec: *rec}, nil
+}
+
+func (r *resolver) AddProduct(ctx context.Context, args struct {
+	PvzID graphql.ID
+	Type  string
+}) (*productResolver, error) {
+	userRole, pvzId, err := pvzMutation(ctx, args.PvzID)
+	if err != nil {
+		return nil, err
+	}
+	product, err := r.services.AddProduct(ctx, userRole, pvzId, args.Type)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return &productResolver{product: *product}, nil
+}
+
+func (r *resolver) DeleteLastProduct(ctx context.Context, args pvzArgs) (bool, error) {
+	userRole, pvzId, err := pvzMutation(ctx, args.PvzID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.services.DeleteLastProduct(ctx, userRole, pvzId); err != nil {
+		return false, newError(err)
+	}
+	return true, nil
+}
+
+type userResolver struct {
+	user models.User
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(r.user.ID.String())
+}
+
+func (r *userResolver) Email() *string {
+	if r.user.Email == "" {
+		return nil
+	}
+	return &r.user.Email
+}
+
+func (r *userResolver) Role() string {
+	return r.user.Role
+}
+
+type pvzResolver struct {
+	pvz models.PVZ
+}
+
+func (r *pvzResolver) ID() graphql.ID {
+	return graphql.ID(r.pvz.ID.String())
+}
+
+func (r *pvzResolver) RegistrationDate() graphql.Time {
+	return graphql.Time{Time: r.pvz.RegistrationDate}
+}
+
+func (r *pvzResolver) City() string {
+	return r.pvz.City
+}
+
+func (r *pvzResolver) Receptions(ctx context.Context, args struct{ StartDate, EndDate *graphql.Time }) ([]*receptionResolver, error) {
+	recs, err := loadersFrom(ctx).receptionsFor(timePtr(args.StartDate), timePtr(args.EndDate)).load(ctx, r.pvz.ID)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*receptionResolver, 0, len(recs))
+	for _, rec := range recs {
+		resolvers = append(resolvers, &receptionResolver{rec: rec})
+	}
+	return resolvers, nil
+}
+
+type receptionResolver struct {
+	rec models.Reception
+}
+
+func (r *receptionResolver) ID() graphql.ID {
+	return graphql.ID(r.rec.ID.String())
+}
+
+func (r *receptionResolver) DateTime() graphql.Time {
+	return graphql.Time{Time: r.rec.DateTime}
+}
+
+func (r *receptionResolver) PvzID() graphql.ID {
+	return graphql.ID(r.rec.PVZId.String())
+}
+
+func (r *receptionResolver) Status() string {
+	return r.rec.Status
+}
+
+func (r *receptionResolver) Products(ctx context.Context) ([]*productResolver, error) {
+	products, err := loadersFrom(ctx).products.load(ctx, r.rec.ID)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*productResolver, 0, len(products))
+	for _, product := range products {
+		resolvers = append(resolvers, &productResolver{product: *product})
+	}
+	return resolvers, nil
+}
+
+func (r *receptionResolver) ProductCount(ctx context.Context) (int32, error) {
+	products, err := loadersFrom(ctx).products.load(ctx, r.rec.ID)
+	return int32(len(products)), err
+}
+
+type productResolver struct {
+	product models.Product
+}
+
+func (r *productResolver) ID() graphql.ID {
+	return graphql.ID(r.product.ID.String())
+}
+
+func (r *productResolver) DateTime() graphql.Time {
+	return graphql.Time{Time: r.product.DateTime}
+}
+
+func (r *productResolver) Type() string {
+	return r.product.Type
+}
+
+func (r *productResolver) ReceptionID() graphql.ID {
+	return graphql.ID(r.product.ReceptionId.String())
+}
@@ -0,0 +1,88 @@
+package grpch
+
+import (
+	"net/http"
+
+	"golang.org/x/text/language"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"pvz/internal/problem"
+)
+
+// statusError возвращает статус gRPC с кодом ошибки API в google.rpc.ErrorInfo.
+func statusError(code codes.Code, reason problem.Code, message string) error {
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{
+		Reason: string(reason),
+		Domain: problem.Domain,
+	})
+	if err != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// serviceError переводит ошибку сервисного слоя в статус gRPC. Текст ошибок без
+// кода клиенту не передается.
+func serviceError(err error) error {
+	e := problem.From(err)
+	return statusError(grpcCode(e.Code), e.Code, e.Message)
+}
+
+// localizeError переводит сообщение статуса с кодом ошибки API на язык lang и
+// добавляет его в детали как google.rpc.LocalizedMessage. Статусы без кода
+// возвращаются как есть.
+func localizeError(err error, lang language.Tag) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != problem.Domain {
+			continue
+		}
+		message := problem.Localize(problem.Code(info.Reason), st.Message(), lang)
+		localized, detailsErr := status.New(st.Code(), message).WithDetails(info, &errdetails.LocalizedMessage{
+			Locale:  lang.String(),
+			Message: message,
+		})
+		if detailsErr != nil {
+			return err
+		}
+		return localized.Err()
+	}
+	return err
+}
+
+// grpcCode подбирает код gRPC по коду ошибки API.
+func grpcCode(code problem.Code) codes.Code {
+	switch code {
+	case problem.CodeUserAlreadyExists, problem.CodeReceptionAlreadyOpen:
+		return codes.AlreadyExists
+	case problem.CodeNoActiveReception, problem.CodeNoProductToDelete:
+		return codes.FailedPrecondition
+	}
+	switch code.HTTPStatus() {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
@@ -2,11 +2,24 @@ package grpch
 
 import (
 	"context"
+	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
 	"pvz/internal/services"
 )
 
+// VersionMetadataKey — заголовок ответа GetPVZList с версией списка ПВЗ. Версия меняется
+// вместе с данными, как ETag в REST, и клиент может не разбирать ответ повторно.
+const VersionMetadataKey = "x-data-version"
+
 type GrpcServer struct {
 	pb.UnimplementedPVZServiceServer
 	services services.ServiceInterface
@@ -19,9 +32,71 @@ func NewGrpcServer(services services.ServiceInterface) *GrpcServer {
 func (s *GrpcServer) GetPVZList(ctx context.Context, req *pb.GetPVZListRequest) (*pb.GetPVZListResponse, error) {
 	pvzs, err := s.services.GetPVZ(ctx)
 	if err != nil {
-		return nil, err
+		return nil, serviceError(err)
 	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(VersionMetadataKey, services.PVZListVersion(pvzs)))
 	return &pb.GetPVZListResponse{
 		Pvzs: pvzs,
 	}, nil
 }
+
+// StreamPVZs отправляет ПВЗ по мере чтения из курсора. Если клиент отменил вызов,
+// чтение прекращается и возвращается код отмены, а не внутренняя ошибка.
+func (s *GrpcServer) StreamPVZs(req *pb.StreamPVZsRequest, stream pb.PVZService_StreamPVZsServer) error {
+	ctx := stream.Context()
+	var fromStr, toStr string
+	if req.GetRegisteredFrom() != nil {
+		fromStr = req.GetRegisteredFrom().AsTime().Format(time.RFC3339Nano)
+	}
+	if req.GetRegisteredTo() != nil {
+		toStr = req.GetRegisteredTo().AsTime().Format(time.RFC3339Nano)
+	}
+	sent := 0
+	err := s.services.StreamPVZs(ctx, req.GetCity(), fromStr, toStr, func(pvz *models.PVZ) error {
+		sent++
+		return stream.Send(&pb.PVZ{
+			Id:               pvz.ID.String(),
+			RegistrationDate: timestamppb.New(pvz.RegistrationDate),
+			City:             pvz.City,
+		})
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			logger.FromContext(ctx).WithField("sent", sent).Info("Клиент прервал поток ПВЗ")
+			return status.FromContextError(ctxErr).Err()
+		}
+		return serviceError(err)
+	}
+	return nil
+}
+
+func (s *GrpcServer) GetIntakeAnalytics(ctx context.Context, req *pb.GetIntakeAnalyticsRequest) (*pb.GetIntakeAnalyticsResponse, error) {
+	role, _ := ctx.Value(contextkeys.ContextKeyRole).(string)
+	var fromStr, toStr string
+	if req.GetFrom() != nil {
+		fromStr = req.GetFrom().AsTime().Format(time.RFC3339Nano)
+	}
+	if req.GetTo() != nil {
+		toStr = req.GetTo().AsTime().Format(time.RFC3339Nano)
+	}
+	rows, err := s.services.IntakeAnalytics(ctx, role, fromStr, toStr, req.GetBucket(), req.GetGroupBy(), req.GetCity(), req.GetPvzId())
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	resp := &pb.GetIntakeAnalyticsResponse{Rows: make([]*pb.IntakeRow, 0, len(rows))}
+	for _, row := range rows {
+		pbRow := &pb.IntakeRow{
+			Bucket:                      timestamppb.New(row.Bucket),
+			City:                        row.City,
+			ProductType:                 row.ProductType,
+			Receptions:                  int64(row.Receptions),
+			Products:                    int64(row.Products),
+			AvgReceptionDurationSeconds: row.AvgReceptionDurationSeconds,
+		}
+		if row.PVZId != nil {
+			pbRow.PvzId = row.PVZId.String()
+		}
+		resp.Rows = append(resp.Rows, pbRow)
+	}
+	return resp, nil
+}
@@ -3,16 +3,29 @@ package grpch
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"pvz/internal/contextkeys"
+	"pvz/internal/events"
 	"pvz/internal/models"
 	pb "pvz/internal/pb/pvz_v1"
+	"pvz/internal/problem"
+	"pvz/internal/services"
 )
 
 type MockService struct {
@@ -25,42 +38,143 @@ func (m *MockService) GetPVZ(ctx context.Context) ([]*pb.PVZ, error) {
 	return args.Get(0).([]*pb.PVZ), args.Error(1)
 }
 
-func (m *MockService) DummyLogin(req *models.DummyLoginRequest) (string, int, error) {
-	return "", 0, nil
+func (m *MockService) ListPVZVersion(ctx context.Context, startDateStr, endDateStr, pageStr, limitStr string) (string, error) {
+	args := m.Called(ctx, startDateStr, endDateStr, pageStr, limitStr)
+	return args.String(0), args.Error(1)
 }
 
-func (m *MockService) Register(ctx context.Context, req *models.RegisterRequest) (*models.User, int, error) {
-	return nil, 0, nil
+func (m *MockService) StreamPVZs(ctx context.Context, city, fromStr, toStr string, fn func(pvz *models.PVZ) error) error {
+	args := m.Called(ctx, city, fromStr, toStr)
+	if pvzs, ok := args.Get(0).([]models.PVZ); ok {
+		for i := range pvzs {
+			if err := fn(&pvzs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockService) ExportProducts(ctx context.Context, fromStr, toStr, city, pvzIdStr string, fn func(row *models.ExportRow) error) error {
+	return nil
+}
+
+func (m *MockService) IntakeAnalytics(ctx context.Context, role, fromStr, toStr, bucket string, groupBy []string, city, pvzIdStr string) ([]models.IntakeRow, error) {
+	args := m.Called(ctx, role, fromStr, toStr, bucket, groupBy, city, pvzIdStr)
+	rows, _ := args.Get(0).([]models.IntakeRow)
+	return rows, args.Error(1)
+}
+
+func (m *MockService) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	return nil, nil
+}
+
+func (m *MockService) ListPVZPage(ctx context.Context, page, limit int) ([]models.PVZ, error) {
+	return nil, nil
+}
+
+func (m *MockService) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (*models.PVZ, error) {
+	return nil, nil
+}
+
+func (m *MockService) ListReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (map[uuid.UUID][]models.Reception, error) {
+	return nil, nil
+}
+
+func (m *MockService) ListProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (map[uuid.UUID][]*models.Product, error) {
+	return nil, nil
+}
+
+func (m *MockService) SubscribePVZEvents(ctx context.Context, role string, pvzId uuid.UUID, lastEventID string) (*events.Subscription, error) {
+	return nil, nil
+}
+
+func (m *MockService) DummyLogin(req *models.DummyLoginRequest) (string, error) {
+	return "", nil
 }
 
-func (m *MockService) Login(ctx context.Context, req *models.LoginRequest) (string, int, error) {
-	return "", 0, nil
+func (m *MockService) Register(ctx context.Context, req *models.RegisterRequest) (*models.User, error) {
+	return nil, nil
 }
 
-func (m *MockService) CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) (int, error) {
-	return 0, nil
+func (m *MockService) Login(ctx context.Context, req *models.LoginRequest) (string, error) {
+	return "", nil
 }
 
-func (m *MockService) ListPVZ(ctx context.Context, startDateStr, endDateStr, pageStr, limitStr string) ([]*models.PVZResponse, int, error) {
-	return nil, 0, nil
+func (m *MockService) CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) error {
+	return nil
 }
 
-func (m *MockService) CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, int, error) {
-	return nil, 0, nil
+func (m *MockService) ListPVZ(ctx context.Context, startDateStr, endDateStr, pageStr, limitStr string) ([]*models.PVZResponse, error) {
+	return nil, nil
 }
 
-func (m *MockService) DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) (int, error) {
-	return 0, nil
+func (m *MockService) CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, error) {
+	return nil, nil
 }
 
-func (m *MockService) CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, int, error) {
-	return nil, 0, nil
+func (m *MockService) DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) error {
+	return nil
 }
 
-func (m *MockService) AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (*models.Product, int, error) {
-	return nil, 0, nil
+func (m *MockService) CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, error) {
+	return nil, nil
 }
 
+func (m *MockService) AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (*models.Product, error) {
+	return nil, nil
+}
+
+// errorReason достаёт код ошибки API из google.rpc.ErrorInfo в деталях статуса.
+func errorReason(t *testing.T, err error) problem.Code {
+	t.Helper()
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, problem.Domain, info.GetDomain())
+			return problem.Code(info.GetReason())
+		}
+	}
+	t.Fatalf("в статусе %v нет ErrorInfo", err)
+	return ""
+}
+
+// В gRPC API нет методов приёмок и товаров, поэтому проверяется сам перевод ошибок сервиса в статусы.
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{services.ErrPVZNotFound.Wrap(errors.New("запись не найдена")), codes.NotFound},
+		{services.ErrNotFound, codes.NotFound},
+		{services.ErrConflict, codes.Aborted},
+		{services.ErrNoActiveReception, codes.FailedPrecondition},
+		{services.ErrReceptionAlreadyOpen, codes.AlreadyExists},
+		{services.ErrUnavailable, codes.Unavailable},
+		{errors.New("ошибка закрытия приёмки: conn reset"), codes.Internal},
+	}
+	for _, tt := range tests {
+		err := serviceError(tt.err)
+		assert.Equal(t, tt.code, status.Code(err), tt.err.Error())
+		assert.Equal(t, problem.From(tt.err).Code, errorReason(t, err))
+	}
+}
+
+// headerStream запоминает заголовки, которые обработчик выставил через grpc.SetHeader.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "/pvz.v1.PVZService/GetPVZList" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
 func TestGetPVZList(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockSvc := new(MockService)
@@ -75,28 +189,172 @@ func TestGetPVZList(t *testing.T) {
 		server := NewGrpcServer(mockSvc)
 		req := &pb.GetPVZListRequest{}
 
-		resp, err := server.GetPVZList(context.Background(), req)
+		stream := &headerStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		resp, err := server.GetPVZList(ctx, req)
 		assert.NoError(t, err, "Ожидалась ошибка nil")
 		assert.NotNil(t, resp, "Ожидался ненулевой ответ")
 		assert.Len(t, resp.Pvzs, 1, "Ожидался один элемент в списке")
 		assert.Equal(t, expectedPVZ.Id, resp.Pvzs[0].Id, "Неверный Id в ответе")
 		assert.Equal(t, expectedPVZ.City, resp.Pvzs[0].City, "Неверный город в ответе")
+		assert.Equal(t, []string{services.PVZListVersion(resp.Pvzs)}, stream.header.Get(VersionMetadataKey), "версия списка передается в заголовке ответа")
 
 		mockSvc.AssertExpectations(t)
 	})
 
 	t.Run("error", func(t *testing.T) {
 		mockSvc := new(MockService)
-		errMessage := "ошибка получения ПВЗ"
-		mockSvc.On("GetPVZ", mock.Anything).Return(([]*pb.PVZ)(nil), errors.New(errMessage))
+		mockSvc.On("GetPVZ", mock.Anything).Return(([]*pb.PVZ)(nil), errors.New("ошибка получения ПВЗ: conn reset"))
 
 		server := NewGrpcServer(mockSvc)
 		req := &pb.GetPVZListRequest{}
 
 		resp, err := server.GetPVZList(context.Background(), req)
 		assert.Nil(t, resp, "В случае ошибки ответ должен быть nil")
-		assert.EqualError(t, err, errMessage, "Неверное сообщение об ошибке")
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "внутренняя ошибка сервера", status.Convert(err).Message(), "Текст ошибки без кода не должен попадать к клиенту")
+		assert.Equal(t, problem.CodeInternal, errorReason(t, err))
 
 		mockSvc.AssertExpectations(t)
 	})
+
+	t.Run("database unavailable", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("GetPVZ", mock.Anything).Return(([]*pb.PVZ)(nil), services.ErrUnavailable.Wrap(errors.New("connection refused")))
+
+		_, err := NewGrpcServer(mockSvc).GetPVZList(context.Background(), &pb.GetPVZListRequest{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, problem.CodeUnavailable, errorReason(t, err))
+		mockSvc.AssertExpectations(t)
+	})
+}
+
+func TestGetIntakeAnalytics(t *testing.T) {
+	from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	pvzId := uuid.New()
+
+	t.Run("success", func(t *testing.T) {
+		mockSvc := new(MockService)
+		avg := 120.0
+		mockSvc.On("IntakeAnalytics", mock.Anything, "moderator", "2025-04-01T00:00:00Z", "", "hour", []string{"pvz"}, "", "").
+			Return([]models.IntakeRow{
+				{Bucket: from, PVZId: &pvzId, Receptions: 1, Products: 4, AvgReceptionDurationSeconds: &avg},
+				{Bucket: from.Add(time.Hour), PVZId: &pvzId, Receptions: 1},
+			}, nil)
+
+		ctx := context.WithValue(context.Background(), contextkeys.ContextKeyRole, "moderator")
+		resp, err := NewGrpcServer(mockSvc).GetIntakeAnalytics(ctx, &pb.GetIntakeAnalyticsRequest{
+			From:    timestamppb.New(from),
+			Bucket:  "hour",
+			GroupBy: []string{"pvz"},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Rows, 2)
+		assert.Equal(t, pvzId.String(), resp.Rows[0].PvzId)
+		assert.Equal(t, int64(4), resp.Rows[0].Products)
+		assert.Equal(t, 120.0, resp.Rows[0].GetAvgReceptionDurationSeconds())
+		assert.Nil(t, resp.Rows[1].AvgReceptionDurationSeconds)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("without role", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("IntakeAnalytics", mock.Anything, "", "", "", "", []string(nil), "", "").
+			Return(nil, services.ErrForbidden)
+
+		_, err := NewGrpcServer(mockSvc).GetIntakeAnalytics(context.Background(), &pb.GetIntakeAnalyticsRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, problem.CodeForbidden, errorReason(t, err))
+	})
+}
+
+func TestStreamPVZs(t *testing.T) {
+	registered := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	serve := func(t *testing.T, mockSvc *MockService) (pb.PVZServiceClient, chan error) {
+		handlerErr := make(chan error, 1)
+		capture := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			err := handler(srv, ss)
+			handlerErr <- err
+			return err
+		}
+		server := grpc.NewServer(grpc.ChainStreamInterceptor(RequestIDStreamInterceptor, capture))
+		pb.RegisterPVZServiceServer(server, NewGrpcServer(mockSvc))
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go server.Serve(lis)
+		t.Cleanup(server.Stop)
+
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewPVZServiceClient(conn), handlerErr
+	}
+
+	t.Run("success", func(t *testing.T) {
+		pvzs := []models.PVZ{
+			{ID: uuid.New(), City: "Казань", RegistrationDate: registered},
+			{ID: uuid.New(), City: "Казань", RegistrationDate: registered.Add(time.Hour)},
+		}
+		mockSvc := new(MockService)
+		mockSvc.On("StreamPVZs", mock.Anything, "Казань", "2025-04-01T00:00:00Z", "").Return(pvzs, nil)
+		client, _ := serve(t, mockSvc)
+
+		stream, err := client.StreamPVZs(context.Background(), &pb.StreamPVZsRequest{
+			City:           "Казань",
+			RegisteredFrom: timestamppb.New(registered),
+		})
+		require.NoError(t, err)
+		var got []string
+		for {
+			pvz, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			got = append(got, pvz.Id)
+		}
+		assert.Equal(t, []string{pvzs[0].ID.String(), pvzs[1].ID.String()}, got)
+		header, err := stream.Header()
+		require.NoError(t, err)
+		assert.NotEmpty(t, header.Get(RequestIDMetadataKey))
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("StreamPVZs", mock.Anything, "Новосибирск", "", "").Return(nil, problem.New(problem.CodeUnknownCity, "неизвестный город"))
+		client, _ := serve(t, mockSvc)
+
+		stream, err := client.StreamPVZs(context.Background(), &pb.StreamPVZsRequest{City: "Новосибирск"})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, problem.CodeUnknownCity, errorReason(t, err))
+	})
+
+	t.Run("client cancellation", func(t *testing.T) {
+		// Ответов больше, чем вмещает окно HTTP/2, поэтому сервер упирается в Send и видит отмену.
+		pvzs := make([]models.PVZ, 100000)
+		for i := range pvzs {
+			pvzs[i] = models.PVZ{ID: uuid.New(), City: "Москва", RegistrationDate: registered}
+		}
+		mockSvc := new(MockService)
+		mockSvc.On("StreamPVZs", mock.Anything, "", "", "").Return(pvzs, nil)
+		client, handlerErr := serve(t, mockSvc)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := client.StreamPVZs(ctx, &pb.StreamPVZsRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		cancel()
+
+		select {
+		case err := <-handlerErr:
+			assert.Equal(t, codes.Canceled, status.Code(err))
+		case <-time.After(5 * time.Second):
+			t.Fatal("сервер не прекратил поток после отмены")
+		}
+	})
 }
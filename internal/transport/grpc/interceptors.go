@@ -0,0 +1,178 @@
+package grpch
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+	"pvz/internal/metrics"
+	"pvz/internal/problem"
+	"pvz/internal/ratelimit"
+)
+
+const (
+	RequestIDMetadataKey = "x-request-id"
+	// LanguageMetadataKey задает язык сообщений об ошибках в формате Accept-Language.
+	LanguageMetadataKey = "accept-language"
+)
+
+func RequestIDInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withRequestID(ctx, info.FullMethod), req)
+}
+
+func RequestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ServerStream: ss, ctx: withRequestID(ss.Context(), info.FullMethod)})
+}
+
+// serverStream подменяет контекст потока, чтобы стрим-перехватчики могли дополнять его так же,
+// как унарные дополняют ctx.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func withRequestID(ctx context.Context, method string) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !logger.ValidRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID))
+
+	ctx = context.WithValue(ctx, contextkeys.ContextKeyRequestID, requestID)
+	return logger.WithFields(ctx, logrus.Fields{
+		"request_id": requestID,
+		"route":      method,
+	})
+}
+
+// LanguageInterceptor переводит сообщения ошибок на язык из метаданных accept-language.
+// Ставится в начало цепочки, чтобы переводились и ошибки остальных перехватчиков.
+func LanguageInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, localizeError(err, incomingLanguage(ctx))
+}
+
+func LanguageStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return localizeError(handler(srv, ss), incomingLanguage(ss.Context()))
+}
+
+func incomingLanguage(ctx context.Context) language.Tag {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return problem.Negotiate(strings.Join(md.Get(LanguageMetadataKey), ","))
+}
+
+// ClientCertInterceptor сопоставляет CN проверенного клиентского сертификата с ролью
+// и кладет ее в контекст так же, как это делает AuthMiddleware для JWT.
+func ClientCertInterceptor(roles map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := withClientCertRole(ctx, roles)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func ClientCertStreamInterceptor(roles map[string]string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := withClientCertRole(ss.Context(), roles)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func withClientCertRole(ctx context.Context, roles map[string]string) (context.Context, error) {
+	var tlsInfo credentials.TLSInfo
+	if p, ok := peer.FromContext(ctx); ok {
+		tlsInfo, _ = p.AuthInfo.(credentials.TLSInfo)
+	}
+	if len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, statusError(codes.Unauthenticated, problem.CodeUnauthorized, "требуется клиентский сертификат")
+	}
+	cn := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+	role, ok := roles[cn]
+	if !ok {
+		logger.FromContext(ctx).WithField("client_cn", cn).Warn("Сертификат клиента не сопоставлен с ролью")
+		return nil, statusError(codes.PermissionDenied, problem.CodeForbidden, "сертификат клиента не сопоставлен с ролью")
+	}
+	ctx = context.WithValue(ctx, contextkeys.ContextKeyUserID, "cert:"+cn)
+	ctx = context.WithValue(ctx, contextkeys.ContextKeyRole, role)
+	return logger.WithFields(ctx, logrus.Fields{
+		"client_cn": cn,
+		"role":      role,
+	}), nil
+}
+
+func rateLimitKey(ctx context.Context) string {
+	if userID, ok := ctx.Value(contextkeys.ContextKeyUserID).(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "ip:" + host
+		}
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:unknown"
+}
+
+func RateLimitInterceptor(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkRateLimit(ctx, limiter, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor учитывает открытие потока как один запрос.
+func RateLimitStreamInterceptor(limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkRateLimit(ss.Context(), limiter, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func checkRateLimit(ctx context.Context, limiter *ratelimit.Limiter, method string) error {
+	if !limiter.Enabled() {
+		return nil
+	}
+	allowed, retryAfter, rule := limiter.Allow("", method, rateLimitKey(ctx))
+	if rule == "" {
+		return nil
+	}
+	if allowed {
+		metrics.RateLimitDecisions.WithLabelValues(method, ratelimit.DecisionAllowed).Inc()
+		return nil
+	}
+	metrics.RateLimitDecisions.WithLabelValues(method, ratelimit.DecisionLimited).Inc()
+	logger.FromContext(ctx).WithField("rate_limit_rule", rule).Warn("Превышен лимит запросов")
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))))
+	return statusError(codes.ResourceExhausted, problem.CodeRateLimited, "слишком много запросов")
+}
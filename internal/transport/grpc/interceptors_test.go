@@ -0,0 +1,132 @@
+package grpch
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+	"pvz/internal/problem"
+	"pvz/internal/ratelimit"
+)
+
+func TestRequestIDInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/pvz.v1.PVZService/GetPVZList"}
+
+	t.Run("Uses metadata request id", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "req-1"))
+		_, err := RequestIDInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			assert.Equal(t, "req-1", ctx.Value(contextkeys.ContextKeyRequestID))
+			fields := logger.FromContext(ctx).Data
+			assert.Equal(t, "req-1", fields["request_id"])
+			assert.Equal(t, info.FullMethod, fields["route"])
+			return nil, nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Generates request id", func(t *testing.T) {
+		_, err := RequestIDInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			id, _ := ctx.Value(contextkeys.ContextKeyRequestID).(string)
+			assert.NotEmpty(t, id)
+			return nil, nil
+		})
+		assert.NoError(t, err)
+	})
+}
+
+func TestLanguageInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/pvz.v1.PVZService/GetPVZList"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, statusError(codes.FailedPrecondition, problem.CodeNoActiveReception, "у ПВЗ нет открытой приёмки")
+	}
+	localized := func(t *testing.T, err error) *errdetails.LocalizedMessage {
+		t.Helper()
+		for _, detail := range status.Convert(err).Details() {
+			if m, ok := detail.(*errdetails.LocalizedMessage); ok {
+				return m
+			}
+		}
+		t.Fatal("в статусе нет google.rpc.LocalizedMessage")
+		return nil
+	}
+
+	t.Run("English", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(LanguageMetadataKey, "en-US"))
+		_, err := LanguageInterceptor(ctx, nil, info, handler)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Equal(t, "the pickup point has no open reception", status.Convert(err).Message())
+		assert.Equal(t, problem.CodeNoActiveReception, errorReason(t, err))
+		assert.Equal(t, "en", localized(t, err).Locale)
+	})
+
+	t.Run("Russian by default", func(t *testing.T) {
+		_, err := LanguageInterceptor(context.Background(), nil, info, handler)
+		assert.Equal(t, "у ПВЗ нет открытой приёмки", status.Convert(err).Message())
+		assert.Equal(t, "ru", localized(t, err).Locale)
+	})
+
+	t.Run("Status without code", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(LanguageMetadataKey, "en"))
+		_, err := LanguageInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.Canceled, "context canceled")
+		})
+		assert.Equal(t, codes.Canceled, status.Code(err))
+		assert.Equal(t, "context canceled", status.Convert(err).Message())
+	})
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/pvz.v1.PVZService/GetPVZList"}
+	limiter := ratelimit.NewLimiter(map[string]ratelimit.Rule{
+		info.FullMethod: {Rate: 0.1, Burst: 1},
+	})
+	interceptor := RateLimitInterceptor(limiter)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 1234}})
+
+	resp, err := interceptor(ctx, nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	resp, err = interceptor(ctx, nil, info, handler)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, problem.CodeRateLimited, errorReason(t, err))
+
+	otherPeer := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.6"), Port: 1234}})
+	_, err = interceptor(otherPeer, nil, info, handler)
+	assert.NoError(t, err)
+
+	_, err = RateLimitInterceptor(ratelimit.NewLimiter(nil))(ctx, nil, info, handler)
+	assert.NoError(t, err)
+}
+
+func TestRateLimitStreamInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/pvz.v1.PVZService/StreamPVZs", IsServerStream: true}
+	interceptor := RateLimitStreamInterceptor(ratelimit.NewLimiter(map[string]ratelimit.Rule{
+		info.FullMethod: {Rate: 0.1, Burst: 1},
+	}))
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 1234}})
+	ss := &serverStream{ctx: ctx}
+	calls := 0
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		calls++
+		return nil
+	}
+
+	assert.NoError(t, interceptor(nil, ss, info, handler))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(interceptor(nil, ss, info, handler)))
+	assert.Equal(t, 1, calls)
+}
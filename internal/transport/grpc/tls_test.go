@@ -0,0 +1,69 @@
+package grpch
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"pvz/internal/contextkeys"
+	pb "pvz/internal/pb/pvz_v1"
+	"pvz/internal/tlsutil"
+	"pvz/internal/tlsutil/tlstest"
+)
+
+func TestClientCertInterceptor(t *testing.T) {
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t)
+	certFile, keyFile := ca.Issue(t, "localhost").WriteFiles(t, dir)
+	reloader, err := tlsutil.NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	tlsConfig, err := tlsutil.ServerConfig(reloader, ca.WriteFile(t, dir))
+	require.NoError(t, err)
+
+	var gotRole, gotUser interface{}
+	capture := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		gotRole = ctx.Value(contextkeys.ContextKeyRole)
+		gotUser = ctx.Value(contextkeys.ContextKeyUserID)
+		return handler(ctx, req)
+	}
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.ChainUnaryInterceptor(ClientCertInterceptor(map[string]string{"terminal-1": "employee"}), capture),
+	)
+	mockSvc := new(MockService)
+	mockSvc.On("GetPVZ", mock.Anything).Return([]*pb.PVZ{}, nil)
+	pb.RegisterPVZServiceServer(server, NewGrpcServer(mockSvc))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	call := func(clientCN string) error {
+		clientTLS := &tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"}
+		clientTLS.Certificates = []tls.Certificate{ca.Issue(t, clientCN).TLSCertificate(t)}
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = pb.NewPVZServiceClient(conn).GetPVZList(context.Background(), &pb.GetPVZListRequest{})
+		return err
+	}
+
+	assert.NoError(t, call("terminal-1"))
+	assert.Equal(t, "employee", gotRole)
+	assert.Equal(t, "cert:terminal-1", gotUser)
+
+	err = call("unknown-terminal")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = ClientCertInterceptor(nil)(context.Background(), nil, &grpc.UnaryServerInfo{}, nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,33 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+	"pvz/internal/problem"
+)
+
+func (h *Handler) IntakeAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var groupBy []string
+	for _, value := range q["groupBy"] {
+		groupBy = append(groupBy, strings.Split(value, ",")...)
+	}
+	role := r.Context().Value(contextkeys.ContextKeyRole).(string)
+	rows, err := h.services.IntakeAnalytics(r.Context(), role, q.Get("from"), q.Get("to"), q.Get("bucket"), groupBy, q.Get("city"), q.Get("pvzId"))
+	if err != nil {
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка IntakeAnalytics")
+		return
+	}
+	logger.FromContext(r.Context()).WithFields(logrus.Fields{
+		"rows": len(rows),
+	}).Info("IntakeAnalytics выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rows)
+}
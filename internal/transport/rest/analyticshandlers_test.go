@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/models"
+	"pvz/internal/services"
+)
+
+func TestIntakeAnalyticsHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		bucket := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+		avg := 90.5
+		mockSvc := new(MockService)
+		mockSvc.On("IntakeAnalytics", mock.Anything, "moderator", "2025-04-01T00:00:00Z", "", "week", []string{"city", "product_type", "pvz"}, "", "").
+			Return([]models.IntakeRow{{Bucket: bucket, City: "Москва", Receptions: 2, Products: 7, AvgReceptionDurationSeconds: &avg}}, nil)
+		req := httptest.NewRequest(http.MethodGet, "/analytics/intake?from=2025-04-01T00:00:00Z&bucket=week&groupBy=city,product_type&groupBy=pvz", nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.ContextKeyRole, "moderator"))
+		rr := httptest.NewRecorder()
+
+		NewHandler(mockSvc).IntakeAnalyticsHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `[{"bucket":"2025-04-01T00:00:00Z","city":"Москва","receptions":2,"products":7,"avgReceptionDurationSeconds":90.5}]`, rr.Body.String())
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("IntakeAnalytics", mock.Anything, "employee", "", "", "", []string(nil), "", "").
+			Return(nil, services.ErrForbidden)
+		req := httptest.NewRequest(http.MethodGet, "/analytics/intake", nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.ContextKeyRole, "employee"))
+		rr := httptest.NewRecorder()
+
+		NewHandler(mockSvc).IntakeAnalyticsHandler(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		var errResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+		assert.Equal(t, "доступ запрещен", errResp.Message)
+	})
+}
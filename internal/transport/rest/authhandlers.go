@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/sirupsen/logrus"
-
+	"pvz/internal/logger"
 	"pvz/internal/models"
+	"pvz/internal/problem"
 	"pvz/internal/services"
 )
 
@@ -20,69 +20,52 @@ func NewHandler(services services.ServiceInterface) *Handler {
 
 func (h *Handler) DummyLoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.DummyLoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный запрос"})
-		logrus.WithError(err).Error("Ошибка DummyLogin")
+	if reqErr := decodeJSON(r, &req); reqErr != nil {
+		writeRequestError(w, r, reqErr, "DummyLogin")
 		return
 	}
-	token, status, err := h.services.DummyLogin(&req)
+	token, err := h.services.DummyLogin(&req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка DummyLogin")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка DummyLogin")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("DummyLogin выполнен успешно")
+	logger.FromContext(r.Context()).Info("DummyLogin выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(token)
 }
 
 func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный запрос"})
-		logrus.WithError(err).Error("Ошибка Register")
+	if reqErr := decodeJSON(r, &req); reqErr != nil {
+		writeRequestError(w, r, reqErr, "Register")
 		return
 	}
-	user, status, err := h.services.Register(r.Context(), &req)
+	user, err := h.services.Register(r.Context(), &req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка Register")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка Register")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("Register выполнен успешно")
+	logger.FromContext(r.Context()).Info("Register выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(user)
 }
 
 func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный запрос"})
-		logrus.WithError(err).Error("Ошибка Login")
+	if reqErr := decodeJSON(r, &req); reqErr != nil {
+		writeRequestError(w, r, reqErr, "Login")
 		return
 	}
-	token, status, err := h.services.Login(r.Context(), &req)
+	token, err := h.services.Login(r.Context(), &req)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка Login")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка Login")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("Login выполнен успешно")
+	logger.FromContext(r.Context()).Info("Login выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(token)
 }
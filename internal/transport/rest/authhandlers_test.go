@@ -4,87 +4,142 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	pb "pvz/internal/pb/pvz_v1"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"pvz/internal/events"
 	"pvz/internal/models"
+	"pvz/internal/services"
 )
 
 type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) DummyLogin(req *models.DummyLoginRequest) (string, int, error) {
+func (m *MockService) DummyLogin(req *models.DummyLoginRequest) (string, error) {
 	args := m.Called(req)
-	return args.String(0), args.Int(1), args.Error(2)
+	return args.String(0), args.Error(1)
 }
 
-func (m *MockService) Register(ctx context.Context, req *models.RegisterRequest) (*models.User, int, error) {
+func (m *MockService) Register(ctx context.Context, req *models.RegisterRequest) (*models.User, error) {
 	args := m.Called(ctx, req)
 	var user *models.User
 	if u := args.Get(0); u != nil {
 		user = u.(*models.User)
 	}
-	return user, args.Int(1), args.Error(2)
+	return user, args.Error(1)
 }
 
-func (m *MockService) Login(ctx context.Context, req *models.LoginRequest) (string, int, error) {
+func (m *MockService) Login(ctx context.Context, req *models.LoginRequest) (string, error) {
 	args := m.Called(ctx, req)
-	return args.String(0), args.Int(1), args.Error(2)
+	return args.String(0), args.Error(1)
 }
 
-func (m *MockService) CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) (int, error) {
+func (m *MockService) CreatePVZ(ctx context.Context, pvz *models.PVZ, role string) error {
 	args := m.Called(ctx, pvz, role)
-	return args.Int(0), args.Error(1)
+	return args.Error(0)
 }
 
-func (m *MockService) ListPVZ(ctx context.Context, startDateStr, endDateStr, pageStr, limitStr string) ([]*models.PVZResponse, int, error) {
+func (m *MockService) ListPVZ(ctx context.Context, startDateStr, endDateStr, pageStr, limitStr string) ([]*models.PVZResponse, error) {
 	args := m.Called(ctx, startDateStr, endDateStr, pageStr, limitStr)
 	var res []*models.PVZResponse
 	if args.Get(0) != nil {
 		res = args.Get(0).([]*models.PVZResponse)
 	}
-	return res, args.Int(1), args.Error(2)
+	return res, args.Error(1)
 }
 
-func (m *MockService) CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, int, error) {
+func (m *MockService) ListPVZVersion(ctx context.Context, startDateStr, endDateStr, pageStr, limitStr string) (string, error) {
+	args := m.Called(ctx, startDateStr, endDateStr, pageStr, limitStr)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockService) CloseLastReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, error) {
 	args := m.Called(ctx, role, pvzId)
 	var rec *models.Reception
 	if r := args.Get(0); r != nil {
 		rec = r.(*models.Reception)
 	}
-	return rec, args.Int(1), args.Error(2)
+	return rec, args.Error(1)
 }
 
-func (m *MockService) DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) (int, error) {
+func (m *MockService) DeleteLastProduct(ctx context.Context, role string, pvzId uuid.UUID) error {
 	args := m.Called(ctx, role, pvzId)
-	return args.Int(0), args.Error(1)
+	return args.Error(0)
 }
 
-func (m *MockService) CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, int, error) {
+func (m *MockService) CreateReception(ctx context.Context, role string, pvzId uuid.UUID) (*models.Reception, error) {
 	args := m.Called(ctx, role, pvzId)
 	var rec *models.Reception
 	if r := args.Get(0); r != nil {
 		rec = r.(*models.Reception)
 	}
-	return rec, args.Int(1), args.Error(2)
+	return rec, args.Error(1)
 }
 
-func (m *MockService) AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (*models.Product, int, error) {
+func (m *MockService) AddProduct(ctx context.Context, role string, pvzId uuid.UUID, producttype string) (*models.Product, error) {
 	args := m.Called(ctx, role, pvzId, producttype)
 	var product *models.Product
 	if args.Get(0) != nil {
 		product = args.Get(0).(*models.Product)
 	}
-	return product, args.Int(1), args.Error(2)
+	return product, args.Error(1)
+}
+func (m *MockService) StreamPVZs(ctx context.Context, city, fromStr, toStr string, fn func(pvz *models.PVZ) error) error {
+	return nil
+}
+
+func (m *MockService) ExportProducts(ctx context.Context, fromStr, toStr, city, pvzIdStr string, fn func(row *models.ExportRow) error) error {
+	args := m.Called(ctx, fromStr, toStr, city, pvzIdStr)
+	if rows, ok := args.Get(0).([]models.ExportRow); ok {
+		for i := range rows {
+			if err := fn(&rows[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
 }
+
+func (m *MockService) IntakeAnalytics(ctx context.Context, role, fromStr, toStr, bucket string, groupBy []string, city, pvzIdStr string) ([]models.IntakeRow, error) {
+	args := m.Called(ctx, role, fromStr, toStr, bucket, groupBy, city, pvzIdStr)
+	rows, _ := args.Get(0).([]models.IntakeRow)
+	return rows, args.Error(1)
+}
+
+func (m *MockService) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	return nil, nil
+}
+
+func (m *MockService) ListPVZPage(ctx context.Context, page, limit int) ([]models.PVZ, error) {
+	return nil, nil
+}
+
+func (m *MockService) GetPVZByID(ctx context.Context, pvzId uuid.UUID) (*models.PVZ, error) {
+	return nil, nil
+}
+
+func (m *MockService) ListReceptionsByPVZs(ctx context.Context, pvzIds []uuid.UUID, startDate, endDate *time.Time) (map[uuid.UUID][]models.Reception, error) {
+	return nil, nil
+}
+
+func (m *MockService) ListProductsByReceptions(ctx context.Context, receptionIds []uuid.UUID) (map[uuid.UUID][]*models.Product, error) {
+	return nil, nil
+}
+
+func (m *MockService) SubscribePVZEvents(ctx context.Context, role string, pvzId uuid.UUID, lastEventID string) (*events.Subscription, error) {
+	args := m.Called(ctx, role, pvzId, lastEventID)
+	sub, _ := args.Get(0).(*events.Subscription)
+	return sub, args.Error(1)
+}
+
 func (m *MockService) GetPVZ(ctx context.Context) ([]*pb.PVZ, error) {
 	return nil, nil
 }
@@ -113,7 +168,7 @@ func TestDummyLoginHandler(t *testing.T) {
 
 		mockSvc := new(MockService)
 		mockSvc.On("DummyLogin", &models.DummyLoginRequest{Role: "invalid"}).
-			Return("", http.StatusBadRequest, errors.New("неверная роль"))
+			Return("", services.ErrInvalidRole)
 
 		handler := NewHandler(mockSvc)
 		handler.DummyLoginHandler(rr, req)
@@ -134,7 +189,7 @@ func TestDummyLoginHandler(t *testing.T) {
 		expectedToken := "sometoken"
 		mockSvc := new(MockService)
 		mockSvc.On("DummyLogin", &models.DummyLoginRequest{Role: "employee"}).
-			Return(expectedToken, http.StatusOK, nil)
+			Return(expectedToken, nil)
 
 		handler := NewHandler(mockSvc)
 		handler.DummyLoginHandler(rr, req)
@@ -177,7 +232,7 @@ func TestRegisterHandler(t *testing.T) {
 		mockSvc := new(MockService)
 
 		mockSvc.On("Register", mock.Anything, &registerReq).
-			Return((*models.User)(nil), http.StatusBadRequest, errors.New("неверная роль"))
+			Return((*models.User)(nil), services.ErrInvalidRole)
 
 		handler := NewHandler(mockSvc)
 		handler.RegisterHandler(rr, req)
@@ -207,11 +262,11 @@ func TestRegisterHandler(t *testing.T) {
 		}
 		mockSvc := new(MockService)
 		mockSvc.On("Register", mock.Anything, &registerReq).
-			Return(expectedUser, http.StatusOK, nil)
+			Return(expectedUser, nil)
 
 		handler := NewHandler(mockSvc)
 		handler.RegisterHandler(rr, req)
-		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, http.StatusCreated, rr.Code)
 
 		var userResp models.User
 		err := json.NewDecoder(rr.Body).Decode(&userResp)
@@ -250,7 +305,7 @@ func TestLoginHandler(t *testing.T) {
 
 		mockSvc := new(MockService)
 		mockSvc.On("Login", mock.Anything, &loginReq).
-			Return("", http.StatusUnauthorized, errors.New("неверные учетные данные"))
+			Return("", services.ErrInvalidCredentials)
 
 		handler := NewHandler(mockSvc)
 		handler.LoginHandler(rr, req)
@@ -275,7 +330,7 @@ func TestLoginHandler(t *testing.T) {
 		expectedToken := "validtoken"
 		mockSvc := new(MockService)
 		mockSvc.On("Login", mock.Anything, &loginReq).
-			Return(expectedToken, http.StatusOK, nil)
+			Return(expectedToken, nil)
 
 		handler := NewHandler(mockSvc)
 		handler.LoginHandler(rr, req)
@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pvz/docs"
+	"pvz/internal/database"
+	"pvz/internal/middleware"
+	"pvz/internal/models"
+	"pvz/internal/problem"
+	"pvz/internal/services"
+)
+
+// newContractServer собирает маршруты поверх настоящего сервиса со строгой
+// проверкой по спецификации: любой дрейф ответа превращается в 500.
+func newContractServer(t *testing.T) *httptest.Server {
+	secret := []byte("contract-secret")
+	h := NewHandler(services.NewService(database.NewMemoryDatabase(), secret))
+	mw := middleware.NewMiddleware(secret)
+	validate, err := mw.OpenAPIMiddleware(docs.Swagger, middleware.OpenAPIOptions{ValidateResponses: true})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	r.Use(validate)
+	r.HandleFunc("/dummyLogin", h.DummyLoginHandler).Methods("POST")
+	r.HandleFunc("/register", h.RegisterHandler).Methods("POST")
+	r.HandleFunc("/login", h.LoginHandler).Methods("POST")
+	api := r.PathPrefix("/").Subrouter()
+	api.Use(mw.AuthMiddleware)
+	api.HandleFunc("/pvz", h.CreatePVZHandler).Methods("POST")
+	api.HandleFunc("/pvz", h.ListPVZHandler).Methods("GET")
+	api.HandleFunc("/pvz/{pvzId}/close_last_reception", h.CloseLastReceptionHandler).Methods("POST")
+	api.HandleFunc("/pvz/{pvzId}/delete_last_product", h.DeleteLastProductHandler).Methods("POST")
+	api.HandleFunc("/analytics/intake", h.IntakeAnalyticsHandler).Methods("GET")
+	api.HandleFunc("/receptions", h.CreateReceptionHandler).Methods("POST")
+	api.HandleFunc("/products", h.AddProductHandler).Methods("POST")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func contractCall(t *testing.T, ts *httptest.Server, method, path, token, body string, wantStatus int) []byte {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	require.Equal(t, wantStatus, resp.StatusCode, "%s %s: %s", method, path, buf.String())
+	return buf.Bytes()
+}
+
+func TestContractFullFlow(t *testing.T) {
+	ts := newContractServer(t)
+
+	contractCall(t, ts, "POST", "/register", "", `{"email":"user@example.com","password":"secret","role":"employee"}`, http.StatusCreated)
+	var empToken string
+	require.NoError(t, json.Unmarshal(contractCall(t, ts, "POST", "/login", "", `{"email":"user@example.com","password":"secret"}`, http.StatusOK), &empToken))
+	var modToken string
+	require.NoError(t, json.Unmarshal(contractCall(t, ts, "POST", "/dummyLogin", "", `{"role":"moderator"}`, http.StatusOK), &modToken))
+
+	contractCall(t, ts, "GET", "/pvz", empToken, "", http.StatusOK)
+
+	var pvz models.PVZ
+	require.NoError(t, json.Unmarshal(contractCall(t, ts, "POST", "/pvz", modToken, `{"city":"Казань"}`, http.StatusCreated), &pvz))
+	contractCall(t, ts, "POST", "/receptions", empToken, `{"pvzId":"`+pvz.ID.String()+`"}`, http.StatusCreated)
+	contractCall(t, ts, "POST", "/products", empToken, `{"type":"обувь","pvzId":"`+pvz.ID.String()+`"}`, http.StatusCreated)
+	contractCall(t, ts, "POST", "/products", empToken, `{"type":"одежда","pvzId":"`+pvz.ID.String()+`"}`, http.StatusCreated)
+	contractCall(t, ts, "POST", "/pvz/"+pvz.ID.String()+"/delete_last_product", empToken, "", http.StatusOK)
+	contractCall(t, ts, "POST", "/pvz/"+pvz.ID.String()+"/close_last_reception", empToken, "", http.StatusOK)
+	contractCall(t, ts, "GET", "/pvz?page=1&limit=5", empToken, "", http.StatusOK)
+	now := time.Now().UTC()
+	period := "from=" + now.Add(-time.Hour).Format(time.RFC3339) + "&to=" + now.Add(time.Hour).Format(time.RFC3339)
+	contractCall(t, ts, "GET", "/analytics/intake?"+period+"&groupBy=city", modToken, "", http.StatusOK)
+}
+
+func TestContractRejectsInvalidRequests(t *testing.T) {
+	ts := newContractServer(t)
+	var modToken string
+	require.NoError(t, json.Unmarshal(contractCall(t, ts, "POST", "/dummyLogin", "", `{"role":"moderator"}`, http.StatusOK), &modToken))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		detail string
+	}{
+		{"id задаёт сервер", "POST", "/pvz", `{"city":"Москва","id":"7a0f8c4e-8d4c-4a51-9d65-0e3f6a1b2c3d"}`, "тело запроса"},
+		{"неизвестный город", "POST", "/pvz", `{"city":"Тверь"}`, "поле city"},
+		{"роль вне перечисления", "POST", "/dummyLogin", `{"role":"admin"}`, "поле role"},
+		{"limit больше 30", "GET", "/pvz?limit=100", "", "параметр limit"},
+		{"pvzId не UUID", "POST", "/pvz/not-a-uuid/close_last_reception", "", "параметр pvzId"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := contractCall(t, ts, tt.method, tt.path, modToken, tt.body, http.StatusBadRequest)
+			var errResp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(body, &errResp))
+			assert.Equal(t, "Запрос не соответствует спецификации API", errResp.Message)
+			require.NotEmpty(t, errResp.Details)
+			assert.Contains(t, errResp.Details[0], tt.detail)
+		})
+	}
+}
+
+func TestContractErrorCodes(t *testing.T) {
+	ts := newContractServer(t)
+	var empToken, modToken string
+	require.NoError(t, json.Unmarshal(contractCall(t, ts, "POST", "/dummyLogin", "", `{"role":"employee"}`, http.StatusOK), &empToken))
+	require.NoError(t, json.Unmarshal(contractCall(t, ts, "POST", "/dummyLogin", "", `{"role":"moderator"}`, http.StatusOK), &modToken))
+	var pvz models.PVZ
+	require.NoError(t, json.Unmarshal(contractCall(t, ts, "POST", "/pvz", modToken, `{"city":"Москва"}`, http.StatusCreated), &pvz))
+	pvzID := pvz.ID.String()
+
+	expectCode := func(body []byte, code problem.Code) {
+		t.Helper()
+		var errResp models.ErrorResponse
+		require.NoError(t, json.Unmarshal(body, &errResp))
+		assert.Equal(t, string(code), errResp.Code)
+		assert.Equal(t, errResp.Detail, errResp.Message)
+	}
+
+	expectCode(contractCall(t, ts, "POST", "/products", empToken, `{"type":"обувь","pvzId":"`+pvzID+`"}`, http.StatusConflict), problem.CodeNoActiveReception)
+	expectCode(contractCall(t, ts, "POST", "/pvz/"+pvzID+"/close_last_reception", empToken, "", http.StatusConflict), problem.CodeNoActiveReception)
+	contractCall(t, ts, "POST", "/receptions", empToken, `{"pvzId":"`+pvzID+`"}`, http.StatusCreated)
+	expectCode(contractCall(t, ts, "POST", "/receptions", empToken, `{"pvzId":"`+pvzID+`"}`, http.StatusConflict), problem.CodeReceptionAlreadyOpen)
+	expectCode(contractCall(t, ts, "POST", "/pvz/"+pvzID+"/delete_last_product", empToken, "", http.StatusConflict), problem.CodeNoProductToDelete)
+	unknownID := uuid.NewString()
+	expectCode(contractCall(t, ts, "POST", "/receptions", empToken, `{"pvzId":"`+unknownID+`"}`, http.StatusNotFound), problem.CodePVZNotFound)
+	expectCode(contractCall(t, ts, "POST", "/pvz/"+unknownID+"/close_last_reception", empToken, "", http.StatusNotFound), problem.CodePVZNotFound)
+	expectCode(contractCall(t, ts, "POST", "/products", empToken, `{"type":"обувь","pvzId":"`+unknownID+`"}`, http.StatusNotFound), problem.CodePVZNotFound)
+	expectCode(contractCall(t, ts, "POST", "/pvz/"+unknownID+"/delete_last_product", empToken, "", http.StatusNotFound), problem.CodePVZNotFound)
+	expectCode(contractCall(t, ts, "POST", "/pvz", empToken, `{"city":"Москва"}`, http.StatusForbidden), problem.CodeForbidden)
+	expectCode(contractCall(t, ts, "GET", "/pvz", "", "", http.StatusUnauthorized), problem.CodeUnauthorized)
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"pvz/internal/logger"
+	"pvz/internal/problem"
+)
+
+type requestError struct {
+	code    problem.Code
+	message string
+	err     error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+// decodeJSON разбирает тело запроса строго: неизвестные поля, лишние данные
+// после объекта и превышение лимита размера тела считаются ошибкой клиента.
+func decodeJSON(r *http.Request, dst interface{}) *requestError {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(dst)
+	if err == nil {
+		if _, tokErr := decoder.Token(); tokErr != io.EOF {
+			err = errors.New("лишние данные после JSON-объекта")
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	var tooLarge *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &tooLarge):
+		return &requestError{code: problem.CodePayloadTooLarge, message: "Слишком большое тело запроса", err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &requestError{code: problem.CodeInvalidRequest, message: fmt.Sprintf("Неизвестное поле %s", field), err: err}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return &requestError{code: problem.CodeInvalidRequest, message: fmt.Sprintf("Неверный тип поля %q", typeErr.Field), err: err}
+	default:
+		return &requestError{code: problem.CodeInvalidRequest, message: "Неверный запрос", err: err}
+	}
+}
+
+func writeRequestError(w http.ResponseWriter, r *http.Request, reqErr *requestError, operation string) {
+	problem.Write(w, r, reqErr.code.HTTPStatus(), reqErr.code, reqErr.message)
+	logger.FromContext(r.Context()).WithError(reqErr).Error("Ошибка " + operation)
+}
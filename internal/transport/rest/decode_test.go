@@ -0,0 +1,45 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"pvz/internal/models"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{"valid", `{"pvzId":"abc","type":"обувь"}`, 0, ""},
+		{"unknown field", `{"pvzId":"abc","type":"обувь","count":2}`, http.StatusBadRequest, `Неизвестное поле "count"`},
+		{"wrong type", `{"pvzId":1}`, http.StatusBadRequest, `Неверный тип поля "pvzId"`},
+		{"trailing data", `{"pvzId":"abc"} {}`, http.StatusBadRequest, "Неверный запрос"},
+		{"empty body", ``, http.StatusBadRequest, "Неверный запрос"},
+		{"too large", `{"pvzId":"` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge, "Слишком большое тело запроса"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(tt.body))
+			req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 48)
+			var dst models.AddProductRequest
+			reqErr := decodeJSON(req, &dst)
+			if tt.status == 0 {
+				assert.Nil(t, reqErr)
+				assert.Equal(t, "abc", dst.PVZId)
+				return
+			}
+			if assert.NotNil(t, reqErr) {
+				assert.Equal(t, tt.status, reqErr.code.HTTPStatus())
+				assert.Equal(t, tt.message, reqErr.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/logger"
+	"pvz/internal/models"
+	"pvz/internal/problem"
+)
+
+// heartbeatInterval — период комментариев SSE, которые не дают прокси закрыть простаивающее соединение.
+var heartbeatInterval = 15 * time.Second
+
+func writeSSE(w io.Writer, event models.OutboxEvent) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload)
+	return err
+}
+
+// PVZEventsHandler передает события приёмки ПВЗ в формате Server-Sent Events.
+// При переподключении с Last-Event-ID сначала отправляются пропущенные события из буфера;
+// если их там уже нет, отправляется событие reset, и клиенту нужно перечитать состояние ПВЗ.
+func (h *Handler) PVZEventsHandler(w http.ResponseWriter, r *http.Request) {
+	role := r.Context().Value(contextkeys.ContextKeyRole).(string)
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Неверный идентификатор ПВЗ")
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка PVZEvents")
+		return
+	}
+	sub, err := h.services.SubscribePVZEvents(r.Context(), role, pvzId, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка PVZEvents")
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	logger.FromContext(r.Context()).WithField("pvz_id", pvzId).Info("Подписка на события ПВЗ")
+
+	if sub.Reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range sub.Backlog {
+		if err := writeSSE(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				logger.FromContext(r.Context()).WithField("pvz_id", pvzId).Warn("Подписчик отстал от ленты событий и отключен")
+				return
+			}
+			err = writeSSE(w, event)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/contextkeys"
+	"pvz/internal/events"
+	"pvz/internal/models"
+	"pvz/internal/services"
+)
+
+func eventsServer(t *testing.T, mockSvc *MockService) *httptest.Server {
+	router := mux.NewRouter()
+	router.HandleFunc("/pvz/{pvzId}/events", func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextkeys.ContextKeyRole, "employee")
+		NewHandler(mockSvc).PVZEventsHandler(w, r.WithContext(ctx))
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readSSE читает n событий потока, пропуская комментарии.
+func readSSE(t *testing.T, reader *bufio.Reader, n int) []map[string]string {
+	t.Helper()
+	var result []map[string]string
+	event := map[string]string{}
+	for len(result) < n {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(event) > 0 {
+				result = append(result, event)
+				event = map[string]string{}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		event[field] = value
+	}
+	return result
+}
+
+func TestPVZEventsHandler(t *testing.T) {
+	pvzId := uuid.New()
+	event := func(id int64, eventType string) models.OutboxEvent {
+		payload, _ := json.Marshal(models.Reception{ID: uuid.New(), PVZId: pvzId, Status: "in_progress"})
+		return models.OutboxEvent{ID: id, Type: eventType, PVZId: pvzId, Payload: payload}
+	}
+
+	t.Run("resume from Last-Event-ID and live events", func(t *testing.T) {
+		feed := events.NewFeed(16)
+		feed.Publish(event(1, models.EventReceptionOpened))
+		feed.Publish(event(2, models.EventProductAdded))
+		lastID := int64(1)
+		mockSvc := new(MockService)
+		mockSvc.On("SubscribePVZEvents", mock.Anything, "employee", pvzId, "1").
+			Return(feed.Subscribe(pvzId, &lastID), nil)
+
+		req, err := http.NewRequest(http.MethodGet, eventsServer(t, mockSvc).URL+"/pvz/"+pvzId.String()+"/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		reader := bufio.NewReader(resp.Body)
+		got := readSSE(t, reader, 1)
+		assert.Equal(t, "2", got[0]["id"])
+		assert.Equal(t, models.EventProductAdded, got[0]["event"])
+
+		feed.Publish(event(3, models.EventProductRemoved))
+		got = readSSE(t, reader, 1)
+		assert.Equal(t, "3", got[0]["id"])
+		assert.Equal(t, models.EventProductRemoved, got[0]["event"])
+		var payload models.Reception
+		require.NoError(t, json.Unmarshal([]byte(got[0]["data"]), &payload))
+		assert.Equal(t, pvzId, payload.PVZId)
+	})
+
+	t.Run("reset when Last-Event-ID is evicted", func(t *testing.T) {
+		feed := events.NewFeed(1)
+		feed.Publish(event(1, models.EventReceptionOpened))
+		feed.Publish(event(2, models.EventProductAdded))
+		lastID := int64(1)
+		mockSvc := new(MockService)
+		mockSvc.On("SubscribePVZEvents", mock.Anything, "employee", pvzId, "1").
+			Return(feed.Subscribe(pvzId, &lastID), nil)
+
+		req, err := http.NewRequest(http.MethodGet, eventsServer(t, mockSvc).URL+"/pvz/"+pvzId.String()+"/events", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		got := readSSE(t, bufio.NewReader(resp.Body), 1)
+		assert.Equal(t, "reset", got[0]["event"])
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("SubscribePVZEvents", mock.Anything, "employee", pvzId, "").
+			Return(nil, services.ErrPVZNotFound)
+
+		resp, err := http.Get(eventsServer(t, mockSvc).URL + "/pvz/" + pvzId.String() + "/events")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		var body models.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "ПВЗ не найден", body.Message)
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		defer func(interval time.Duration) { heartbeatInterval = interval }(heartbeatInterval)
+		heartbeatInterval = 10 * time.Millisecond
+		feed := events.NewFeed(16)
+		mockSvc := new(MockService)
+		mockSvc.On("SubscribePVZEvents", mock.Anything, "employee", pvzId, "").
+			Return(feed.Subscribe(pvzId, nil), nil)
+
+		resp, err := http.Get(eventsServer(t, mockSvc).URL + "/pvz/" + pvzId.String() + "/events")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, ": ping\n", line)
+	})
+}
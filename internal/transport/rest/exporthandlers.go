@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"pvz/internal/export"
+	"pvz/internal/logger"
+	"pvz/internal/models"
+	"pvz/internal/problem"
+)
+
+var exportColumns = []string{
+	"pvz_id", "pvz_city", "pvz_registration_date",
+	"reception_id", "reception_date_time", "reception_status",
+	"product_id", "product_date_time", "product_type",
+}
+
+func exportRecord(row *models.ExportRow) []string {
+	return []string{
+		row.PVZ.ID.String(), row.PVZ.City, row.PVZ.RegistrationDate.UTC().Format(time.RFC3339),
+		row.Reception.ID.String(), row.Reception.DateTime.UTC().Format(time.RFC3339), row.Reception.Status,
+		row.Product.ID.String(), row.Product.DateTime.UTC().Format(time.RFC3339), row.Product.Type,
+	}
+}
+
+// ExportReceptionsHandler отдает товары с приёмками и ПВЗ в CSV или XLSX в зависимости от Accept.
+// Ответ пишется по мере чтения из БД: пока не записана первая строка, ошибки возвращаются
+// обычным JSON, а после этого соединение обрывается, чтобы клиент не принял неполный файл.
+func (h *Handler) ExportReceptionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	format, ok := export.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		problem.Write(w, r, http.StatusNotAcceptable, problem.CodeNotAcceptable, "Поддерживаются только text/csv и "+export.ContentTypeXLSX)
+		return
+	}
+
+	var writer export.Writer
+	started := false
+	rows := 0
+	start := func() error {
+		started = true
+		// Большая выгрузка не должна обрываться таймаутом записи сервера.
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="receptions.`+string(format)+`"`)
+		w.WriteHeader(http.StatusOK)
+		var err error
+		if writer, err = export.NewWriter(format, w); err != nil {
+			return err
+		}
+		return writer.WriteRow(exportColumns)
+	}
+
+	q := r.URL.Query()
+	err := h.services.ExportProducts(r.Context(), q.Get("from"), q.Get("to"), q.Get("city"), q.Get("pvzId"), func(row *models.ExportRow) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		rows++
+		return writer.WriteRow(exportRecord(row))
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		if !started {
+			problem.WriteError(w, r, err)
+			logger.FromContext(r.Context()).WithError(err).Error("Ошибка ExportReceptions")
+			return
+		}
+		logger.FromContext(r.Context()).WithError(err).WithField("rows", rows).Error("Выгрузка прервана")
+		panic(http.ErrAbortHandler)
+	}
+	logger.FromContext(r.Context()).WithFields(logrus.Fields{
+		"format": format,
+		"rows":   rows,
+	}).Info("ExportReceptions выполнен успешно")
+}
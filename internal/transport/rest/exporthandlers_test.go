@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"pvz/internal/export"
+	"pvz/internal/models"
+	"pvz/internal/problem"
+)
+
+func TestExportReceptionsHandler(t *testing.T) {
+	date := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	row := models.ExportRow{
+		PVZ:       models.PVZ{ID: uuid.New(), City: "Москва", RegistrationDate: date},
+		Reception: models.Reception{ID: uuid.New(), DateTime: date, Status: "close"},
+		Product:   models.Product{ID: uuid.New(), DateTime: date, Type: "обувь"},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("ExportProducts", mock.Anything, "2025-04-01T00:00:00Z", "", "Москва", "").Return([]models.ExportRow{row}, nil)
+		req := httptest.NewRequest(http.MethodGet, "/exports/receptions?from=2025-04-01T00:00:00Z&city=Москва", nil)
+		rr := httptest.NewRecorder()
+
+		NewHandler(mockSvc).ExportReceptionsHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, export.ContentTypeCSV, rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="receptions.csv"`, rr.Header().Get("Content-Disposition"))
+		assert.Equal(t, "\uFEFF"+
+			"pvz_id,pvz_city,pvz_registration_date,reception_id,reception_date_time,reception_status,product_id,product_date_time,product_type\n"+
+			row.PVZ.ID.String()+",Москва,2025-04-01T10:00:00Z,"+row.Reception.ID.String()+",2025-04-01T10:00:00Z,close,"+row.Product.ID.String()+",2025-04-01T10:00:00Z,обувь\n",
+			rr.Body.String())
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("xlsx without rows", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("ExportProducts", mock.Anything, "", "", "", "").Return(nil, nil)
+		req := httptest.NewRequest(http.MethodGet, "/exports/receptions", nil)
+		req.Header.Set("Accept", export.ContentTypeXLSX)
+		rr := httptest.NewRecorder()
+
+		NewHandler(mockSvc).ExportReceptionsHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, export.ContentTypeXLSX, rr.Header().Get("Content-Type"))
+		_, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		assert.NoError(t, err, "даже пустая выгрузка — корректная книга")
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/exports/receptions", nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+
+		NewHandler(new(MockService)).ExportReceptionsHandler(rr, req)
+
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("ExportProducts", mock.Anything, "yesterday", "", "", "").Return(nil, problem.New(problem.CodeInvalidRequest, "неверный формат from, ожидается RFC3339"))
+		req := httptest.NewRequest(http.MethodGet, "/exports/receptions?from=yesterday", nil)
+		rr := httptest.NewRecorder()
+
+		NewHandler(mockSvc).ExportReceptionsHandler(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+		assert.Equal(t, "неверный формат from, ожидается RFC3339", errResp.Message)
+	})
+
+	t.Run("error after first row aborts response", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("ExportProducts", mock.Anything, "", "", "", "").Return([]models.ExportRow{row}, errors.New("ошибка выгрузки товаров"))
+		req := httptest.NewRequest(http.MethodGet, "/exports/receptions", nil)
+		rr := httptest.NewRecorder()
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			NewHandler(mockSvc).ExportReceptionsHandler(rr, req)
+		})
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
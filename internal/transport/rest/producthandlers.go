@@ -9,8 +9,9 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"pvz/internal/contextkeys"
-	"pvz/internal/metrics"
+	"pvz/internal/logger"
 	"pvz/internal/models"
+	"pvz/internal/problem"
 )
 
 func (h *Handler) DeleteLastProductHandler(w http.ResponseWriter, r *http.Request) {
@@ -19,56 +20,43 @@ func (h *Handler) DeleteLastProductHandler(w http.ResponseWriter, r *http.Reques
 	pvzIdStr := vars["pvzId"]
 	pvzId, err := uuid.Parse(pvzIdStr)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный идентификатор ПВЗ"})
-		logrus.WithError(err).Error("Ошибка DeleteLastProduct")
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Неверный идентификатор ПВЗ")
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка DeleteLastProduct")
 		return
 	}
-	status, err := h.services.DeleteLastProduct(r.Context(), role, pvzId)
+	err = h.services.DeleteLastProduct(r.Context(), role, pvzId)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка DeleteLastProduct")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка DeleteLastProduct")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("DeleteLastProduct выполнен успешно")
+	logger.FromContext(r.Context()).Info("DeleteLastProduct выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Товар удалён"})
 }
 
 func (h *Handler) AddProductHandler(w http.ResponseWriter, r *http.Request) {
 	role := r.Context().Value(contextkeys.ContextKeyRole).(string)
 	var req models.AddProductRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный запрос"})
-		logrus.WithError(err).Error("Ошибка AddProduct")
+	if reqErr := decodeJSON(r, &req); reqErr != nil {
+		writeRequestError(w, r, reqErr, "AddProduct")
 		return
 	}
 	pvzId, err := uuid.Parse(req.PVZId)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный идентификатор ПВЗ"})
-		logrus.WithError(err).Error("Ошибка AddProduct")
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Неверный идентификатор ПВЗ")
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка AddProduct")
 		return
 	}
-	product, status, err := h.services.AddProduct(r.Context(), role, pvzId, req.Type)
+	r = r.WithContext(logger.WithFields(r.Context(), logrus.Fields{"pvz_id": pvzId.String()}))
+	product, err := h.services.AddProduct(r.Context(), role, pvzId, req.Type)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка AddProduct")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка AddProduct")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("AddProduct выполнен успешно")
-	metrics.AddedProductsTotal.Inc()
+	logger.FromContext(r.Context()).Info("AddProduct выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(product)
 }
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -16,7 +15,9 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"pvz/internal/contextkeys"
+	"pvz/internal/database"
 	"pvz/internal/models"
+	"pvz/internal/services"
 )
 
 func TestDeleteLastProductHandler(t *testing.T) {
@@ -48,19 +49,19 @@ func TestDeleteLastProductHandler(t *testing.T) {
 		rr := httptest.NewRecorder()
 
 		mockSvc := new(MockService)
-		errMsg := "ошибка удаления товара: some error"
 		mockSvc.
 			On("DeleteLastProduct", mock.Anything, "employee", validUUID).
-			Return(http.StatusBadRequest, errors.New(errMsg))
+			Return(services.ErrNoProductToDelete)
 
 		handler := NewHandler(mockSvc)
 		handler.DeleteLastProductHandler(rr, req)
 
-		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, http.StatusConflict, rr.Code)
 		var errResp models.ErrorResponse
 		err := json.NewDecoder(rr.Body).Decode(&errResp)
 		assert.NoError(t, err)
-		assert.Equal(t, errMsg, errResp.Message)
+		assert.Equal(t, "NO_PRODUCT_TO_DELETE", errResp.Code)
+		assert.Equal(t, services.ErrNoProductToDelete.Message, errResp.Message)
 		mockSvc.AssertExpectations(t)
 	})
 
@@ -74,7 +75,7 @@ func TestDeleteLastProductHandler(t *testing.T) {
 		mockSvc := new(MockService)
 		mockSvc.
 			On("DeleteLastProduct", mock.Anything, "employee", validUUID).
-			Return(http.StatusOK, nil)
+			Return(nil)
 
 		handler := NewHandler(mockSvc)
 		handler.DeleteLastProductHandler(rr, req)
@@ -121,6 +122,23 @@ func TestAddProductHandler(t *testing.T) {
 		assert.Equal(t, "Неверный идентификатор ПВЗ", errResp.Message)
 	})
 
+	// Без проверки по спецификации неизвестный тип доходит до сервиса и все равно получает 400.
+	t.Run("unknown type", func(t *testing.T) {
+		reqBody := `{"pvzId": "` + uuid.NewString() + `", "type": "мебель"}`
+		req := httptest.NewRequest(http.MethodPost, "/add-product", bytes.NewBufferString(reqBody))
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.ContextKeyRole, "employee"))
+		rr := httptest.NewRecorder()
+
+		handler := NewHandler(services.NewService(database.NewMemoryDatabase(), []byte("secret")))
+		handler.AddProductHandler(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var errResp models.ErrorResponse
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+		assert.Equal(t, "INVALID_REQUEST", errResp.Code)
+		assert.Equal(t, "неизвестный тип товара", errResp.Message)
+	})
+
 	t.Run("service returns error", func(t *testing.T) {
 		validUUID := uuid.New()
 		reqData := models.AddProductRequest{
@@ -133,19 +151,19 @@ func TestAddProductHandler(t *testing.T) {
 		rr := httptest.NewRecorder()
 
 		mockSvc := new(MockService)
-		errMsg := "ошибка добавления товара: some error"
 		mockSvc.
 			On("AddProduct", mock.Anything, "employee", validUUID, "someType").
-			Return((*models.Product)(nil), http.StatusBadRequest, errors.New(errMsg))
+			Return((*models.Product)(nil), services.ErrNoActiveReception)
 
 		handler := NewHandler(mockSvc)
 		handler.AddProductHandler(rr, req)
 
-		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, http.StatusConflict, rr.Code)
 		var errResp models.ErrorResponse
 		err := json.NewDecoder(rr.Body).Decode(&errResp)
 		assert.NoError(t, err)
-		assert.Equal(t, errMsg, errResp.Message)
+		assert.Equal(t, "NO_ACTIVE_RECEPTION", errResp.Code)
+		assert.Equal(t, services.ErrNoActiveReception.Message, errResp.Message)
 		mockSvc.AssertExpectations(t)
 	})
 
@@ -170,7 +188,7 @@ func TestAddProductHandler(t *testing.T) {
 		mockSvc := new(MockService)
 		mockSvc.
 			On("AddProduct", mock.Anything, "employee", validUUID, "someType").
-			Return(expectedProduct, http.StatusOK, nil)
+			Return(expectedProduct, nil)
 
 		handler := NewHandler(mockSvc)
 		handler.AddProductHandler(rr, req)
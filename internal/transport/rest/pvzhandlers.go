@@ -3,36 +3,34 @@ package rest
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"pvz/internal/contextkeys"
-	"pvz/internal/metrics"
+	"pvz/internal/logger"
 	"pvz/internal/models"
+	"pvz/internal/problem"
 )
 
 func (h *Handler) CreatePVZHandler(w http.ResponseWriter, r *http.Request) {
-	var pvz models.PVZ
-	if err := json.NewDecoder(r.Body).Decode(&pvz); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный запрос"})
-		logrus.WithError(err).Error("Ошибка CreatePVZ")
+	var req models.CreatePVZRequest
+	if reqErr := decodeJSON(r, &req); reqErr != nil {
+		writeRequestError(w, r, reqErr, "CreatePVZ")
 		return
 	}
+	pvz := models.PVZ{City: req.City}
 	role := r.Context().Value(contextkeys.ContextKeyRole).(string)
-	status, err := h.services.CreatePVZ(r.Context(), &pvz, role)
+	err := h.services.CreatePVZ(r.Context(), &pvz, role)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка CreatePVZ")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка CreatePVZ")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
+	logger.FromContext(r.Context()).WithFields(logrus.Fields{
+		"pvz_id": pvz.ID,
 	}).Info("CreatePVZ выполнен успешно")
-	metrics.CreatedPVZTotal.Inc()
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(pvz)
 }
@@ -43,16 +41,37 @@ func (h *Handler) ListPVZHandler(w http.ResponseWriter, r *http.Request) {
 	endDateStr := q.Get("endDate")
 	pageStr := q.Get("page")
 	limitStr := q.Get("limit")
-	results, status, err := h.services.ListPVZ(r.Context(), startDateStr, endDateStr, pageStr, limitStr)
+	version, err := h.services.ListPVZVersion(r.Context(), startDateStr, endDateStr, pageStr, limitStr)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка ListPVZ")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка ListPVZ")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("ListPVZ выполнен успешно")
+	etag := `"` + version + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	results, err := h.services.ListPVZ(r.Context(), startDateStr, endDateStr, pageStr, limitStr)
+	if err != nil {
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка ListPVZ")
+		return
+	}
+	logger.FromContext(r.Context()).Info("ListPVZ выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(results)
 }
+
+// etagMatches проверяет заголовок If-None-Match: список тегов через запятую или "*".
+// По RFC 9110 теги сравниваются слабо, то есть без учета префикса W/.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,7 @@ import (
 
 	"pvz/internal/contextkeys"
 	"pvz/internal/models"
+	"pvz/internal/services"
 )
 
 func TestCreatePVZHandler(t *testing.T) {
@@ -39,31 +40,43 @@ func TestCreatePVZHandler(t *testing.T) {
 				assert.Equal(t, "Неверный запрос", errResp.Message)
 			},
 		},
+		{
+			name:           "server-assigned fields",
+			requestBody:    `{"city":"Москва","id":"7a0f8c4e-8d4c-4a51-9d65-0e3f6a1b2c3d"}`,
+			role:           "moderator",
+			mockSetup:      func(m *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: func(body []byte) {
+				var errResp models.ErrorResponse
+				assert.NoError(t, json.Unmarshal(body, &errResp))
+				assert.Equal(t, `Неизвестное поле "id"`, errResp.Message)
+			},
+		},
 		{
 			name: "service returns error",
 			requestBody: func() string {
-				req := models.PVZ{City: "NotValidCity"}
+				req := models.CreatePVZRequest{City: "NotValidCity"}
 				b, _ := json.Marshal(req)
 				return string(b)
 			}(),
 			role: "moderator",
 			mockSetup: func(m *MockService) {
-				errorMessage := "ошибка ПВЗ можно создать только в Москве, Санкт-Петербурге или Казани"
 				m.On("CreatePVZ", mock.Anything, mock.MatchedBy(func(p *models.PVZ) bool {
 					return p.City == "NotValidCity"
-				}), "moderator").Return(http.StatusBadRequest, errors.New(errorMessage))
+				}), "moderator").Return(services.ErrUnknownCity)
 			},
 			expectedStatus: http.StatusBadRequest,
 			expectedBody: func(body []byte) {
 				var errResp models.ErrorResponse
 				assert.NoError(t, json.Unmarshal(body, &errResp))
-				assert.Equal(t, "ошибка ПВЗ можно создать только в Москве, Санкт-Петербурге или Казани", errResp.Message)
+				assert.Equal(t, "UNKNOWN_CITY", errResp.Code)
+				assert.Equal(t, "неизвестный город", errResp.Message)
 			},
 		},
 		{
 			name: "success",
 			requestBody: func() string {
-				req := models.PVZ{City: "Москва"}
+				req := models.CreatePVZRequest{City: "Москва"}
 				b, _ := json.Marshal(req)
 				return string(b)
 			}(),
@@ -71,7 +84,7 @@ func TestCreatePVZHandler(t *testing.T) {
 			mockSetup: func(m *MockService) {
 				m.On("CreatePVZ", mock.Anything, mock.MatchedBy(func(p *models.PVZ) bool {
 					return p.City == "Москва"
-				}), "moderator").Return(http.StatusOK, nil).Run(func(args mock.Arguments) {
+				}), "moderator").Return(nil).Run(func(args mock.Arguments) {
 					p := args.Get(1).(*models.PVZ)
 					p.RegistrationDate = time.Now()
 					p.ID = uuid.New()
@@ -119,15 +132,16 @@ func TestListPVZHandler(t *testing.T) {
 			name:        "service returns error",
 			queryString: "/list-pvz?startDate=2023-01-01T00:00:00Z&endDate=2023-01-02T00:00:00Z&page=1&limit=10",
 			mockSetup: func(m *MockService) {
-				errorMessage := "ошибка выборки ПВЗ"
+				m.On("ListPVZVersion", mock.Anything, "2023-01-01T00:00:00Z", "2023-01-02T00:00:00Z", "1", "10").Return("v1", nil)
 				m.On("ListPVZ", mock.Anything, "2023-01-01T00:00:00Z", "2023-01-02T00:00:00Z", "1", "10").
-					Return(([]*models.PVZResponse)(nil), http.StatusBadRequest, errors.New(errorMessage))
+					Return(([]*models.PVZResponse)(nil), errors.New("ошибка выборки ПВЗ: no rows in result set"))
 			},
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusInternalServerError,
 			expectedBody: func(body []byte) {
 				var errResp models.ErrorResponse
 				assert.NoError(t, json.Unmarshal(body, &errResp))
-				assert.Equal(t, "ошибка выборки ПВЗ", errResp.Message)
+				assert.Equal(t, "INTERNAL", errResp.Code)
+				assert.Equal(t, "внутренняя ошибка сервера", errResp.Message, "текст ошибки БД не должен попадать к клиенту")
 			},
 		},
 		{
@@ -143,8 +157,9 @@ func TestListPVZHandler(t *testing.T) {
 					PVZ:        &samplePVZ,
 					Receptions: []*models.ReceptionInfo{},
 				}
+				m.On("ListPVZVersion", mock.Anything, "", "", "2", "5").Return("v1", nil)
 				m.On("ListPVZ", mock.Anything, "", "", "2", "5").
-					Return([]*models.PVZResponse{sampleResponse}, http.StatusOK, nil)
+					Return([]*models.PVZResponse{sampleResponse}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody: func(body []byte) {
@@ -173,3 +188,63 @@ func TestListPVZHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestListPVZHandlerConditional(t *testing.T) {
+	list := func(ifNoneMatch string, mockSetup func(m *MockService)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/pvz?page=1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		mockSvc := new(MockService)
+		mockSvc.On("ListPVZVersion", mock.Anything, "", "", "1", "").Return("abc", nil)
+		if mockSetup != nil {
+			mockSetup(mockSvc)
+		}
+		NewHandler(mockSvc).ListPVZHandler(rr, req)
+		mockSvc.AssertExpectations(t)
+		return rr
+	}
+	withList := func(m *MockService) {
+		m.On("ListPVZ", mock.Anything, "", "", "1", "").Return([]*models.PVZResponse{}, nil)
+	}
+
+	rr := list("", withList)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"abc"`, rr.Header().Get("ETag"))
+
+	rr = list(`"abc"`, nil)
+	assert.Equal(t, http.StatusNotModified, rr.Code, "данные не изменились — список не запрашивается")
+	assert.Equal(t, `"abc"`, rr.Header().Get("ETag"))
+	assert.Empty(t, rr.Body.Bytes())
+
+	rr = list(`"old"`, withList)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"abc"`, rr.Header().Get("ETag"))
+
+	t.Run("version error", func(t *testing.T) {
+		mockSvc := new(MockService)
+		mockSvc.On("ListPVZVersion", mock.Anything, "", "", "", "").Return("", services.ErrUnavailable.Wrap(errors.New("connection refused")))
+		rr := httptest.NewRecorder()
+		NewHandler(mockSvc).ListPVZHandler(rr, httptest.NewRequest(http.MethodGet, "/pvz", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Empty(t, rr.Header().Get("ETag"))
+		mockSvc.AssertExpectations(t)
+	})
+}
+
+func TestETagMatches(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":               false,
+		`"abc"`:          true,
+		`W/"abc"`:        true,
+		`"old", "abc"`:   true,
+		`"old",W/"abc"`:  true,
+		"*":              true,
+		`"abcd"`:         false,
+		`abc`:            false,
+		`"old", "other"`: false,
+	} {
+		assert.Equal(t, want, etagMatches(header, `"abc"`), "If-None-Match: %s", header)
+	}
+}
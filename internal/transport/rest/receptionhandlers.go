@@ -9,8 +9,9 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"pvz/internal/contextkeys"
-	"pvz/internal/metrics"
+	"pvz/internal/logger"
 	"pvz/internal/models"
+	"pvz/internal/problem"
 )
 
 func (h *Handler) CloseLastReceptionHandler(w http.ResponseWriter, r *http.Request) {
@@ -19,56 +20,43 @@ func (h *Handler) CloseLastReceptionHandler(w http.ResponseWriter, r *http.Reque
 	pvzIdStr := vars["pvzId"]
 	pvzId, err := uuid.Parse(pvzIdStr)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный идентификатор ПВЗ"})
-		logrus.WithError(err).Error("Ошибка CloseLastReception")
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Неверный идентификатор ПВЗ")
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка CloseLastReception")
 		return
 	}
-	rec, status, err := h.services.CloseLastReception(r.Context(), role, pvzId)
+	rec, err := h.services.CloseLastReception(r.Context(), role, pvzId)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка CloseLastReception")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка CloseLastReception")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("CloseLastReception выполнен успешно")
+	logger.FromContext(r.Context()).Info("CloseLastReception выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(rec)
 }
 
 func (h *Handler) CreateReceptionHandler(w http.ResponseWriter, r *http.Request) {
 	role := r.Context().Value(contextkeys.ContextKeyRole).(string)
 	var req models.CreateReceptionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный запрос"})
-		logrus.WithError(err).Error("Ошибка CreateReception")
+	if reqErr := decodeJSON(r, &req); reqErr != nil {
+		writeRequestError(w, r, reqErr, "CreateReception")
 		return
 	}
 	pvzId, err := uuid.Parse(req.PVZId)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: "Неверный идентификатор ПВЗ"})
-		logrus.WithError(err).Error("Ошибка CreateReception")
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Неверный идентификатор ПВЗ")
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка CreateReception")
 		return
 	}
-	rec, status, err := h.services.CreateReception(r.Context(), role, pvzId)
+	r = r.WithContext(logger.WithFields(r.Context(), logrus.Fields{"pvz_id": pvzId.String()}))
+	rec, err := h.services.CreateReception(r.Context(), role, pvzId)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(models.ErrorResponse{Message: err.Error()})
-		logrus.WithError(err).Error("Ошибка CreateReception")
+		problem.WriteError(w, r, err)
+		logger.FromContext(r.Context()).WithError(err).Error("Ошибка CreateReception")
 		return
 	}
-	logrus.WithFields(logrus.Fields{
-		"status": status,
-	}).Info("CreateReception выполнен успешно")
-	metrics.CreatedReceptionTotal.Inc()
+	logger.FromContext(r.Context()).Info("CreateReception выполнен успешно")
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(rec)
 }
@@ -17,6 +17,7 @@ import (
 
 	"pvz/internal/contextkeys"
 	"pvz/internal/models"
+	"pvz/internal/services"
 )
 
 func TestCloseLastReceptionHandler(t *testing.T) {
@@ -51,14 +52,14 @@ func TestCloseLastReceptionHandler(t *testing.T) {
 			serviceSetup: func(m *MockService) {
 				m.
 					On("CloseLastReception", mock.Anything, "employee", validUUID).
-					Return(nil, http.StatusBadRequest, errors.New("ошибка закрытия приёмки"))
+					Return(nil, services.ErrNoActiveReception)
 			},
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusConflict,
 			expectedResponse: func(body []byte) {
 				var errResp models.ErrorResponse
 				err := json.Unmarshal(body, &errResp)
 				assert.NoError(t, err)
-				assert.Equal(t, "ошибка закрытия приёмки", errResp.Message)
+				assert.Equal(t, "NO_ACTIVE_RECEPTION", errResp.Code)
 			},
 		},
 		{
@@ -74,7 +75,7 @@ func TestCloseLastReceptionHandler(t *testing.T) {
 				}
 				m.
 					On("CloseLastReception", mock.Anything, "employee", validUUID).
-					Return(expectedRec, http.StatusOK, nil)
+					Return(expectedRec, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedResponse: func(body []byte) {
@@ -160,14 +161,15 @@ func TestCreateReceptionHandler(t *testing.T) {
 			serviceSetup: func(m *MockService) {
 				m.
 					On("CreateReception", mock.Anything, "employee", validUUID).
-					Return(nil, http.StatusBadRequest, errors.New("ошибка создания приёмки"))
+					Return(nil, services.ErrUnavailable.Wrap(errors.New("dial tcp: connection refused")))
 			},
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusServiceUnavailable,
 			expectedResponse: func(body []byte) {
 				var errResp models.ErrorResponse
 				err := json.Unmarshal(body, &errResp)
 				assert.NoError(t, err)
-				assert.Equal(t, "ошибка создания приёмки", errResp.Message)
+				assert.Equal(t, "UNAVAILABLE", errResp.Code)
+				assert.Equal(t, services.ErrUnavailable.Message, errResp.Message)
 			},
 		},
 		{
@@ -187,7 +189,7 @@ func TestCreateReceptionHandler(t *testing.T) {
 				}
 				m.
 					On("CreateReception", mock.Anything, "employee", validUUID).
-					Return(expectedRec, http.StatusOK, nil)
+					Return(expectedRec, nil)
 			},
 			expectedStatus: http.StatusCreated,
 			expectedResponse: func(body []byte) {
@@ -27,4 +27,83 @@ CREATE TABLE IF NOT EXISTS products (
     type VARCHAR(50) NOT NULL CHECK (type IN ('электроника', 'одежда', 'обувь')),
     reception_id UUID NOT NULL,
     FOREIGN KEY (reception_id) REFERENCES receptions(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+ALTER TABLE receptions ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS receptions_date_time_idx ON receptions (date_time);
+
+ALTER TABLE receptions ADD COLUMN IF NOT EXISTS close_reason VARCHAR(50) CHECK (close_reason IN ('manual', 'auto_closed'));
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    pvz_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE SEQUENCE IF NOT EXISTS pvz_events_seq;
+
+-- Версия ПВЗ растет при любом изменении его приёмок и товаров; по ней строятся ETag.
+ALTER TABLE pvz ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_pvz_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'receptions' THEN
+        UPDATE pvz SET version = version + 1 WHERE id = COALESCE(NEW.pvz_id, OLD.pvz_id);
+    ELSE
+        UPDATE pvz SET version = version + 1
+        WHERE id = (SELECT pvz_id FROM receptions WHERE id = COALESCE(NEW.reception_id, OLD.reception_id));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS receptions_bump_pvz_version ON receptions;
+CREATE TRIGGER receptions_bump_pvz_version AFTER INSERT OR UPDATE OR DELETE ON receptions
+    FOR EACH ROW EXECUTE FUNCTION bump_pvz_version();
+
+DROP TRIGGER IF EXISTS products_bump_pvz_version ON products;
+CREATE TRIGGER products_bump_pvz_version AFTER INSERT OR UPDATE OR DELETE ON products
+    FOR EACH ROW EXECUTE FUNCTION bump_pvz_version();
+
+-- Реплики сбрасывают кеш ПВЗ по уведомлению. Смена версии ПВЗ кеш не затрагивает.
+CREATE OR REPLACE FUNCTION notify_pvz_changes() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('pvz_changes', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS pvz_notify_changes ON pvz;
+CREATE TRIGGER pvz_notify_changes AFTER INSERT OR DELETE OR UPDATE OF city, registration_date ON pvz
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_pvz_changes();
+
+-- Не больше одной открытой приёмки на ПВЗ. Лишние открытые приёмки, если они успели
+-- появиться, закрываются, кроме последней: иначе индекс не создать. Причина закрытия
+-- migration_dedup отличает их от закрытых вручную и автозакрытием.
+ALTER TABLE receptions DROP CONSTRAINT IF EXISTS receptions_close_reason_check;
+ALTER TABLE receptions ADD CONSTRAINT receptions_close_reason_check
+    CHECK (close_reason IN ('manual', 'auto_closed', 'migration_dedup'));
+UPDATE receptions r SET status = 'close', closed_at = now(), close_reason = 'migration_dedup'
+WHERE r.status = 'in_progress' AND EXISTS (
+    SELECT 1 FROM receptions newer
+    WHERE newer.pvz_id = r.pvz_id AND newer.status = 'in_progress'
+      AND (newer.date_time, newer.id) > (r.date_time, r.id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS receptions_one_open_per_pvz_idx ON receptions (pvz_id) WHERE status = 'in_progress';
@@ -0,0 +1,47 @@
+// Package migrations встраивает SQL-миграции в бинарник. Скрипты идемпотентны,
+// поэтому их можно применять повторно к уже инициализированной базе.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+func Names() ([]string, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Apply выполняет все миграции по порядку имен файлов и возвращает их имена.
+func Apply(ctx context.Context, db Execer) ([]string, error) {
+	names, err := Names()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		script, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := db.Exec(ctx, string(script)); err != nil {
+			return nil, fmt.Errorf("ошибка миграции %s: %w", name, err)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	mockPool, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(regexp.QuoteMeta(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`)).
+		WillReturnResult(pgxmock.NewResult("CREATE", 0))
+
+	names, err := Apply(context.Background(), mockPool)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"init.sql"}, names)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+
+	mockPool.ExpectExec("CREATE").WillReturnError(errors.New("permission denied"))
+	_, err = Apply(context.Background(), mockPool)
+	assert.EqualError(t, err, "ошибка миграции init.sql: permission denied")
+}